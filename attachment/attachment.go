@@ -0,0 +1,235 @@
+package attachment
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// RoomTarget 发送给整个聊天室的附件目标
+const RoomTarget = "room"
+
+// maxNameRunes 附件文件名的最大字符数
+const maxNameRunes = 100
+
+// idBytes 下载ID的随机字节数，下载ID即访问凭据，必须足够长以防被猜测
+const idBytes = 16
+
+// idAttempts 下载ID冲突时的最大重试次数
+const idAttempts = 5
+
+// Attachment 附件元数据
+type Attachment struct {
+	ID        string    `json:"id"`              // 下载ID
+	Name      string    `json:"name"`            // 文件名
+	Size      int64     `json:"size"`            // 文件大小（字节）
+	Hash      string    `json:"hash"`            // 内容SHA-256
+	From      string    `json:"from"`            // 发送者
+	FromID    string    `json:"from_id"`         // 发送者用户ID
+	To        string    `json:"to"`              // 接收者用户名，RoomTarget 表示整个聊天室
+	ToID      string    `json:"to_id,omitempty"` // 接收者用户ID
+	Room      string    `json:"room,omitempty"`  // 房间附件所在的房间
+	Timestamp time.Time `json:"timestamp"`       // 上传时间
+}
+
+// Store 附件存储
+//
+// 文件内容按SHA-256保存在 blobs 目录（内容寻址，相同内容只保存一份），
+// 元数据按下载ID保存在 meta 目录。
+type Store struct {
+	dir     string     // 存储目录
+	maxSize int64      // 单个附件的最大大小
+	mutex   sync.Mutex // 互斥锁
+}
+
+// NewStore 创建附件存储
+func NewStore(dir string, maxSize int64) (*Store, error) {
+	for _, sub := range []string{"blobs", "meta", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("创建附件目录失败: %v", err)
+		}
+	}
+	return &Store{dir: dir, maxSize: maxSize}, nil
+}
+
+// MaxSize 获取单个附件的最大大小
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Put 从 r 读取 size 字节保存为附件，返回附件元数据
+func (s *Store) Put(meta Attachment, r io.Reader) (*Attachment, error) {
+	if meta.Size < 0 || meta.Size > s.maxSize {
+		return nil, fmt.Errorf("附件大小超出限制")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, "tmp"), "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(r, meta.Size))
+	closeErr := tmp.Close()
+	if err != nil {
+		return nil, err
+	}
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	if written != meta.Size {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	meta.Hash = hex.EncodeToString(hasher.Sum(nil))
+	meta.Name = SanitizeName(meta.Name)
+	meta.Timestamp = time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	blob := s.blobPath(meta.Hash)
+	if _, err := os.Stat(blob); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(blob), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmp.Name(), blob); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; attempt < idAttempts; attempt++ {
+		id, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		meta.ID = id
+		err = s.writeMeta(&meta)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &meta, nil
+	}
+	return nil, fmt.Errorf("生成附件下载ID失败")
+}
+
+// writeMeta 创建元数据文件，文件已存在时返回 os.ErrExist，不会覆盖已有附件
+func (s *Store) writeMeta(meta *Attachment) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.metaPath(meta.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// newID 生成随机下载ID
+func newID() (string, error) {
+	buf := make([]byte, idBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Get 按下载ID获取附件元数据
+func (s *Store) Get(id string) (*Attachment, error) {
+	if !isID(id) {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(s.metaPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var meta Attachment
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// Open 打开附件内容
+func (s *Store) Open(meta *Attachment) (io.ReadCloser, error) {
+	return os.Open(s.blobPath(meta.Hash))
+}
+
+// CanAccess 判断用户是否可以下载附件
+//
+// 发送者和私人附件的接收者总是可以下载；房间附件还需要 canReadRoom 允许用户访问附件所在的房间，
+// 没有记录房间的旧附件只有发送者可以下载。
+func (a *Attachment) CanAccess(userID string, canReadRoom func(room string) bool) bool {
+	if a.FromID == userID || (a.ToID != "" && a.ToID == userID) {
+		return true
+	}
+	return a.To == RoomTarget && a.Room != "" && canReadRoom(a.Room)
+}
+
+// blobPath 内容文件路径，按哈希前两位分目录
+func (s *Store) blobPath(hash string) string {
+	return filepath.Join(s.dir, "blobs", hash[:2], hash)
+}
+
+// metaPath 元数据文件路径
+func (s *Store) metaPath(id string) string {
+	return filepath.Join(s.dir, "meta", id+".json")
+}
+
+// isID 判断是否为合法的下载ID，防止路径穿越
+func isID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// SanitizeName 清理文件名：去掉目录部分、控制字符并限制长度
+func SanitizeName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	var b strings.Builder
+	count := 0
+	for _, r := range name {
+		if unicode.IsControl(r) || r == '/' {
+			continue
+		}
+		if count >= maxNameRunes {
+			break
+		}
+		b.WriteRune(r)
+		count++
+	}
+	result := strings.TrimSpace(b.String())
+	if result == "" || result == "." || result == ".." {
+		return "file"
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package attachment
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPutID(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		meta, err := store.Put(Attachment{Name: "a.txt", Size: 5, To: RoomTarget}, strings.NewReader("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(meta.ID) != idBytes*2 || !isID(meta.ID) {
+			t.Fatalf("下载ID %q 不是%d位十六进制串", meta.ID, idBytes*2)
+		}
+		if seen[meta.ID] {
+			t.Fatalf("下载ID %q 重复", meta.ID)
+		}
+		seen[meta.ID] = true
+	}
+}
+
+func TestWriteMetaExclusive(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := &Attachment{ID: "abcd", Name: "first.txt"}
+	if err := store.writeMeta(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.writeMeta(&Attachment{ID: "abcd", Name: "second.txt"}); !os.IsExist(err) {
+		t.Fatalf("重复的下载ID应该返回 ErrExist，实际为 %v", err)
+	}
+	meta, err := store.Get("abcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Name != "first.txt" {
+		t.Fatalf("已有附件被覆盖: %q", meta.Name)
+	}
+}
+
+func TestPutSize(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(Attachment{Name: "a.txt", Size: 5}, strings.NewReader("hello")); err == nil {
+		t.Fatal("超过大小限制的附件应该被拒绝")
+	}
+	if _, err := store.Put(Attachment{Name: "a.txt", Size: 4}, strings.NewReader("hi")); err == nil {
+		t.Fatal("内容不足声明大小时应该失败")
+	}
+}
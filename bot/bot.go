@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"chatroom/i18n"
+	"chatroom/nickname"
+	"chatroom/room"
+	"chatroom/store"
+)
+
+// 令牌参数
+const (
+	tokenBytes = 20 // 令牌的随机字节数，编码为40个十六进制字符
+	idLength   = 6  // 编号长度（令牌哈希的前几位）
+)
+
+// ErrUnknownToken 令牌不属于任何机器人
+var ErrUnknownToken = errors.New("无效的令牌")
+
+// Bot 通过入站 webhook 在房间中发言的机器人
+type Bot struct {
+	ID      string    `json:"id"`      // 编号，用于管理，不能用来发言
+	Name    string    `json:"name"`    // 发言时显示的名字
+	Room    string    `json:"room"`    // 发言的房间
+	Creator string    `json:"creator"` // 创建者用户名
+	Created time.Time `json:"created"` // 创建时间
+}
+
+// Manager 机器人管理器
+//
+// 机器人以令牌的 SHA-256 哈希为键保存在状态后端中，各实例共享；令牌本身只在创建时显示一次，
+// 泄露后只能删除机器人重新创建。
+type Manager struct {
+	backend store.BotStore   // 状态后端
+	policy  *nickname.Policy // 用户名策略，机器人的名字遵循相同的规则
+}
+
+// NewManager 创建机器人管理器
+func NewManager(backend store.BotStore, policy *nickname.Policy) *Manager {
+	return &Manager{backend: backend, policy: policy}
+}
+
+// Create 创建在房间中发言的机器人，返回机器人和令牌
+func (m *Manager) Create(name, roomName, creator string) (Bot, string, error) {
+	name, err := m.policy.Normalize(name)
+	if err != nil {
+		return Bot{}, "", err
+	}
+	roomName, err = room.Normalize(roomName)
+	if err != nil {
+		return Bot{}, "", err
+	}
+
+	bots, err := m.load()
+	if err != nil {
+		return Bot{}, "", err
+	}
+	ids := make(map[string]bool, len(bots))
+	for _, b := range bots {
+		ids[b.ID] = true
+	}
+
+	buf := make([]byte, tokenBytes)
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			return Bot{}, "", err
+		}
+		token := hex.EncodeToString(buf)
+		key := hashToken(token)
+		if ids[key[:idLength]] {
+			continue
+		}
+		b := Bot{ID: key[:idLength], Name: name, Room: roomName, Creator: creator, Created: time.Now()}
+		data, err := json.Marshal(b)
+		if err != nil {
+			return Bot{}, "", err
+		}
+		return b, token, m.backend.SaveBot(key, string(data))
+	}
+}
+
+// Authenticate 按令牌查找机器人
+func (m *Manager) Authenticate(token string) (Bot, bool, error) {
+	bots, err := m.load()
+	if err != nil {
+		return Bot{}, false, err
+	}
+	b, exists := bots[hashToken(token)]
+	return b, exists, nil
+}
+
+// Remove 按编号删除机器人
+func (m *Manager) Remove(id string) (Bot, error) {
+	bots, err := m.load()
+	if err != nil {
+		return Bot{}, err
+	}
+	id = strings.ToLower(strings.TrimPrefix(id, "#"))
+	for key, b := range bots {
+		if b.ID == id {
+			return b, m.backend.DeleteBot(key)
+		}
+	}
+	return Bot{}, i18n.Errorf("error.bot_not_found", i18n.Params{"id": id})
+}
+
+// List 获取所有机器人（按名字排序）
+func (m *Manager) List() ([]Bot, error) {
+	bots, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Bot, 0, len(bots))
+	for _, b := range bots {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// load 从后端加载所有机器人，跳过无法解析的记录
+func (m *Manager) load() (map[string]Bot, error) {
+	entries, err := m.backend.Bots()
+	if err != nil {
+		return nil, err
+	}
+	bots := make(map[string]Bot, len(entries))
+	for key, entry := range entries {
+		var b Bot
+		if err := json.Unmarshal([]byte(entry), &b); err != nil {
+			continue
+		}
+		bots[key] = b
+	}
+	return bots, nil
+}
+
+// hashToken 计算令牌的哈希，后端只保存哈希
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
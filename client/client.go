@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"chatroom/e2e"
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/nickname"
+)
+
+// Client 命令行客户端
+//
+// 连接后切换到结构化(JSON)输出，在本地把事件渲染为文本。端到端加密私聊在本地完成：
+// 输入 \ewhisper <用户名> <内容> 时先向服务器查询对方公钥，加密签名后只发送密封信封；
+// 收到的密封信封在本地解密，并用发送者的公钥验证签名。明文不会离开客户端。
+type Client struct {
+	conn   net.Conn                    // 服务器连接
+	keys   *e2e.KeyPair                // 本地密钥对
+	mutex  sync.Mutex                  // 保护以下字段
+	lang   string                      // 界面语言
+	peers  map[string]e2e.PublicKey    // 已查询到的公钥 (比较键 -> 公钥)
+	outbox map[string][]string         // 等待对方公钥的待发送内容 (比较键 -> 内容)
+	inbox  map[string][]message.Sealed // 等待发送者公钥的密封信封 (比较键 -> 信封)
+}
+
+func main() {
+	home, _ := os.UserHomeDir()
+	var (
+		keyFile = flag.String("key", filepath.Join(home, ".chatroom_e2e_key"), "端到端加密密钥文件，不存在时自动生成")
+		lang    = flag.String("lang", i18n.DefaultLang, "界面语言 (zh-CN 或 en-US)")
+	)
+	flag.Parse()
+
+	host, port := "127.0.0.1", "8080"
+	if args := flag.Args(); len(args) >= 2 {
+		host, port = args[0], args[1]
+	} else if len(args) == 1 {
+		host = args[0]
+	}
+
+	keys, err := loadKeys(*keyFile)
+	if err != nil {
+		fmt.Printf("加载密钥失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		fmt.Printf("连接服务器失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	normalized, ok := i18n.Normalize(*lang)
+	if !ok {
+		normalized = i18n.DefaultLang
+	}
+	client := &Client{
+		conn:   conn,
+		keys:   keys,
+		lang:   normalized,
+		peers:  make(map[string]e2e.PublicKey),
+		outbox: make(map[string][]string),
+		inbox:  make(map[string][]message.Sealed),
+	}
+
+	// 切换到结构化输出并发布公钥
+	client.send("\\lang " + normalized)
+	client.send("\\format json")
+	public := keys.Public()
+	client.send("\\pubkey " + public.BoxString() + " " + public.SignString())
+	fmt.Println(i18n.T(normalized, "e2e.enabled", i18n.Params{"fingerprint": public.Fingerprint(), "path": *keyFile}))
+
+	// 处理退出信号
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		conn.Close()
+	}()
+
+	// 接收服务器消息，连接关闭后退出
+	done := make(chan struct{})
+	go func() {
+		client.receive()
+		close(done)
+	}()
+
+	// 读取用户输入
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			client.handleInput(strings.TrimSpace(scanner.Text()))
+		}
+		conn.Close()
+	}()
+	<-done
+}
+
+// loadKeys 从文件加载密钥对，文件不存在时生成并保存（仅所有者可读）
+func loadKeys(path string) (*e2e.KeyPair, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return e2e.ParseKeyPair(string(data))
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	keys, err := e2e.GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(keys.Marshal()+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// send 向服务器发送一行
+func (c *Client) send(line string) {
+	fmt.Fprintf(c.conn, "%s\n", line)
+}
+
+// handleInput 处理用户输入，\ewhisper 在本地加密，其余原样发送
+func (c *Client) handleInput(input string) {
+	if input == "" {
+		return
+	}
+	fields := strings.SplitN(input, " ", 3)
+	switch strings.ToLower(fields[0]) {
+	case "\\ewhisper", "\\ew":
+		if len(fields) < 3 || strings.TrimSpace(fields[2]) == "" {
+			break
+		}
+		c.whisper(fields[1], fields[2])
+		return
+	case "\\lang":
+		if len(fields) > 1 {
+			if lang, ok := i18n.Normalize(fields[1]); ok {
+				c.mutex.Lock()
+				c.lang = lang
+				c.mutex.Unlock()
+			}
+		}
+	}
+	c.send(input)
+}
+
+// whisper 加密发送私聊，还没有对方公钥时先查询，收到公钥后再发送
+func (c *Client) whisper(name, text string) {
+	key := nickname.Key(name)
+	c.mutex.Lock()
+	peer, known := c.peers[key]
+	if !known {
+		c.outbox[key] = append(c.outbox[key], text)
+	}
+	c.mutex.Unlock()
+
+	if !known {
+		c.send("\\pubkey " + name)
+		return
+	}
+	c.seal(name, peer, text)
+}
+
+// seal 用对方公钥加密签名后发送密封信封
+func (c *Client) seal(name string, peer e2e.PublicKey, text string) {
+	box, err := e2e.Seal(peer, c.keys, text)
+	if err != nil {
+		c.print(i18n.T(c.currentLang(), "e2e.seal_failed", i18n.Params{"error": err}))
+		return
+	}
+	c.send("\\ewhisper " + name + " " + box)
+}
+
+// receive 读取服务器发来的每一行，JSON事件在本地渲染，其他内容（如切换输出模式前的欢迎消息）原样显示
+func (c *Client) receive() {
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var event message.Event
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &event) != nil || event.Type == "" {
+			c.print(line)
+			continue
+		}
+
+		switch {
+		case event.Type == message.EventKey && event.Key != nil:
+			c.handleKey(*event.Key)
+		case event.Type == message.EventSealed && event.Sealed != nil:
+			c.handleSealed(*event.Sealed)
+		default:
+			c.print(strings.TrimSuffix(event.FormatText(c.currentLang(), false), "\n"))
+		}
+	}
+	fmt.Println("与服务器的连接已断开")
+}
+
+// handleKey 记录查询到的公钥，并处理等待该公钥的待发送内容和密封信封
+func (c *Client) handleKey(info message.Key) {
+	peer, err := e2e.ParsePublicKey(info.Box, info.Sign)
+	if err != nil {
+		return
+	}
+	lang := c.currentLang()
+	key := nickname.Key(info.User)
+
+	c.mutex.Lock()
+	previous, known := c.peers[key]
+	c.peers[key] = peer
+	pending := c.outbox[key]
+	delete(c.outbox, key)
+	sealed := c.inbox[key]
+	delete(c.inbox, key)
+	c.mutex.Unlock()
+
+	// 指纹在本地计算，不信任服务器提供的指纹
+	if known && previous.String() != peer.String() {
+		c.print(i18n.T(lang, "e2e.key_changed", i18n.Params{"name": info.User, "fingerprint": peer.Fingerprint()}))
+	} else if len(pending) == 0 && len(sealed) == 0 {
+		c.print(i18n.T(lang, "event.pubkey", i18n.Params{"name": info.User, "fingerprint": peer.Fingerprint()}))
+	}
+	for _, text := range pending {
+		c.seal(info.User, peer, text)
+	}
+	for _, s := range sealed {
+		c.open(s, peer)
+	}
+}
+
+// handleSealed 处理收到的密封信封，还没有发送者公钥时先查询
+func (c *Client) handleSealed(s message.Sealed) {
+	key := nickname.Key(s.From)
+	c.mutex.Lock()
+	peer, known := c.peers[key]
+	if !known {
+		c.inbox[key] = append(c.inbox[key], s)
+	}
+	c.mutex.Unlock()
+
+	if !known {
+		c.send("\\pubkey " + s.From)
+		return
+	}
+	c.open(s, peer)
+}
+
+// open 解密密封信封并验证发送者的签名，验证失败时丢弃
+func (c *Client) open(s message.Sealed, sender e2e.PublicKey) {
+	lang := c.currentLang()
+	letter, err := e2e.Open(c.keys, s.Box)
+	if err != nil {
+		c.print(i18n.T(lang, "e2e.undecryptable", i18n.Params{"from": s.From}))
+		return
+	}
+	if !letter.Verify(sender) {
+		c.print(i18n.T(lang, "e2e.bad_signature", i18n.Params{"from": s.From}))
+		return
+	}
+	c.print(i18n.T(lang, "e2e.received", i18n.Params{"from": s.From, "text": letter.Text}))
+}
+
+// currentLang 获取当前界面语言
+func (c *Client) currentLang() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lang
+}
+
+// print 显示一行
+func (c *Client) print(line string) {
+	fmt.Println(line)
+}
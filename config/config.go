@@ -4,29 +4,72 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/nickname"
 )
 
 // Config 服务器配置
 type Config struct {
-	Host       string
-	Port       int
-	MaxUsers   int
-	Timeout    int
-	BufferSize int
-	LogLevel   string
-	EnableLogs bool
+	Host        string
+	Port        int
+	MaxUsers    int
+	Timeout     int
+	IdleTimeout int // 多少秒未活动后视为空闲
+	BufferSize  int
+	LogLevel    string
+	EnableLogs  bool
+	Store       string // 状态后端: memory 或 redis
+	RedisAddr   string // Redis地址
+	HistorySize int    // 每个房间保留的历史消息数
+	Language    string // 默认界面语言
+
+	MaxNameWidth  int      // 用户名最大显示宽度
+	ReservedNames []string // 保留用户名
+
+	OperatorPassword string // 管理员密码，为空表示不启用 \oper
+
+	AttachmentDir     string // 附件存储目录
+	MaxAttachmentSize int64  // 单个附件的最大大小（字节）
+
+	FilterFile   string // 内容过滤规则文件，为空表示不启用过滤
+	MOTDFile     string // 每日消息文件，为空表示不显示
+	WebhooksFile string // webhook 配置文件，为空表示不发送 webhook
+
+	HTTPAddr  string // HTTP接口监听地址（入站 webhook 等），为空表示不启用
+	ReposFile string // GitHub/GitLab 仓库事件路由文件，为空表示不接收仓库事件
+
+	StreamToken string // 只读事件流 (GET /rooms/<房间>/events) 的访问令牌，为空表示不启用
+	APIToken    string // 创建 REST 接口会话的访问令牌，为空表示不启用 REST 接口
+
+	ExportDir string // 命令行导出聊天记录时的默认输出目录
 }
 
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
-		Host:       "127.0.0.1",
-		Port:       8080,
-		MaxUsers:   100,
-		Timeout:    40,
-		BufferSize: 1024,
-		LogLevel:   "INFO",
-		EnableLogs: true,
+		Host:        "127.0.0.1",
+		Port:        8080,
+		MaxUsers:    100,
+		Timeout:     40,
+		IdleTimeout: 20,
+		BufferSize:  1024,
+		LogLevel:    "INFO",
+		EnableLogs:  true,
+		Store:       "memory",
+		RedisAddr:   "127.0.0.1:6379",
+		HistorySize: 500,
+		Language:    "zh-CN",
+
+		MaxNameWidth:  nickname.DefaultMaxWidth,
+		ReservedNames: append([]string{}, nickname.DefaultReserved...),
+
+		AttachmentDir:     "data/attachments",
+		MaxAttachmentSize: 1024 * 1024,
+
+		ExportDir: "data/exports",
 	}
 }
 
@@ -54,9 +97,89 @@ func (c *Config) LoadFromEnv() {
 		}
 	}
 
+	if idleStr := os.Getenv("CHATROOM_IDLE_TIMEOUT"); idleStr != "" {
+		if idle, err := strconv.Atoi(idleStr); err == nil {
+			c.IdleTimeout = idle
+		}
+	}
+
 	if logLevel := os.Getenv("CHATROOM_LOG_LEVEL"); logLevel != "" {
 		c.LogLevel = logLevel
 	}
+
+	if storeName := os.Getenv("CHATROOM_STORE"); storeName != "" {
+		c.Store = storeName
+	}
+
+	if redisAddr := os.Getenv("CHATROOM_REDIS_ADDR"); redisAddr != "" {
+		c.RedisAddr = redisAddr
+	}
+
+	if lang := os.Getenv("CHATROOM_LANG"); lang != "" {
+		c.Language = lang
+	}
+
+	if widthStr := os.Getenv("CHATROOM_MAX_NAME_WIDTH"); widthStr != "" {
+		if width, err := strconv.Atoi(widthStr); err == nil {
+			c.MaxNameWidth = width
+		}
+	}
+
+	if reserved := os.Getenv("CHATROOM_RESERVED_NAMES"); reserved != "" {
+		c.ReservedNames = strings.Split(reserved, ",")
+	}
+
+	if password := os.Getenv("CHATROOM_OPER_PASSWORD"); password != "" {
+		c.OperatorPassword = password
+	}
+
+	if attachmentDir := os.Getenv("CHATROOM_ATTACHMENT_DIR"); attachmentDir != "" {
+		c.AttachmentDir = attachmentDir
+	}
+
+	if maxSizeStr := os.Getenv("CHATROOM_MAX_ATTACHMENT_SIZE"); maxSizeStr != "" {
+		if maxSize, err := strconv.ParseInt(maxSizeStr, 10, 64); err == nil {
+			c.MaxAttachmentSize = maxSize
+		}
+	}
+
+	if filterFile := os.Getenv("CHATROOM_FILTER_FILE"); filterFile != "" {
+		c.FilterFile = filterFile
+	}
+
+	if motdFile := os.Getenv("CHATROOM_MOTD_FILE"); motdFile != "" {
+		c.MOTDFile = motdFile
+	}
+
+	if webhooksFile := os.Getenv("CHATROOM_WEBHOOKS_FILE"); webhooksFile != "" {
+		c.WebhooksFile = webhooksFile
+	}
+
+	if httpAddr := os.Getenv("CHATROOM_HTTP_ADDR"); httpAddr != "" {
+		c.HTTPAddr = httpAddr
+	}
+
+	if reposFile := os.Getenv("CHATROOM_REPOS_FILE"); reposFile != "" {
+		c.ReposFile = reposFile
+	}
+
+	if streamToken := os.Getenv("CHATROOM_STREAM_TOKEN"); streamToken != "" {
+		c.StreamToken = streamToken
+	}
+
+	if apiToken := os.Getenv("CHATROOM_API_TOKEN"); apiToken != "" {
+		c.APIToken = apiToken
+	}
+
+	if exportDir := os.Getenv("CHATROOM_EXPORT_DIR"); exportDir != "" {
+		c.ExportDir = exportDir
+	}
+
+	if historySizeStr := os.Getenv("CHATROOM_HISTORY_SIZE"); historySizeStr != "" {
+		if historySize, err := strconv.Atoi(historySizeStr); err == nil {
+			c.HistorySize = historySize
+		}
+	}
 }
 
 // GetAddress 获取服务器地址
@@ -75,5 +198,28 @@ func (c *Config) Validate() error {
 	if c.Timeout < 1 {
 		return fmt.Errorf("超时时间必须大于0")
 	}
+	if c.IdleTimeout < 1 {
+		return fmt.Errorf("空闲判定时间必须大于0")
+	}
+	if c.Store != "memory" && c.Store != "redis" {
+		return fmt.Errorf("状态后端必须是memory或redis")
+	}
+	if c.HistorySize < 0 {
+		return fmt.Errorf("历史消息数不能为负数")
+	}
+	if c.MaxAttachmentSize < 1 {
+		return fmt.Errorf("附件最大大小必须大于0")
+	}
+	if c.MaxAttachmentSize > message.MaxSendSize {
+		return fmt.Errorf("附件最大大小不能超过%d字节", message.MaxSendSize)
+	}
+	if c.MaxNameWidth < 1 {
+		return fmt.Errorf("用户名最大宽度必须大于0")
+	}
+	lang, ok := i18n.Normalize(c.Language)
+	if !ok {
+		return fmt.Errorf("不支持的语言: %s", c.Language)
+	}
+	c.Language = lang
 	return nil
 }
@@ -0,0 +1,310 @@
+package dm
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"chatroom/i18n"
+	"chatroom/nickname"
+	"chatroom/store"
+)
+
+// 私聊群组的限制
+const (
+	MaxMembers   = 10 // 每个群组最多成员数（包括创建者）
+	handleLength = 4  // 代号长度
+)
+
+// handleAlphabet 代号使用的字符，去掉了容易混淆的 0/o、1/l/i
+const handleAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// Conversation 私聊群组
+type Conversation struct {
+	Handle  string            `json:"handle"`  // 代号
+	Members map[string]string `json:"members"` // 成员 (用户ID -> 用户名)
+	Creator string            `json:"creator"` // 创建者用户名
+	Created time.Time         `json:"created"` // 创建时间
+}
+
+// Has 判断用户是否为群组成员
+//
+// 成员按用户ID记录：用户名在原用户离开后可以被别人使用，用户ID只属于一次连接，
+// 用户离开聊天室时退出所有群组（见 Manager.Forget）。
+func (c *Conversation) Has(userID string) bool {
+	_, exists := c.Members[userID]
+	return exists
+}
+
+// Member 按用户名查找成员的用户ID，比较时忽略大小写和易混淆字符
+func (c *Conversation) Member(name string) (string, bool) {
+	key := nickname.Key(name)
+	for id, member := range c.Members {
+		if nickname.Key(member) == key {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// Names 获取成员用户名（按字母顺序）
+func (c *Conversation) Names() []string {
+	names := make([]string, 0, len(c.Members))
+	for _, name := range c.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Prefix 返回群组消息的前缀，如 "[DM:k3f9]"
+func (c *Conversation) Prefix() string {
+	return "[DM:" + c.Handle + "]"
+}
+
+// Target 用户最近的私聊对象，\r 回复到这里
+type Target struct {
+	Handle string // 私聊群组代号
+	Name   string // 一对一私聊的对方用户名
+}
+
+// encode 编码为后端保存的格式：群组为 "dm:<代号>"，一对一私聊为 "@<用户名>"
+func (t Target) encode() string {
+	if t.Handle != "" {
+		return "dm:" + t.Handle
+	}
+	return "@" + t.Name
+}
+
+// decodeTarget 解析后端保存的私聊对象
+func decodeTarget(value string) (Target, bool) {
+	if handle, ok := strings.CutPrefix(value, "dm:"); ok && handle != "" {
+		return Target{Handle: handle}, true
+	}
+	if name, ok := strings.CutPrefix(value, "@"); ok && name != "" {
+		return Target{Name: name}, true
+	}
+	return Target{}, false
+}
+
+// Manager 私聊群组管理器
+//
+// 群组和每个用户最近的私聊对象都保存在状态后端中，成员可以连接在不同的实例。
+// 群组在最后一个成员离开时删除。
+type Manager struct {
+	backend store.ConversationStore // 状态后端
+}
+
+// NewManager 创建私聊群组管理器
+func NewManager(backend store.ConversationStore) *Manager {
+	return &Manager{backend: backend}
+}
+
+// Open 创建私聊群组，others 为创建者之外的成员 (用户ID -> 用户名，调用者负责确认他们在线)
+//
+// 已有成员完全相同的群组时直接返回该群组，避免重复创建。
+func (m *Manager) Open(creatorID, creator string, others map[string]string) (Conversation, error) {
+	members := map[string]string{creatorID: creator}
+	for id, name := range others {
+		members[id] = name
+	}
+	if len(members) < 2 || len(members) > MaxMembers {
+		return Conversation{}, i18n.Errorf("error.dm_members", i18n.Params{"max": MaxMembers - 1})
+	}
+
+	conversations, err := m.load()
+	if err != nil {
+		return Conversation{}, err
+	}
+	for _, c := range conversations {
+		if sameMembers(c.Members, members) {
+			return c, nil
+		}
+	}
+
+	handle, err := newHandle(conversations)
+	if err != nil {
+		return Conversation{}, err
+	}
+	c := Conversation{Handle: handle, Members: members, Creator: creator, Created: time.Now()}
+	return c, m.save(c)
+}
+
+// Find 查找用户所在的私聊群组，群组不存在或用户不是成员时返回错误
+func (m *Manager) Find(handle, userID string) (Conversation, error) {
+	conversations, err := m.load()
+	if err != nil {
+		return Conversation{}, err
+	}
+	c, exists := conversations[strings.ToLower(handle)]
+	if !exists || !c.Has(userID) {
+		return Conversation{}, i18n.Errorf("error.dm_not_found", i18n.Params{"handle": handle})
+	}
+	return c, nil
+}
+
+// Add 由用户ID为 byID 的成员把用户加入群组
+func (m *Manager) Add(handle, byID, userID, name string) (Conversation, error) {
+	c, err := m.Find(handle, byID)
+	if err != nil {
+		return Conversation{}, err
+	}
+	if c.Has(userID) {
+		return Conversation{}, i18n.Errorf("error.dm_already_member", i18n.Params{"name": name, "handle": c.Handle})
+	}
+	if len(c.Members) >= MaxMembers {
+		return Conversation{}, i18n.Errorf("error.dm_full", i18n.Params{"max": MaxMembers})
+	}
+	c.Members[userID] = name
+	return c, m.save(c)
+}
+
+// Remove 由用户ID为 byID 的成员把用户移出群组，byID 和 userID 相同时表示离开；返回移除的用户名
+//
+// 最后一个成员离开后群组被删除。
+func (m *Manager) Remove(handle, byID, userID string) (Conversation, string, error) {
+	c, err := m.Find(handle, byID)
+	if err != nil {
+		return Conversation{}, "", err
+	}
+	removed, exists := c.Members[userID]
+	if !exists {
+		return Conversation{}, "", i18n.Errorf("error.dm_not_found", i18n.Params{"handle": handle})
+	}
+	delete(c.Members, userID)
+	return c, removed, m.store(c)
+}
+
+// Rename 用户改名后更新其所在群组中显示的用户名
+func (m *Manager) Rename(userID, name string) error {
+	list, err := m.List(userID)
+	if err != nil {
+		return err
+	}
+	for _, c := range list {
+		c.Members[userID] = name
+		if err := m.save(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Forget 用户离开聊天室时退出所有群组，返回用户退出后的群组（最近的私聊对象随用户注销清除）
+func (m *Manager) Forget(userID string) ([]Conversation, error) {
+	list, err := m.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range list {
+		delete(c.Members, userID)
+		if err := m.store(c); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// List 获取用户所在的所有私聊群组（按代号排序）
+func (m *Manager) List(userID string) ([]Conversation, error) {
+	conversations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	var list []Conversation
+	for _, c := range conversations {
+		if c.Has(userID) {
+			list = append(list, c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Handle < list[j].Handle
+	})
+	return list, nil
+}
+
+// IsHandle 判断是否为用户所在群组的代号
+func (m *Manager) IsHandle(handle, userID string) bool {
+	_, err := m.Find(handle, userID)
+	return err == nil
+}
+
+// Remember 记录用户最近的私聊对象
+func (m *Manager) Remember(userID string, target Target) error {
+	return m.backend.SetReplyTarget(userID, target.encode())
+}
+
+// LastTarget 获取用户最近的私聊对象
+func (m *Manager) LastTarget(userID string) (Target, bool, error) {
+	value, err := m.backend.ReplyTarget(userID)
+	if err != nil {
+		return Target{}, false, err
+	}
+	target, ok := decodeTarget(value)
+	return target, ok, nil
+}
+
+// load 从后端加载所有群组，跳过无法解析的记录
+func (m *Manager) load() (map[string]Conversation, error) {
+	entries, err := m.backend.Conversations()
+	if err != nil {
+		return nil, err
+	}
+	conversations := make(map[string]Conversation, len(entries))
+	for handle, entry := range entries {
+		var c Conversation
+		if err := json.Unmarshal([]byte(entry), &c); err != nil {
+			continue
+		}
+		conversations[handle] = c
+	}
+	return conversations, nil
+}
+
+// store 保存成员变化后的群组，没有成员时删除
+func (m *Manager) store(c Conversation) error {
+	if len(c.Members) == 0 {
+		return m.backend.DeleteConversation(c.Handle)
+	}
+	return m.save(c)
+}
+
+// save 保存群组到后端
+func (m *Manager) save(c Conversation) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return m.backend.SaveConversation(c.Handle, string(data))
+}
+
+// sameMembers 判断两个成员集合是否相同
+func sameMembers(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if _, exists := b[key]; !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// newHandle 生成未被使用的随机代号
+func newHandle(existing map[string]Conversation) (string, error) {
+	buf := make([]byte, handleLength)
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for i, b := range buf {
+			buf[i] = handleAlphabet[int(b)%len(handleAlphabet)]
+		}
+		if _, taken := existing[string(buf)]; !taken {
+			return string(buf), nil
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package dm
+
+import (
+	"testing"
+
+	"chatroom/store"
+)
+
+func TestMembershipByUserID(t *testing.T) {
+	m := NewManager(store.NewMemoryBackend())
+
+	c, err := m.Open("user_a", "Alice", map[string]string{"user_b": "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Find(c.Handle, "user_b"); err != nil {
+		t.Fatalf("成员应该可以找到群组: %v", err)
+	}
+	if _, err := m.Find(c.Handle, "user_c"); err == nil {
+		t.Fatal("不是成员的用户ID不应该找到群组")
+	}
+	if id, ok := c.Member("bob"); !ok || id != "user_b" {
+		t.Fatalf("Member(bob) = %q, %v", id, ok)
+	}
+
+	if err := m.Rename("user_b", "Bobby"); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ = m.Find(c.Handle, "user_a"); c.Members["user_b"] != "Bobby" {
+		t.Fatalf("改名后群组中的用户名应该更新: %v", c.Members)
+	}
+
+	left, err := m.Forget("user_b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].Has("user_b") {
+		t.Fatalf("Forget 应该返回用户退出后的群组: %v", left)
+	}
+	if _, err := m.Find(c.Handle, "user_b"); err == nil {
+		t.Fatal("用户离开后不应该还是成员")
+	}
+
+	if _, err := m.Forget("user_a"); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := m.List("user_a"); len(list) != 0 {
+		t.Fatal("最后一个成员离开后群组应该被删除")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	m := NewManager(store.NewMemoryBackend())
+	c, err := m.Open("user_a", "Alice", map[string]string{"user_b": "Bob", "user_c": "Carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.Remove(c.Handle, "user_x", "user_b"); err == nil {
+		t.Fatal("不是成员的用户不能移除别人")
+	}
+	c, removed, err := m.Remove(c.Handle, "user_a", "user_b")
+	if err != nil || removed != "Bob" || c.Has("user_b") {
+		t.Fatalf("Remove = %v, %q, %v", c.Members, removed, err)
+	}
+}
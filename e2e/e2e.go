@@ -0,0 +1,260 @@
+package e2e
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 密封信封的格式：临时公钥(32字节) || 随机数(12字节) || AES-256-GCM 密文，整体使用标准 base64 编码。
+// 密钥由临时私钥和接收者公钥的 X25519 共享密钥经 SHA-256 派生，服务器只能看到接收者和长度。
+const (
+	keySize   = 32
+	nonceSize = 12
+	tagSize   = 16
+	overhead  = keySize + nonceSize + tagSize
+
+	// MaxBoxLength 密封信封编码后的最大长度
+	MaxBoxLength = 8192
+
+	kdfLabel  = "chatroom-e2e-v1"         // 密钥派生的标签
+	signLabel = "chatroom-e2e-sig-v1\x00" // 签名的标签
+)
+
+var encoding = base64.StdEncoding
+
+// ErrMalformed 密封信封或公钥格式错误
+var ErrMalformed = errors.New("格式错误")
+
+// PublicKey 用户发布到服务器密钥目录的公钥：X25519 用于加密，Ed25519 用于验证签名
+type PublicKey struct {
+	Box  *ecdh.PublicKey   // 加密公钥
+	Sign ed25519.PublicKey // 签名公钥
+}
+
+// ParsePublicKey 解析 base64 编码的加密公钥和签名公钥
+func ParsePublicKey(box, sign string) (PublicKey, error) {
+	boxBytes, err := encoding.DecodeString(box)
+	if err != nil || len(boxBytes) != keySize {
+		return PublicKey{}, ErrMalformed
+	}
+	boxKey, err := ecdh.X25519().NewPublicKey(boxBytes)
+	if err != nil {
+		return PublicKey{}, ErrMalformed
+	}
+	signBytes, err := encoding.DecodeString(sign)
+	if err != nil || len(signBytes) != ed25519.PublicKeySize {
+		return PublicKey{}, ErrMalformed
+	}
+	return PublicKey{Box: boxKey, Sign: ed25519.PublicKey(signBytes)}, nil
+}
+
+// DecodePublicKey 解析 String 的输出
+func DecodePublicKey(value string) (PublicKey, error) {
+	box, sign, ok := strings.Cut(value, " ")
+	if !ok {
+		return PublicKey{}, ErrMalformed
+	}
+	return ParsePublicKey(box, sign)
+}
+
+// BoxString 返回 base64 编码的加密公钥
+func (p PublicKey) BoxString() string {
+	return encoding.EncodeToString(p.Box.Bytes())
+}
+
+// SignString 返回 base64 编码的签名公钥
+func (p PublicKey) SignString() string {
+	return encoding.EncodeToString(p.Sign)
+}
+
+// String 返回 "加密公钥 签名公钥"，用于保存和 \pubkey 命令
+func (p PublicKey) String() string {
+	return p.BoxString() + " " + p.SignString()
+}
+
+// Fingerprint 返回公钥指纹，供用户在带外核对，如 "3f2a 9c01 77be 0d45"
+func (p PublicKey) Fingerprint() string {
+	sum := sha256.Sum256(append(p.Box.Bytes(), p.Sign...))
+	hexSum := hex.EncodeToString(sum[:8])
+	var groups []string
+	for i := 0; i < len(hexSum); i += 4 {
+		groups = append(groups, hexSum[i:i+4])
+	}
+	return strings.Join(groups, " ")
+}
+
+// KeyPair 客户端保存的密钥对
+type KeyPair struct {
+	Box  *ecdh.PrivateKey   // 加密私钥
+	Sign ed25519.PrivateKey // 签名私钥
+}
+
+// GenerateKeyPair 生成新的密钥对
+func GenerateKeyPair() (*KeyPair, error) {
+	box, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	_, sign, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyPair{Box: box, Sign: sign}, nil
+}
+
+// ParseKeyPair 解析 Marshal 的输出
+func ParseKeyPair(value string) (*KeyPair, error) {
+	boxPart, signPart, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok {
+		return nil, ErrMalformed
+	}
+	boxBytes, err := encoding.DecodeString(boxPart)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	box, err := ecdh.X25519().NewPrivateKey(boxBytes)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	seed, err := encoding.DecodeString(signPart)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, ErrMalformed
+	}
+	return &KeyPair{Box: box, Sign: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// Marshal 编码私钥（加密私钥和签名种子），用于保存到本地文件
+func (k *KeyPair) Marshal() string {
+	return encoding.EncodeToString(k.Box.Bytes()) + " " + encoding.EncodeToString(k.Sign.Seed())
+}
+
+// Public 返回对应的公钥
+func (k *KeyPair) Public() PublicKey {
+	return PublicKey{Box: k.Box.PublicKey(), Sign: k.Sign.Public().(ed25519.PublicKey)}
+}
+
+// Letter 密封信封中的内容
+//
+// 签名覆盖接收者的加密公钥和正文：服务器无法篡改内容，接收者也无法把信件转投给他人而不被发现。
+// 发送者的身份由服务器转发时标注的用户名及其公钥确认。
+type Letter struct {
+	Text      string `json:"text"` // 正文
+	Signature []byte `json:"sig"`  // 发送者的 Ed25519 签名
+
+	recipient []byte // 接收者的加密公钥，打开信封时填入
+}
+
+// signedData 返回签名覆盖的数据
+func signedData(recipient []byte, text string) []byte {
+	data := append([]byte(signLabel), recipient...)
+	return append(data, text...)
+}
+
+// Verify 用发送者的公钥验证签名
+func (l *Letter) Verify(from PublicKey) bool {
+	return ed25519.Verify(from.Sign, signedData(l.recipient, l.Text), l.Signature)
+}
+
+// Seal 签名并加密私聊内容，返回只有接收者能打开的密封信封
+func Seal(to PublicKey, from *KeyPair, text string) (string, error) {
+	letter := Letter{Text: text, Signature: ed25519.Sign(from.Sign, signedData(to.Box.Bytes(), text))}
+	plaintext, err := json.Marshal(letter)
+	if err != nil {
+		return "", err
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	shared, err := ephemeral.ECDH(to.Box)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(shared, ephemeral.PublicKey(), to.Box)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	header := append(ephemeral.PublicKey().Bytes(), nonce...)
+	sealed := aead.Seal(header, nonce, plaintext, additionalData(ephemeral.PublicKey(), to.Box))
+	box := encoding.EncodeToString(sealed)
+	if len(box) > MaxBoxLength {
+		return "", fmt.Errorf("私聊内容过长")
+	}
+	return box, nil
+}
+
+// Open 用接收者的私钥打开密封信封，调用者还需要用发送者的公钥验证签名
+func Open(k *KeyPair, box string) (*Letter, error) {
+	sealed, err := encoding.DecodeString(box)
+	if err != nil || len(sealed) < overhead {
+		return nil, ErrMalformed
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(sealed[:keySize])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	shared, err := k.Box.ECDH(ephemeral)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	aead, err := newAEAD(shared, ephemeral, k.Box.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	nonce := sealed[keySize : keySize+nonceSize]
+	plaintext, err := aead.Open(nil, nonce, sealed[keySize+nonceSize:], additionalData(ephemeral, k.Box.PublicKey()))
+	if err != nil {
+		return nil, fmt.Errorf("解密失败")
+	}
+
+	var letter Letter
+	if err := json.Unmarshal(plaintext, &letter); err != nil {
+		return nil, ErrMalformed
+	}
+	letter.recipient = k.Box.PublicKey().Bytes()
+	return &letter, nil
+}
+
+// ValidBox 检查密封信封的格式（服务器转发前调用，不需要也无法解密）
+func ValidBox(box string) bool {
+	if len(box) > MaxBoxLength {
+		return false
+	}
+	sealed, err := encoding.DecodeString(box)
+	return err == nil && len(sealed) >= overhead
+}
+
+// newAEAD 从共享密钥派生对称密钥（绑定双方公钥）并创建 AES-256-GCM 加密器
+func newAEAD(shared []byte, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	h := sha256.New()
+	h.Write([]byte(kdfLabel))
+	h.Write(shared)
+	h.Write(ephemeral.Bytes())
+	h.Write(recipient.Bytes())
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData 返回认证但不加密的附加数据
+func additionalData(ephemeral, recipient *ecdh.PublicKey) []byte {
+	return append(ephemeral.Bytes(), recipient.Bytes()...)
+}
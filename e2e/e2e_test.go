@@ -0,0 +1,201 @@
+package e2e
+
+import (
+	"strings"
+	"testing"
+)
+
+// newKeyPair 生成测试用的密钥对
+func newKeyPair(t *testing.T) *KeyPair {
+	t.Helper()
+	k, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// tamper 翻转密封信封解码后第 i 个字节的一位，返回重新编码的信封
+func tamper(t *testing.T, box string, i int) string {
+	t.Helper()
+	sealed, err := encoding.DecodeString(box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i < 0 {
+		i += len(sealed)
+	}
+	sealed[i] ^= 1
+	return encoding.EncodeToString(sealed)
+}
+
+func TestSealOpen(t *testing.T) {
+	alice, bob := newKeyPair(t), newKeyPair(t)
+	box, err := Seal(bob.Public(), alice, "今晚七点见")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ValidBox(box) {
+		t.Fatal("Seal 的输出应通过格式检查")
+	}
+	if strings.Contains(box, "今晚") {
+		t.Fatal("信封中不应出现明文")
+	}
+
+	letter, err := Open(bob, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letter.Text != "今晚七点见" {
+		t.Fatalf("正文 %q", letter.Text)
+	}
+	if !letter.Verify(alice.Public()) {
+		t.Fatal("发送者的签名应通过验证")
+	}
+
+	// 同样的内容每次加密的结果不同
+	again, _ := Seal(bob.Public(), alice, "今晚七点见")
+	if again == box {
+		t.Error("两次加密的结果不应相同")
+	}
+}
+
+func TestOpenWrongRecipient(t *testing.T) {
+	alice, bob, carol := newKeyPair(t), newKeyPair(t), newKeyPair(t)
+	box, err := Seal(bob.Public(), alice, "只给 bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(carol, box); err == nil {
+		t.Fatal("其他人的私钥不应能打开信封")
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	alice, bob := newKeyPair(t), newKeyPair(t)
+	box, err := Seal(bob.Public(), alice, "不能被篡改")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		index int
+	}{
+		{"临时公钥", 0},
+		{"随机数", keySize},
+		{"密文", keySize + nonceSize},
+		{"认证标签", -1},
+	}
+	for _, tt := range tests {
+		if _, err := Open(bob, tamper(t, box, tt.index)); err == nil {
+			t.Errorf("篡改%s后不应能打开信封", tt.name)
+		}
+	}
+}
+
+func TestVerifyBadSignature(t *testing.T) {
+	alice, bob, mallory := newKeyPair(t), newKeyPair(t), newKeyPair(t)
+
+	// 冒充者用自己的签名私钥签名，不能通过 alice 公钥的验证
+	box, err := Seal(bob.Public(), mallory, "我是 alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	letter, err := Open(bob, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letter.Verify(alice.Public()) {
+		t.Fatal("冒充者的签名不应通过 alice 公钥的验证")
+	}
+
+	// 正文被改动后签名不再有效
+	box, _ = Seal(bob.Public(), alice, "原文")
+	letter, err = Open(bob, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	letter.Text = "改过的正文"
+	if letter.Verify(alice.Public()) {
+		t.Fatal("正文改动后签名不应有效")
+	}
+
+	// 签名绑定接收者：bob 把信件转投给 carol 时，carol 能发现
+	carol := newKeyPair(t)
+	letter, _ = Open(bob, box)
+	forwarded := &Letter{Text: letter.Text, Signature: letter.Signature, recipient: carol.Public().Box.Bytes()}
+	if forwarded.Verify(alice.Public()) {
+		t.Fatal("转投给其他接收者的信件不应通过验证")
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	k := newKeyPair(t)
+	public := k.Public()
+
+	parsed, err := DecodePublicKey(public.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.String() != public.String() || parsed.Fingerprint() != public.Fingerprint() {
+		t.Fatal("解析后的公钥与原公钥不同")
+	}
+	if fp := public.Fingerprint(); len(strings.Fields(fp)) != 4 {
+		t.Errorf("指纹 %q 应为4组", fp)
+	}
+
+	short := encoding.EncodeToString(make([]byte, keySize-1))
+	for _, tt := range []struct{ box, sign string }{
+		{"", public.SignString()},
+		{"不是base64", public.SignString()},
+		{short, public.SignString()},
+		{public.BoxString(), ""},
+		{public.BoxString(), short},
+		{public.BoxString(), "!!!"},
+	} {
+		if _, err := ParsePublicKey(tt.box, tt.sign); err != ErrMalformed {
+			t.Errorf("ParsePublicKey(%q, %q) = %v, want ErrMalformed", tt.box, tt.sign, err)
+		}
+	}
+	if _, err := DecodePublicKey(public.BoxString()); err != ErrMalformed {
+		t.Errorf("缺少签名公钥时 DecodePublicKey = %v, want ErrMalformed", err)
+	}
+}
+
+func TestKeyPairMarshal(t *testing.T) {
+	k := newKeyPair(t)
+	parsed, err := ParseKeyPair(k.Marshal() + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Public().String() != k.Public().String() {
+		t.Fatal("解析后的密钥对与原密钥对不同")
+	}
+	for _, value := range []string{"", k.Marshal()[:10], "a b", strings.Fields(k.Marshal())[0] + " " + encoding.EncodeToString([]byte("short"))} {
+		if _, err := ParseKeyPair(value); err != ErrMalformed {
+			t.Errorf("ParseKeyPair(%q) = %v, want ErrMalformed", value, err)
+		}
+	}
+}
+
+func TestValidBox(t *testing.T) {
+	alice, bob := newKeyPair(t), newKeyPair(t)
+	box, _ := Seal(bob.Public(), alice, "hi")
+
+	tooShort := encoding.EncodeToString(make([]byte, overhead-1))
+	for _, value := range []string{"", "不是base64", tooShort, strings.Repeat("A", MaxBoxLength+4)} {
+		if ValidBox(value) {
+			t.Errorf("ValidBox(%.20q) = true, want false", value)
+		}
+		if _, err := Open(bob, value); err == nil {
+			t.Errorf("Open(%.20q) 应返回错误", value)
+		}
+	}
+	if !ValidBox(box) {
+		t.Error("正常的信封应通过格式检查")
+	}
+
+	if _, err := Seal(bob.Public(), alice, strings.Repeat("长", MaxBoxLength)); err == nil {
+		t.Error("超过最大长度的内容应返回错误")
+	}
+}
@@ -0,0 +1,311 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatroom/history"
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/nickname"
+)
+
+// Format 导出格式
+type Format string
+
+const (
+	FormatMarkdown Format = "md"    // Markdown
+	FormatHTML     Format = "html"  // 独立的HTML页面
+	FormatJSONL    Format = "jsonl" // 每行一条消息的JSON
+)
+
+// DefaultFormat 默认导出格式
+const DefaultFormat = FormatMarkdown
+
+// ParseFormat 解析导出格式，支持 md/markdown、html 和 jsonl/json
+func ParseFormat(value string) (Format, bool) {
+	switch strings.ToLower(value) {
+	case "md", "markdown":
+		return FormatMarkdown, true
+	case "html", "htm":
+		return FormatHTML, true
+	case "jsonl", "json":
+		return FormatJSONL, true
+	}
+	return "", false
+}
+
+// ParseSince 解析起始时间，可以是相对时长（如 30m、24h、7d）或日期（2006-01-02、2006-01-02T15:04）
+func ParseSince(value string, now time.Time) (time.Time, bool) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, -n), true
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), true
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Transcript 要导出的聊天记录
+type Transcript struct {
+	Room     string             // 房间
+	Since    time.Time          // 起始时间，零值表示全部
+	Created  time.Time          // 导出时间
+	Lang     string             // 标题等文字使用的语言
+	Messages []*message.Message // 消息（包括私聊），按时间排序
+}
+
+// NewTranscript 合并房间消息和私聊消息，过滤掉起始时间之前的消息并按时间排序
+func NewTranscript(room string, since time.Time, lang string, messages ...[]*message.Message) *Transcript {
+	t := &Transcript{Room: room, Since: since, Created: time.Now(), Lang: lang}
+	for _, list := range messages {
+		for _, msg := range list {
+			if !since.IsZero() && msg.Timestamp.Before(since) {
+				continue
+			}
+			t.Messages = append(t.Messages, msg)
+		}
+	}
+	sort.SliceStable(t.Messages, func(i, j int) bool {
+		return t.Messages[i].Timestamp.Before(t.Messages[j].Timestamp)
+	})
+	return t
+}
+
+// Participant 判断私聊消息是否属于要导出的用户
+type Participant func(msg *message.Message) bool
+
+// ByUserID 按用户ID匹配私聊的收发双方
+//
+// 用户名可以在原用户离开后被别人使用，聊天中的 \export 只按用户ID导出本次连接收发的私聊。
+func ByUserID(id string) Participant {
+	return func(msg *message.Message) bool {
+		return id != "" && (msg.FromID == id || msg.ToID == id)
+	}
+}
+
+// ByName 按用户名匹配私聊的收发双方，比较时忽略大小写和易混淆字符
+//
+// 同一个用户名先后可能属于不同的人，只用于管理员在命令行导出。
+func ByName(name string) Participant {
+	key := nickname.Key(name)
+	return func(msg *message.Message) bool {
+		return nickname.Key(msg.From) == key || nickname.Key(msg.To) == key
+	}
+}
+
+// Load 从历史记录加载房间消息，participant 不为nil时同时包含其匹配的私聊
+func Load(historyStore *history.Store, room string, participant Participant, since time.Time, lang string) (*Transcript, error) {
+	messages, err := historyStore.Recent(room, 0)
+	if err != nil {
+		return nil, err
+	}
+	if participant == nil {
+		return NewTranscript(room, since, lang, messages), nil
+	}
+
+	private, err := historyStore.Recent(message.PrivateRoom, 0)
+	if err != nil {
+		return nil, err
+	}
+	var own []*message.Message
+	for _, msg := range private {
+		if participant(msg) {
+			own = append(own, msg)
+		}
+	}
+	return NewTranscript(room, since, lang, messages, own), nil
+}
+
+// FileName 生成导出文件名，如 chat-lobby-20240501-1530.md
+func (t *Transcript) FileName(format Format) string {
+	return fmt.Sprintf("chat-%s-%s.%s", t.Room, t.Created.Format("20060102-1504"), format)
+}
+
+// Render 按指定格式输出聊天记录
+func (t *Transcript) Render(w io.Writer, format Format) error {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case FormatMarkdown:
+		t.renderMarkdown(bw)
+	case FormatHTML:
+		t.renderHTML(bw)
+	case FormatJSONL:
+		if err := t.renderJSONL(bw); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+	return bw.Flush()
+}
+
+// title 标题和导出说明
+func (t *Transcript) title() (string, []string) {
+	title := i18n.T(t.Lang, "export.title", i18n.Params{"room": t.Room})
+	info := []string{i18n.T(t.Lang, "export.created", i18n.Params{"time": formatTime(t.Created)})}
+	if !t.Since.IsZero() {
+		info = append(info, i18n.T(t.Lang, "export.since", i18n.Params{"time": formatTime(t.Since)}))
+	}
+	info = append(info, i18n.T(t.Lang, "export.count", i18n.Params{"count": len(t.Messages)}))
+	return title, info
+}
+
+// annotations 消息的附加说明（私聊、机器人、已编辑、已删除），按语言渲染
+func (t *Transcript) annotations(msg *message.Message) (prefix, suffix string) {
+	switch {
+	case msg.Type == message.TypePrivate:
+		prefix = i18n.T(t.Lang, "export.private", i18n.Params{"from": msg.From, "to": msg.To})
+	case msg.Bot:
+		prefix = i18n.T(t.Lang, "export.bot", nil)
+	}
+	switch {
+	case msg.Deleted:
+		suffix = i18n.T(t.Lang, "export.deleted", nil)
+	case msg.Edited:
+		suffix = i18n.T(t.Lang, "export.edited", nil)
+	}
+	return prefix, suffix
+}
+
+// reactionSummary 表情回应汇总，如 "👍 2  🎉 1"
+func reactionSummary(msg *message.Message) string {
+	var parts []string
+	for _, emoji := range msg.ReactionEmojis() {
+		parts = append(parts, fmt.Sprintf("%s %d", emoji, len(msg.Reactions[emoji])))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// renderMarkdown 输出Markdown
+func (t *Transcript) renderMarkdown(w *bufio.Writer) {
+	title, info := t.title()
+	fmt.Fprintf(w, "# %s\n\n", escapeMarkdown(title))
+	for _, line := range info {
+		fmt.Fprintf(w, "- %s\n", escapeMarkdown(line))
+	}
+	w.WriteString("\n")
+
+	for _, msg := range t.Messages {
+		prefix, suffix := t.annotations(msg)
+		if msg.ReplyTo != "" {
+			fmt.Fprintf(w, "> %s: %s\n>\n", escapeMarkdown(msg.ReplyFrom), escapeMarkdown(msg.ReplyQuote))
+		}
+		fmt.Fprintf(w, "**%s** `#%s` ", formatTime(msg.Timestamp), msg.ID)
+		if prefix != "" {
+			fmt.Fprintf(w, "_%s_ ", escapeMarkdown(prefix))
+		}
+		fmt.Fprintf(w, "**%s**: ", escapeMarkdown(msg.From))
+		if !msg.Deleted {
+			w.WriteString(escapeMarkdown(msg.Content))
+		}
+		if suffix != "" {
+			fmt.Fprintf(w, " _%s_", escapeMarkdown(suffix))
+		}
+		if reactions := reactionSummary(msg); reactions != "" {
+			fmt.Fprintf(w, "  \n%s", reactions)
+		}
+		w.WriteString("\n\n")
+	}
+}
+
+// renderHTML 输出带内联样式的独立HTML页面
+func (t *Transcript) renderHTML(w *bufio.Writer) {
+	title, info := t.title()
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; color: #222; }
+.info { color: #666; }
+.msg { padding: 4px 0; border-bottom: 1px solid #eee; }
+.time, .id { color: #888; font-family: monospace; }
+.from { font-weight: bold; }
+.private { background: #f5f0ff; }
+.note { color: #888; font-style: italic; }
+.quote { border-left: 3px solid #ccc; padding-left: 6px; color: #666; }
+.content { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+`, html.EscapeString(title), html.EscapeString(title))
+	for _, line := range info {
+		fmt.Fprintf(w, "<div class=\"info\">%s</div>\n", html.EscapeString(line))
+	}
+	w.WriteString("<hr>\n")
+
+	for _, msg := range t.Messages {
+		prefix, suffix := t.annotations(msg)
+		class := "msg"
+		if msg.Type == message.TypePrivate {
+			class += " private"
+		}
+		fmt.Fprintf(w, "<div class=\"%s\" id=\"msg-%s\">\n", class, html.EscapeString(msg.ID))
+		if msg.ReplyTo != "" {
+			fmt.Fprintf(w, "<div class=\"quote\">%s: %s</div>\n",
+				html.EscapeString(msg.ReplyFrom), html.EscapeString(msg.ReplyQuote))
+		}
+		fmt.Fprintf(w, "<span class=\"time\">%s</span> <span class=\"id\">#%s</span> ",
+			formatTime(msg.Timestamp), html.EscapeString(msg.ID))
+		if prefix != "" {
+			fmt.Fprintf(w, "<span class=\"note\">%s</span> ", html.EscapeString(prefix))
+		}
+		fmt.Fprintf(w, "<span class=\"from\">%s</span>: ", html.EscapeString(msg.From))
+		if !msg.Deleted {
+			fmt.Fprintf(w, "<span class=\"content\">%s</span>", html.EscapeString(msg.Content))
+		}
+		if suffix != "" {
+			fmt.Fprintf(w, " <span class=\"note\">%s</span>", html.EscapeString(suffix))
+		}
+		if reactions := reactionSummary(msg); reactions != "" {
+			fmt.Fprintf(w, "<div class=\"note\">%s</div>", html.EscapeString(reactions))
+		}
+		w.WriteString("\n</div>\n")
+	}
+	w.WriteString("</body>\n</html>\n")
+}
+
+// renderJSONL 每行输出一条消息的JSON编码
+func (t *Transcript) renderJSONL(w *bufio.Writer) error {
+	for _, msg := range t.Messages {
+		line, err := msg.Encode()
+		if err != nil {
+			return err
+		}
+		w.WriteString(line)
+		w.WriteString("\n")
+	}
+	return nil
+}
+
+// formatTime 格式化时间
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// markdownEscaper 转义Markdown特殊字符
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", "&lt;", ">", "&gt;", "#", `\#`, "|", `\|`, "\n", "  \n",
+)
+
+// escapeMarkdown 转义文本中的Markdown特殊字符
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
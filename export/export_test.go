@@ -0,0 +1,49 @@
+package export
+
+import (
+	"testing"
+	"time"
+
+	"chatroom/history"
+	"chatroom/message"
+	"chatroom/store"
+)
+
+func TestLoadPrivateByUserID(t *testing.T) {
+	historyStore := history.NewStore(store.NewMemoryBackend(), 100)
+
+	// 之前的 alice 和 bob 的私聊，之后另一个人使用了 alice 这个用户名
+	old := message.NewPrivateMessage("alice", "bob", "旧的私聊")
+	old.FromID, old.ToID = "user_old", "user_bob"
+	own := message.NewPrivateMessage("bob", "alice", "新的私聊")
+	own.FromID, own.ToID = "user_bob", "user_new"
+	for _, msg := range []*message.Message{old, own} {
+		if err := historyStore.Append(message.PrivateRoom, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transcript, err := Load(historyStore, message.DefaultRoom, ByUserID("user_new"), time.Time{}, "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcript.Messages) != 1 || transcript.Messages[0].Content != "新的私聊" {
+		t.Fatalf("按用户ID只应导出本人收发的私聊，实际 %d 条", len(transcript.Messages))
+	}
+
+	transcript, err = Load(historyStore, message.DefaultRoom, ByName("alice"), time.Time{}, "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcript.Messages) != 2 {
+		t.Fatalf("按用户名应导出该用户名收发过的私聊，实际 %d 条", len(transcript.Messages))
+	}
+
+	transcript, err = Load(historyStore, message.DefaultRoom, nil, time.Time{}, "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcript.Messages) != 0 {
+		t.Fatal("没有参与者时不应包含私聊")
+	}
+}
@@ -0,0 +1,439 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RuleType 规则类型
+type RuleType string
+
+const (
+	RuleWord   RuleType = "word"   // 屏蔽词（ASCII单词按整词匹配，忽略大小写）
+	RuleRegex  RuleType = "regex"  // 正则表达式
+	RuleLink   RuleType = "link"   // 链接域名白名单/黑名单
+	RuleCaps   RuleType = "caps"   // 大写字母比例过高
+	RuleRepeat RuleType = "repeat" // 同一字符连续重复过多
+)
+
+// Action 规则命中后的动作
+type Action string
+
+const (
+	ActionMask   Action = "mask"   // 遮盖命中内容后照常发送
+	ActionReject Action = "reject" // 拒绝发送并提示发送者
+	ActionFlag   Action = "flag"   // 照常发送，同时通知管理员
+	ActionMute   Action = "mute"   // 拒绝发送并禁言发送者一段时间
+)
+
+// DefaultMuteDuration mute 动作未指定时长时的默认禁言时长
+const DefaultMuteDuration = 5 * time.Minute
+
+// 各规则的默认参数
+const (
+	defaultCapsRatio    = 0.7 // 大写字母比例阈值
+	defaultCapsMinCount = 8   // 参与判断的最少字母数
+	defaultRepeatRun    = 6   // 连续重复字符阈值
+)
+
+// linkPattern 匹配消息中的链接
+//
+// 除带协议或 www. 前缀的链接外，也匹配 evil.com/x 这样不带前缀的域名，
+// 顶级域名须为两个以上的字母，避免误判 3.14 或 e.g. 等普通文本。
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+` +
+	`|\b(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,63}\b(?::\d+)?(?:/[^\s<>"]*)?`)
+
+// Rule 过滤规则
+type Rule struct {
+	Type    RuleType      // 规则类型
+	Action  Action        // 命中后的动作
+	Pattern string        // 规则参数原文
+	MuteFor time.Duration // 禁言时长（仅 mute 动作）
+	Line    int           // 规则文件中的行号
+
+	re       *regexp.Regexp // word/regex 规则编译后的表达式
+	allow    bool           // link 规则是否为白名单
+	domains  []string       // link 规则的域名
+	ratio    float64        // caps 规则的大写比例阈值
+	minCount int            // caps 规则参与判断的最少字母数
+	run      int            // repeat 规则的连续重复阈值
+}
+
+// String 返回规则的简短描述，用于日志和管理员通知
+func (r *Rule) String() string {
+	return fmt.Sprintf("#%d %s %s", r.Line, r.Type, r.Pattern)
+}
+
+// Result 过滤结果
+type Result struct {
+	Content  string        // 过滤（遮盖）后的内容
+	Rejected *Rule         // 导致拒绝的规则，为nil表示允许发送
+	Flagged  []*Rule       // 需要通知管理员的规则
+	MuteFor  time.Duration // 需要禁言的时长，为0表示不禁言
+}
+
+// Filter 消息过滤器
+//
+// 规则按文件中的顺序依次执行：mask 规则会修改内容后继续执行后续规则，
+// reject/mute 规则命中后立即停止。
+type Filter struct {
+	path  string       // 规则文件路径，为空表示不启用过滤
+	mutex sync.RWMutex // 读写锁
+	rules []*Rule      // 当前生效的规则
+}
+
+// NewFilter 创建过滤器并从规则文件加载规则，path为空时不启用任何规则
+func NewFilter(path string) (*Filter, error) {
+	f := &Filter{path: path}
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload 重新加载规则文件，返回规则数量
+//
+// 加载失败时保留原有规则。
+func (f *Filter) Reload() (int, error) {
+	if f.path == "" {
+		return 0, nil
+	}
+
+	file, err := os.Open(f.path)
+	if err != nil {
+		return 0, fmt.Errorf("打开过滤规则文件失败: %v", err)
+	}
+	defer file.Close()
+
+	rules, err := ParseRules(file)
+	if err != nil {
+		return 0, err
+	}
+
+	f.mutex.Lock()
+	f.rules = rules
+	f.mutex.Unlock()
+	return len(rules), nil
+}
+
+// Count 获取当前规则数量
+func (f *Filter) Count() int {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return len(f.rules)
+}
+
+// Apply 对消息内容执行所有规则
+func (f *Filter) Apply(content string) Result {
+	f.mutex.RLock()
+	rules := f.rules
+	f.mutex.RUnlock()
+
+	result := Result{Content: content}
+	for _, rule := range rules {
+		if !rule.match(result.Content) {
+			continue
+		}
+		switch rule.Action {
+		case ActionMask:
+			result.Content = rule.mask(result.Content)
+		case ActionFlag:
+			result.Flagged = append(result.Flagged, rule)
+		case ActionReject:
+			result.Rejected = rule
+			return result
+		case ActionMute:
+			result.Rejected = rule
+			result.MuteFor = rule.MuteFor
+			return result
+		}
+	}
+	return result
+}
+
+// ParseRules 解析规则文件
+//
+// 每行一条规则，格式为 "<类型> <动作> <参数>"，空行和以 # 开头的行被忽略：
+//
+//	word   mask    badword
+//	regex  reject  (?i)buy\s+now
+//	link   flag    deny:example.com,example.org
+//	link   reject  allow:company.com
+//	caps   mask    0.7 8
+//	repeat mute:10m 8
+//
+// mute 动作可以用 mute:<时长> 指定禁言时长。
+func ParseRules(r io.Reader) ([]*Rule, error) {
+	var rules []*Rule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := parseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("过滤规则第%d行: %v", line, err)
+		}
+		rule.Line = line
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取过滤规则失败: %v", err)
+	}
+	return rules, nil
+}
+
+// parseRule 解析一行规则
+func parseRule(text string) (*Rule, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("规则格式应为 \"<类型> <动作> <参数>\"")
+	}
+
+	rule := &Rule{Type: RuleType(strings.ToLower(fields[0]))}
+	if err := rule.parseAction(strings.ToLower(fields[1])); err != nil {
+		return nil, err
+	}
+
+	// 参数取动作之后的剩余部分，保留正则中的空格
+	rest := strings.TrimSpace(text)
+	for _, field := range fields[:2] {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, field))
+	}
+	rule.Pattern = rest
+
+	var err error
+	switch rule.Type {
+	case RuleWord:
+		if rest == "" {
+			return nil, fmt.Errorf("缺少屏蔽词")
+		}
+		rule.re, err = regexp.Compile(wordPattern(rest))
+	case RuleRegex:
+		if rest == "" {
+			return nil, fmt.Errorf("缺少正则表达式")
+		}
+		rule.re, err = regexp.Compile(rest)
+	case RuleLink:
+		err = rule.parseLink(rest)
+	case RuleCaps:
+		err = rule.parseCaps(strings.Fields(rest))
+	case RuleRepeat:
+		rule.run = defaultRepeatRun
+		if rest != "" {
+			rule.run, err = strconv.Atoi(rest)
+			if err == nil && rule.run < 2 {
+				err = fmt.Errorf("重复次数必须大于1")
+			}
+		}
+	default:
+		return nil, fmt.Errorf("未知的规则类型: %s", fields[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// parseAction 解析动作，mute 可以带时长
+func (r *Rule) parseAction(text string) error {
+	name, duration, hasDuration := strings.Cut(text, ":")
+	r.Action = Action(name)
+	switch r.Action {
+	case ActionMask, ActionReject, ActionFlag:
+		if hasDuration {
+			return fmt.Errorf("只有 mute 动作可以指定时长")
+		}
+	case ActionMute:
+		r.MuteFor = DefaultMuteDuration
+		if hasDuration {
+			d, err := time.ParseDuration(duration)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("无效的禁言时长: %s", duration)
+			}
+			r.MuteFor = d
+		}
+	default:
+		return fmt.Errorf("未知的动作: %s", name)
+	}
+	return nil
+}
+
+// parseLink 解析 allow:<域名,...> 或 deny:<域名,...>
+func (r *Rule) parseLink(text string) error {
+	mode, list, ok := strings.Cut(text, ":")
+	switch {
+	case !ok:
+		return fmt.Errorf("链接规则格式应为 allow:<域名> 或 deny:<域名>")
+	case mode == "allow":
+		r.allow = true
+	case mode != "deny":
+		return fmt.Errorf("未知的链接规则: %s", mode)
+	}
+	for _, domain := range strings.Split(list, ",") {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+		if domain != "" {
+			r.domains = append(r.domains, domain)
+		}
+	}
+	if len(r.domains) == 0 {
+		return fmt.Errorf("缺少域名")
+	}
+	return nil
+}
+
+// parseCaps 解析 [比例] [最少字母数]
+func (r *Rule) parseCaps(args []string) error {
+	r.ratio, r.minCount = defaultCapsRatio, defaultCapsMinCount
+	if len(args) > 0 {
+		ratio, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || ratio <= 0 || ratio > 1 {
+			return fmt.Errorf("大写比例必须在0到1之间")
+		}
+		r.ratio = ratio
+	}
+	if len(args) > 1 {
+		minCount, err := strconv.Atoi(args[1])
+		if err != nil || minCount < 1 {
+			return fmt.Errorf("最少字母数必须大于0")
+		}
+		r.minCount = minCount
+	}
+	return nil
+}
+
+// wordPattern 生成屏蔽词的正则，ASCII单词按整词匹配，其他文字（如中文）按子串匹配
+func wordPattern(word string) string {
+	quoted := regexp.QuoteMeta(word)
+	for _, c := range word {
+		if c >= utf8.RuneSelf || !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_') {
+			return `(?i)` + quoted
+		}
+	}
+	return `(?i)\b` + quoted + `\b`
+}
+
+// match 判断内容是否命中规则
+func (r *Rule) match(content string) bool {
+	switch r.Type {
+	case RuleWord, RuleRegex:
+		return r.re.MatchString(content)
+	case RuleLink:
+		for _, link := range linkPattern.FindAllString(content, -1) {
+			if r.violates(link) {
+				return true
+			}
+		}
+		return false
+	case RuleCaps:
+		upper, letters := 0, 0
+		for _, c := range content {
+			if unicode.IsUpper(c) {
+				upper++
+				letters++
+			} else if unicode.IsLower(c) {
+				letters++
+			}
+		}
+		return letters >= r.minCount && float64(upper) >= r.ratio*float64(letters)
+	case RuleRepeat:
+		return r.hasRun(content)
+	}
+	return false
+}
+
+// mask 遮盖命中的内容
+func (r *Rule) mask(content string) string {
+	switch r.Type {
+	case RuleWord, RuleRegex:
+		return r.re.ReplaceAllStringFunc(content, stars)
+	case RuleLink:
+		return linkPattern.ReplaceAllStringFunc(content, func(link string) string {
+			if r.violates(link) {
+				return stars(link)
+			}
+			return link
+		})
+	case RuleCaps:
+		return strings.ToLower(content)
+	case RuleRepeat:
+		// 将过长的连续重复缩短到阈值以下
+		return collapseRuns(content, r.run-1)
+	}
+	return content
+}
+
+// violates 判断链接是否违反域名规则
+func (r *Rule) violates(link string) bool {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	listed := false
+	for _, domain := range r.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			listed = true
+			break
+		}
+	}
+	if r.allow {
+		return !listed
+	}
+	return listed
+}
+
+// hasRun 判断内容中是否有连续重复不少于阈值的非空白字符
+func (r *Rule) hasRun(content string) bool {
+	var last rune
+	count := 0
+	for _, c := range content {
+		if c == last {
+			count++
+		} else {
+			last, count = c, 1
+		}
+		if count >= r.run && !unicode.IsSpace(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// collapseRuns 将连续重复超过 max 次的字符缩短为 max 个
+func collapseRuns(content string, max int) string {
+	var b strings.Builder
+	var last rune
+	count := 0
+	for _, c := range content {
+		if c == last {
+			count++
+		} else {
+			last, count = c, 1
+		}
+		if count <= max || unicode.IsSpace(c) {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// stars 用等长的星号替换文本
+func stars(text string) string {
+	return strings.Repeat("*", utf8.RuneCountInString(text))
+}
@@ -0,0 +1,207 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFilter 将规则写入临时文件并创建过滤器，返回规则文件路径
+func newFilter(t *testing.T, rules string) (*Filter, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFilter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, path
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{"缺少动作", "word"},
+		{"未知类型", "emoji mask :)"},
+		{"未知动作", "word block darn"},
+		{"非mute动作带时长", "word reject:5m darn"},
+		{"无效禁言时长", "word mute:soon darn"},
+		{"非正禁言时长", "word mute:-1m darn"},
+		{"缺少屏蔽词", "word mask"},
+		{"缺少正则", "regex reject"},
+		{"无效正则", "regex reject (unclosed"},
+		{"链接规则缺少模式", "link flag example.com"},
+		{"未知链接模式", "link flag block:example.com"},
+		{"链接规则缺少域名", "link flag deny: , ."},
+		{"大写比例无效", "caps mask high"},
+		{"大写比例超出范围", "caps mask 1.5"},
+		{"最少字母数无效", "caps mask 0.7 0"},
+		{"重复次数无效", "repeat mask many"},
+		{"重复次数过小", "repeat mask 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 错误信息应带上规则所在的行号
+			_, err := ParseRules(strings.NewReader("# 注释\n\nword mask ok\n" + tt.rules + "\n"))
+			if err == nil {
+				t.Fatalf("ParseRules(%q) 应返回错误", tt.rules)
+			}
+			if !strings.Contains(err.Error(), "第4行") {
+				t.Errorf("错误 %q 应包含行号", err)
+			}
+		})
+	}
+}
+
+func TestParseRulesDefaults(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader("WORD MUTE darn\ncaps mask\nrepeat flag\nregex reject (?i)buy\\s+now  please"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 4 {
+		t.Fatalf("规则数量 %d", len(rules))
+	}
+	if rules[0].Type != RuleWord || rules[0].MuteFor != DefaultMuteDuration {
+		t.Errorf("类型和动作应忽略大小写并使用默认禁言时长: %+v", rules[0])
+	}
+	if rules[1].ratio != defaultCapsRatio || rules[1].minCount != defaultCapsMinCount {
+		t.Errorf("caps 默认参数 %v %d", rules[1].ratio, rules[1].minCount)
+	}
+	if rules[2].run != defaultRepeatRun {
+		t.Errorf("repeat 默认参数 %d", rules[2].run)
+	}
+	if rules[3].Pattern != `(?i)buy\s+now  please` || rules[3].Line != 4 {
+		t.Errorf("正则应保留参数中的空格: %q 第%d行", rules[3].Pattern, rules[3].Line)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    string
+		content  string
+		want     string        // 过滤后的内容
+		rejected bool          // 是否拒绝发送
+		flagged  int           // 通知管理员的规则数
+		muteFor  time.Duration // 禁言时长
+	}{
+		{"屏蔽词遮盖", "word mask darn", "well DARN it", "well **** it", false, 0, 0},
+		{"屏蔽词按整词匹配", "word mask darn", "darned", "darned", false, 0, 0},
+		{"中文屏蔽词按子串匹配", "word mask 垃圾", "这是垃圾话", "这是**话", false, 0, 0},
+		{"屏蔽词拒绝", "word reject darn", "darn", "darn", true, 0, 0},
+		{"屏蔽词通知", "word flag darn", "darn", "darn", false, 1, 0},
+		{"屏蔽词禁言", "word mute:3s darn", "darn", "darn", true, 0, 3 * time.Second},
+
+		{"正则遮盖", `regex mask \d{4}-\d{4}`, "call 5555-1234", "call *********", false, 0, 0},
+		{"正则拒绝", `regex reject (?i)buy\s+now`, "BUY   now!", "BUY   now!", true, 0, 0},
+		{"正则通知", `regex flag (?i)buy\s+now`, "buy now", "buy now", false, 1, 0},
+		{"正则禁言", `regex mute (?i)buy\s+now`, "buy now", "buy now", true, 0, DefaultMuteDuration},
+		{"正则未命中", `regex reject (?i)buy\s+now`, "buying nothing", "buying nothing", false, 0, 0},
+
+		{"黑名单遮盖带协议链接", "link mask deny:evil.com", "see https://evil.com/x ok", "see ****************** ok", false, 0, 0},
+		{"黑名单遮盖不带前缀的域名", "link mask deny:evil.com", "see evil.com/x ok", "see ********** ok", false, 0, 0},
+		{"黑名单匹配子域名", "link reject deny:evil.com", "go to sub.EVIL.com", "go to sub.EVIL.com", true, 0, 0},
+		{"黑名单匹配www前缀", "link reject deny:evil.com", "www.evil.com", "www.evil.com", true, 0, 0},
+		{"黑名单匹配端口", "link reject deny:evil.com", "evil.com:8080/login", "evil.com:8080/login", true, 0, 0},
+		{"黑名单不匹配相似域名", "link reject deny:evil.com", "notevil.com/x", "notevil.com/x", false, 0, 0},
+		{"黑名单通知", "link flag deny:evil.com,bad.org", "bad.org", "bad.org", false, 1, 0},
+		{"黑名单禁言", "link mute:1m deny:evil.com", "evil.com", "evil.com", true, 0, time.Minute},
+		{"白名单放行", "link reject allow:company.com", "docs at company.com/wiki", "docs at company.com/wiki", false, 0, 0},
+		{"白名单拒绝不带前缀的域名", "link reject allow:company.com", "try other.net", "try other.net", true, 0, 0},
+		{"白名单遮盖", "link mask allow:company.com", "company.com and other.net/x", "company.com and ***********", false, 0, 0},
+		{"数字和缩写不是链接", "link reject allow:company.com", "pi is 3.14, e.g. v1.2", "pi is 3.14, e.g. v1.2", false, 0, 0},
+
+		{"大写遮盖", "caps mask", "STOP SHOUTING NOW", "stop shouting now", false, 0, 0},
+		{"大写字母数不足", "caps reject", "OK NO", "OK NO", false, 0, 0},
+		{"大写比例自定义", "caps reject 0.5 4", "HELLO there", "HELLO there", true, 0, 0},
+		{"大写比例未达到", "caps reject 0.5 4", "Hello There", "Hello There", false, 0, 0},
+		{"大写通知", "caps flag", "STOP SHOUTING", "STOP SHOUTING", false, 1, 0},
+		{"大写禁言", "caps mute:10m", "STOP SHOUTING", "STOP SHOUTING", true, 0, 10 * time.Minute},
+
+		{"重复遮盖", "repeat mask 4", "nooooooo!", "nooo!", false, 0, 0},
+		{"重复不计空白", "repeat reject 3", "a      b", "a      b", false, 0, 0},
+		{"重复拒绝", "repeat reject", "!!!!!!", "!!!!!!", true, 0, 0},
+		{"重复通知", "repeat flag 3", "zzz", "zzz", false, 1, 0},
+		{"重复禁言", "repeat mute", "hahahaaaaaaa", "hahahaaaaaaa", true, 0, DefaultMuteDuration},
+
+		{"遮盖后继续匹配", "word mask darn\nword reject darn", "darn", "****", false, 0, 0},
+		{"通知后继续匹配", "word flag darn\ncaps flag 0.5 4", "DARN THIS", "DARN THIS", false, 2, 0},
+		{"拒绝后立即停止", "word reject darn\nword flag darn", "darn", "darn", true, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, _ := newFilter(t, tt.rules)
+			result := f.Apply(tt.content)
+			if result.Content != tt.want {
+				t.Errorf("内容 %q, want %q", result.Content, tt.want)
+			}
+			if (result.Rejected != nil) != tt.rejected {
+				t.Errorf("拒绝 %v, want %v", result.Rejected, tt.rejected)
+			}
+			if len(result.Flagged) != tt.flagged {
+				t.Errorf("通知 %d, want %d", len(result.Flagged), tt.flagged)
+			}
+			if result.MuteFor != tt.muteFor {
+				t.Errorf("禁言 %v, want %v", result.MuteFor, tt.muteFor)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	f, path := newFilter(t, "word mask darn\n")
+	if got := f.Apply("darn").Content; got != "****" {
+		t.Fatalf("内容 %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("word reject darn\nword mask heck\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	n, err := f.Reload()
+	if err != nil || n != 2 || f.Count() != 2 {
+		t.Fatalf("Reload = %d, %v", n, err)
+	}
+	if f.Apply("darn").Rejected == nil {
+		t.Error("重新加载后应使用新规则")
+	}
+	if got := f.Apply("heck").Content; got != "****" {
+		t.Errorf("内容 %q", got)
+	}
+
+	// 规则文件有错误时保留原有规则
+	if err := os.WriteFile(path, []byte("word explode darn\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Reload(); err == nil {
+		t.Fatal("规则文件有错误时应返回错误")
+	}
+	if f.Count() != 2 || f.Apply("darn").Rejected == nil {
+		t.Error("加载失败时应保留原有规则")
+	}
+
+	// 规则文件被删除时同样保留原有规则
+	os.Remove(path)
+	if _, err := f.Reload(); err == nil || f.Count() != 2 {
+		t.Errorf("规则文件缺失时 Reload = %v, 规则数 %d", err, f.Count())
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	f, err := NewFilter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := f.Reload(); n != 0 || err != nil {
+		t.Errorf("Reload = %d, %v", n, err)
+	}
+	result := f.Apply("darn evil.com")
+	if result.Content != "darn evil.com" || result.Rejected != nil || len(result.Flagged) != 0 {
+		t.Errorf("未启用过滤时不应修改内容: %+v", result)
+	}
+}
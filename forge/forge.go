@@ -0,0 +1,337 @@
+package forge
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"chatroom/i18n"
+	"chatroom/room"
+)
+
+// 代码托管平台
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+)
+
+// 事件种类，GitLab 的合并请求归入 pull_request，GitHub 的检查和工作流归入 pipeline
+const (
+	KindPush        = "push"
+	KindPullRequest = "pull_request"
+	KindIssue       = "issue"
+	KindPipeline    = "pipeline"
+)
+
+// kindAliases 配置文件中事件种类的别名
+var kindAliases = map[string]string{
+	"push":          KindPush,
+	"pull_request":  KindPullRequest,
+	"merge_request": KindPullRequest,
+	"issue":         KindIssue,
+	"issues":        KindIssue,
+	"pipeline":      KindPipeline,
+	"check":         KindPipeline,
+}
+
+// maxTitleRunes 摘要中标题和提交说明的最大字符数
+const maxTitleRunes = 72
+
+// changeActions 需要通知的拉取请求、合并请求和议题动作，其他动作（如编辑、加标签）被忽略
+var changeActions = map[string]bool{"opened": true, "closed": true, "merged": true, "reopened": true}
+
+// Event 解析后的仓库事件
+type Event struct {
+	Repo string // 仓库全名，如 acme/api
+	Kind string // 事件种类，为空表示不需要通知的事件（如 ping 或未处理的动作）
+
+	text   i18n.Text         // 摘要模板和参数
+	labels map[string]string // 需要按语言渲染的参数 (参数名 -> 消息键)，如动作和状态
+}
+
+// Summary 按语言渲染一行摘要
+func (e Event) Summary(lang string) string {
+	params := make(i18n.Params, len(e.text.Params)+len(e.labels))
+	for name, value := range e.text.Params {
+		params[name] = value
+	}
+	for name, key := range e.labels {
+		params[name] = i18n.T(lang, key, nil)
+	}
+	return strings.TrimSpace(i18n.T(lang, e.text.Key, params))
+}
+
+// Parse 按平台解析事件，eventType 为平台的事件类型请求头
+func Parse(provider, eventType string, body []byte) (Event, error) {
+	switch provider {
+	case GitHub:
+		return parseGitHub(eventType, body)
+	case GitLab:
+		return parseGitLab(eventType, body)
+	default:
+		return Event{}, fmt.Errorf("未知的平台: %s", provider)
+	}
+}
+
+// Name 返回平台的显示名，作为发送摘要的机器人的名字
+func Name(provider string) string {
+	if provider == GitLab {
+		return "GitLab"
+	}
+	return "GitHub"
+}
+
+// EventHeader 返回平台的事件类型请求头
+func EventHeader(provider string) string {
+	if provider == GitLab {
+		return "X-Gitlab-Event"
+	}
+	return "X-GitHub-Event"
+}
+
+// Route 一条路由：把某个仓库的某些事件发送到房间
+type Route struct {
+	Provider string          // 平台
+	Repo     string          // 仓库全名（小写），* 表示所有仓库
+	Room     string          // 目标房间
+	Line     int             // 在配置文件中的行号
+	secret   string          // 签名密钥（GitHub）或令牌（GitLab）
+	kinds    map[string]bool // 事件种类，为nil表示所有种类
+}
+
+// Verify 验证请求来自平台：GitHub 校验 X-Hub-Signature-256 中的 HMAC-SHA256，GitLab 比较 X-Gitlab-Token
+func (r Route) Verify(header http.Header, body []byte) bool {
+	switch r.Provider {
+	case GitHub:
+		mac := hmac.New(sha256.New, []byte(r.secret))
+		mac.Write(body)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(header.Get("X-Hub-Signature-256")), []byte(expected))
+	case GitLab:
+		return hmac.Equal([]byte(header.Get("X-Gitlab-Token")), []byte(r.secret))
+	default:
+		return false
+	}
+}
+
+// Accepts 判断路由是否接收该种类的事件
+func (r Route) Accepts(kind string) bool {
+	return r.kinds == nil || r.kinds[kind]
+}
+
+// Router 仓库事件路由表
+//
+// 从配置文件加载，修改后发送 SIGHUP 重新加载；同一个仓库可以有多条路由，把不同种类的事件发送到不同房间。
+type Router struct {
+	path   string       // 配置文件路径，为空表示不启用
+	mutex  sync.RWMutex // 读写锁
+	routes []Route      // 当前生效的路由
+}
+
+// NewRouter 创建路由表并从配置文件加载，path为空时不启用
+func NewRouter(path string) (*Router, error) {
+	r := &Router{path: path}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 重新加载配置文件，返回路由数量；加载失败时保留原有路由
+func (r *Router) Reload() (int, error) {
+	if r.path == "" {
+		return 0, nil
+	}
+
+	file, err := os.Open(r.path)
+	if err != nil {
+		return 0, fmt.Errorf("打开仓库路由文件失败: %v", err)
+	}
+	defer file.Close()
+
+	routes, err := ParseRoutes(file)
+	if err != nil {
+		return 0, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.routes = routes
+	return len(routes), nil
+}
+
+// Count 获取当前路由数量
+func (r *Router) Count() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.routes)
+}
+
+// Match 获取平台和仓库匹配的路由
+func (r *Router) Match(provider, repo string) []Route {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	repo = strings.ToLower(repo)
+	var matched []Route
+	for _, route := range r.routes {
+		if route.Provider == provider && (route.Repo == "*" || route.Repo == repo) {
+			matched = append(matched, route)
+		}
+	}
+	return matched
+}
+
+// Rooms 获取事件要发送到的房间（去重，按路由顺序）
+//
+// 只有通过验证的路由才会被使用，verified 表示是否至少有一条匹配的路由通过了验证；
+// 不需要通知的事件（Kind 为空）和路由不接收的种类不发送到任何房间。
+func (r *Router) Rooms(provider string, header http.Header, body []byte, event Event) (rooms []string, verified bool) {
+	seen := make(map[string]bool)
+	for _, route := range r.Match(provider, event.Repo) {
+		if !route.Verify(header, body) {
+			continue
+		}
+		verified = true
+		if event.Kind != "" && route.Accepts(event.Kind) && !seen[route.Room] {
+			seen[route.Room] = true
+			rooms = append(rooms, route.Room)
+		}
+	}
+	return rooms, verified
+}
+
+// ParseRoutes 解析仓库路由文件
+//
+// 每行一条路由，格式为 "<平台> <仓库> <密钥> <房间> [事件种类...]"，空行和以 # 开头的行被忽略：
+//
+//	github  acme/api   s3cret  dev  push pull_request
+//	github  acme/api   s3cret  ops  pipeline
+//	gitlab  group/web  t0ken   dev
+//
+// 仓库为 * 时匹配所有仓库；省略事件种类时接收 push、pull_request（merge_request）、issue 和 pipeline（check）全部种类。
+func ParseRoutes(r io.Reader) ([]Route, error) {
+	var routes []Route
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		route, err := parseRoute(text)
+		if err != nil {
+			return nil, fmt.Errorf("仓库路由第%d行: %v", line, err)
+		}
+		route.Line = line
+		routes = append(routes, route)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取仓库路由失败: %v", err)
+	}
+	return routes, nil
+}
+
+// parseRoute 解析一行路由
+func parseRoute(text string) (Route, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 4 {
+		return Route{}, fmt.Errorf("格式应为 \"<平台> <仓库> <密钥> <房间> [事件种类...]\"")
+	}
+
+	provider := strings.ToLower(fields[0])
+	if provider != GitHub && provider != GitLab {
+		return Route{}, fmt.Errorf("未知的平台: %s", fields[0])
+	}
+	roomName, err := room.Normalize(fields[3])
+	if err != nil {
+		return Route{}, fmt.Errorf("无效的房间名: %s", fields[3])
+	}
+
+	route := Route{Provider: provider, Repo: strings.ToLower(fields[1]), Room: roomName, secret: fields[2]}
+	for _, field := range fields[4:] {
+		if field == "*" {
+			route.kinds = nil
+			break
+		}
+		kind, exists := kindAliases[strings.ToLower(field)]
+		if !exists {
+			return Route{}, fmt.Errorf("未知的事件种类: %s", field)
+		}
+		if route.kinds == nil {
+			route.kinds = make(map[string]bool)
+		}
+		route.kinds[kind] = true
+	}
+	return route, nil
+}
+
+// pushEvent 推送的摘要：推送提交、推送或删除标签、删除分支
+func pushEvent(repo, user, ref string, deleted bool, count int, head, url string) Event {
+	params := i18n.Params{"repo": repo, "user": user, "count": count, "title": shorten(head), "url": url}
+	tag, isTag := strings.CutPrefix(ref, "refs/tags/")
+	branch := strings.TrimPrefix(ref, "refs/heads/")
+	params["tag"] = tag
+	params["branch"] = branch
+
+	key := "forge.push"
+	switch {
+	case isTag && deleted:
+		key = "forge.tag_deleted"
+	case isTag:
+		key = "forge.tag"
+	case deleted:
+		key = "forge.branch_deleted"
+	case count == 0:
+		key = "forge.branch_updated"
+	}
+	return Event{Repo: repo, Kind: KindPush, text: i18n.NewText(key, params)}
+}
+
+// changeEvent 拉取请求、合并请求和议题的摘要，如 "[acme/api] alice 打开了拉取请求 #12: 修复登录 https://..."
+//
+// action 为 opened、closed、merged 或 reopened，noun 为 pull_request、merge_request 或 issue。
+func changeEvent(kind, repo, user, action, noun, ref, title, url string) Event {
+	return Event{
+		Repo:   repo,
+		Kind:   kind,
+		text:   i18n.NewText("forge.change", i18n.Params{"repo": repo, "user": user, "ref": ref, "title": shorten(title), "url": url}),
+		labels: map[string]string{"verb": "forge.verb." + action, "noun": "forge.noun." + noun},
+	}
+}
+
+// pipelineEvent 流水线和检查的摘要，status 为 success、failed 或 canceled
+func pipelineEvent(repo, name, branch, status, url string) Event {
+	return Event{
+		Repo:   repo,
+		Kind:   KindPipeline,
+		text:   i18n.NewText("forge.pipeline", i18n.Params{"repo": repo, "name": name, "branch": branch, "url": url}),
+		labels: map[string]string{"status": "forge.status." + status},
+	}
+}
+
+// shorten 取第一行并截断到 maxTitleRunes 个字符
+func shorten(text string) string {
+	text, _, _ = strings.Cut(strings.TrimSpace(text), "\n")
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= maxTitleRunes {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:maxTitleRunes-1]) + "…"
+}
+
+// shortSHA 提交ID的前7位
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
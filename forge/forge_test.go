@@ -0,0 +1,230 @@
+package forge
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixture 读取 testdata 中的请求体
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// githubHeader 用密钥签名请求体，返回 GitHub 的请求头
+func githubHeader(event, secret string, body []byte) http.Header {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	header := http.Header{}
+	header.Set(EventHeader(GitHub), event)
+	header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+// gitlabHeader 返回带令牌的 GitLab 请求头
+func gitlabHeader(event, token string) http.Header {
+	header := http.Header{}
+	header.Set(EventHeader(GitLab), event)
+	header.Set("X-Gitlab-Token", token)
+	return header
+}
+
+// newRouter 用给定的配置内容创建路由表
+func newRouter(t *testing.T, config string) *Router {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "repos.conf")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRouter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		provider, event, file string
+		repo, kind, summary   string
+	}{
+		{GitHub, "push", "gh_push.json", "Acme/API", KindPush, "octocat pushed 2 commits to main"},
+		{GitHub, "issues", "gh_issue.json", "acme/api", KindIssue, "[acme/api] carol opened issue #7: Crash on empty input https://github.com/acme/api/issues/7"},
+		{GitHub, "pull_request", "gh_pr_merged.json", "acme/api", KindPullRequest, "bob merged pull request #12"},
+		{GitHub, "pull_request", "gh_pr_labeled.json", "acme/api", "", ""},
+		{GitHub, "workflow_run", "gh_workflow.json", "acme/api", KindPipeline, "Pipeline CI on main failed"},
+		{GitHub, "ping", "gh_ping.json", "", "", ""},
+		{GitLab, "Push Hook", "gl_push.json", "group/web", KindPush, "jsmith pushed 2 commits to master: fixed readme"},
+		{GitLab, "Tag Push Hook", "gl_tag_deleted.json", "group/web", KindPush, "[group/web] jsmith deleted tag v1.0.0"},
+		{GitLab, "Merge Request Hook", "gl_mr.json", "group/web", KindPullRequest, "root merged merge request !1"},
+		{GitLab, "Pipeline Hook", "gl_pipeline.json", "group/web", KindPipeline, "Pipeline #31 on master succeeded"},
+	}
+	for _, tt := range tests {
+		event, err := Parse(tt.provider, tt.event, fixture(t, tt.file))
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if tt.repo != "" && event.Repo != tt.repo {
+			t.Errorf("%s: 仓库 %q, want %q", tt.file, event.Repo, tt.repo)
+		}
+		if event.Kind != tt.kind {
+			t.Errorf("%s: 种类 %q, want %q", tt.file, event.Kind, tt.kind)
+		}
+		if tt.kind != "" && !strings.Contains(event.Summary("en-US"), tt.summary) {
+			t.Errorf("%s: 摘要 %q, want 包含 %q", tt.file, event.Summary("en-US"), tt.summary)
+		}
+	}
+
+	if _, err := Parse("gitea", "push", fixture(t, "gh_push.json")); err == nil {
+		t.Error("未知的平台应返回错误")
+	}
+}
+
+func TestVerifyGitHub(t *testing.T) {
+	routes, err := ParseRoutes(strings.NewReader("github acme/api s3cret dev"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := routes[0]
+	body := fixture(t, "gh_push.json")
+
+	if !route.Verify(githubHeader("push", "s3cret", body), body) {
+		t.Fatal("正确的签名应通过验证")
+	}
+	if route.Verify(githubHeader("push", "wrong", body), body) {
+		t.Error("错误密钥的签名不应通过验证")
+	}
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-2] ^= 1
+	if route.Verify(githubHeader("push", "s3cret", body), tampered) {
+		t.Error("篡改后的请求体不应通过验证")
+	}
+	header := githubHeader("push", "s3cret", body)
+	header.Del("X-Hub-Signature-256")
+	if route.Verify(header, body) {
+		t.Error("没有签名的请求不应通过验证")
+	}
+	// GitLab 的令牌不能用于 GitHub 路由
+	if route.Verify(gitlabHeader("Push Hook", "s3cret"), body) {
+		t.Error("GitHub 路由不应接受令牌")
+	}
+}
+
+func TestVerifyGitLab(t *testing.T) {
+	routes, err := ParseRoutes(strings.NewReader("gitlab group/web t0ken ops"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := routes[0]
+	body := fixture(t, "gl_push.json")
+
+	if !route.Verify(gitlabHeader("Push Hook", "t0ken"), body) {
+		t.Fatal("正确的令牌应通过验证")
+	}
+	if route.Verify(gitlabHeader("Push Hook", "t0ken2"), body) {
+		t.Error("错误的令牌不应通过验证")
+	}
+	if route.Verify(http.Header{}, body) {
+		t.Error("没有令牌的请求不应通过验证")
+	}
+}
+
+func TestParseRoutesErrors(t *testing.T) {
+	for _, line := range []string{
+		"github acme/api s3cret",
+		"gitea acme/api s3cret dev",
+		"github acme/api s3cret dev.ops",
+		"github acme/api s3cret dev deploy",
+	} {
+		if _, err := ParseRoutes(strings.NewReader(line)); err == nil {
+			t.Errorf("ParseRoutes(%q) 应返回错误", line)
+		}
+	}
+
+	routes, err := ParseRoutes(strings.NewReader("# 注释\n\nGitHub Acme/API s3cret dev merge_request issues\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].Line != 3 || routes[0].Repo != "acme/api" || routes[0].Provider != GitHub {
+		t.Fatalf("路由 %+v", routes)
+	}
+	if !routes[0].Accepts(KindPullRequest) || !routes[0].Accepts(KindIssue) || routes[0].Accepts(KindPush) {
+		t.Error("事件种类的别名没有生效")
+	}
+}
+
+func TestRooms(t *testing.T) {
+	router := newRouter(t, strings.Join([]string{
+		"github acme/api s3cret dev",
+		"github acme/api s3cret releases push",
+		"github * s3cret all pipeline",
+		"github acme/api other audit",
+		"github acme/api s3cret dev push",
+		"gitlab group/web t0ken ops merge_request",
+	}, "\n"))
+	if router.Count() != 6 {
+		t.Fatalf("路由数量 %d, want 6", router.Count())
+	}
+
+	rooms := func(provider string, header http.Header, body []byte, event Event) string {
+		got, verified := router.Rooms(provider, header, body, event)
+		if !verified {
+			return "unverified"
+		}
+		return strings.Join(got, ",")
+	}
+
+	// 仓库名不区分大小写，同一房间只发送一次，密钥不同的路由不使用
+	push := fixture(t, "gh_push.json")
+	event, _ := Parse(GitHub, "push", push)
+	if got := rooms(GitHub, githubHeader("push", "s3cret", push), push, event); got != "dev,releases" {
+		t.Errorf("推送发送到 %q, want dev,releases", got)
+	}
+
+	// * 匹配所有仓库，只接收指定种类的事件
+	workflow := fixture(t, "gh_workflow.json")
+	event, _ = Parse(GitHub, "workflow_run", workflow)
+	if got := rooms(GitHub, githubHeader("workflow_run", "s3cret", workflow), workflow, event); got != "dev,all" {
+		t.Errorf("流水线发送到 %q, want dev,all", got)
+	}
+
+	// 另一条路由的密钥也可以通过验证
+	if got := rooms(GitHub, githubHeader("workflow_run", "other", workflow), workflow, event); got != "audit" {
+		t.Errorf("用另一个密钥签名时发送到 %q, want audit", got)
+	}
+
+	// 签名错误时不发送
+	if got := rooms(GitHub, githubHeader("push", "wrong", push), push, event); got != "unverified" {
+		t.Errorf("签名错误时发送到 %q", got)
+	}
+
+	// 通过验证但不需要通知的事件不发送到任何房间
+	labeled := fixture(t, "gh_pr_labeled.json")
+	event, _ = Parse(GitHub, "pull_request", labeled)
+	if got := rooms(GitHub, githubHeader("pull_request", "s3cret", labeled), labeled, event); got != "" {
+		t.Errorf("忽略的事件发送到 %q", got)
+	}
+
+	// 平台不同的路由不匹配
+	mr := fixture(t, "gl_mr.json")
+	event, _ = Parse(GitLab, "Merge Request Hook", mr)
+	if got := rooms(GitLab, gitlabHeader("Merge Request Hook", "t0ken"), mr, event); got != "ops" {
+		t.Errorf("合并请求发送到 %q, want ops", got)
+	}
+	pipeline := fixture(t, "gl_pipeline.json")
+	event, _ = Parse(GitLab, "Pipeline Hook", pipeline)
+	if got := rooms(GitLab, gitlabHeader("Pipeline Hook", "t0ken"), pipeline, event); got != "" {
+		t.Errorf("GitLab 流水线发送到 %q, want 不发送", got)
+	}
+}
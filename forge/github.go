@@ -0,0 +1,138 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// githubPayload GitHub 事件中用到的字段
+type githubPayload struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+
+	// push
+	Ref        string     `json:"ref"`
+	Deleted    bool       `json:"deleted"`
+	Compare    string     `json:"compare"`
+	Commits    []struct{} `json:"commits"`
+	HeadCommit *struct {
+		Message string `json:"message"`
+	} `json:"head_commit"`
+	Pusher struct {
+		Name string `json:"name"`
+	} `json:"pusher"`
+
+	// pull_request / issues
+	PullRequest *githubChange `json:"pull_request"`
+	Issue       *githubChange `json:"issue"`
+
+	// workflow_run / check_suite
+	WorkflowRun *struct {
+		Name       string `json:"name"`
+		HeadBranch string `json:"head_branch"`
+		Conclusion string `json:"conclusion"`
+		HTMLURL    string `json:"html_url"`
+	} `json:"workflow_run"`
+	CheckSuite *struct {
+		HeadBranch string `json:"head_branch"`
+		HeadSHA    string `json:"head_sha"`
+		Conclusion string `json:"conclusion"`
+		App        struct {
+			Name string `json:"name"`
+		} `json:"app"`
+	} `json:"check_suite"`
+}
+
+// githubChange 拉取请求或议题
+type githubChange struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+	Merged  bool   `json:"merged"`
+}
+
+// githubConclusions 检查结论 -> 摘要状态，其他结论（如 neutral、skipped）被忽略
+var githubConclusions = map[string]string{
+	"success":   "success",
+	"failure":   "failed",
+	"timed_out": "failed",
+	"cancelled": "canceled",
+}
+
+// parseGitHub 解析 GitHub 事件，eventType 为 X-GitHub-Event 请求头
+//
+// 处理 push、pull_request、issues、workflow_run 和 check_suite，其他事件（包括 ping）只返回仓库名。
+func parseGitHub(eventType string, body []byte) (Event, error) {
+	var p githubPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return Event{}, fmt.Errorf("解析 GitHub 事件失败: %v", err)
+	}
+	repo := p.Repository.FullName
+	ignored := Event{Repo: repo}
+
+	switch eventType {
+	case "push":
+		user := p.Pusher.Name
+		if user == "" {
+			user = p.Sender.Login
+		}
+		head := ""
+		if p.HeadCommit != nil {
+			head = p.HeadCommit.Message
+		}
+		return pushEvent(repo, user, p.Ref, p.Deleted, len(p.Commits), head, p.Compare), nil
+
+	case "pull_request":
+		if p.PullRequest == nil {
+			return ignored, nil
+		}
+		action := p.Action
+		if action == "closed" && p.PullRequest.Merged {
+			action = "merged"
+		}
+		if !changeActions[action] {
+			return ignored, nil
+		}
+		return changeEvent(KindPullRequest, repo, p.Sender.Login, action, "pull_request",
+			fmt.Sprintf("#%d", p.PullRequest.Number), p.PullRequest.Title, p.PullRequest.HTMLURL), nil
+
+	case "issues":
+		if p.Issue == nil || !changeActions[p.Action] {
+			return ignored, nil
+		}
+		return changeEvent(KindIssue, repo, p.Sender.Login, p.Action, "issue",
+			fmt.Sprintf("#%d", p.Issue.Number), p.Issue.Title, p.Issue.HTMLURL), nil
+
+	case "workflow_run":
+		if p.Action != "completed" || p.WorkflowRun == nil {
+			return ignored, nil
+		}
+		status, ok := githubConclusions[p.WorkflowRun.Conclusion]
+		if !ok {
+			return ignored, nil
+		}
+		run := p.WorkflowRun
+		return pipelineEvent(repo, run.Name, run.HeadBranch, status, run.HTMLURL), nil
+
+	case "check_suite":
+		if p.Action != "completed" || p.CheckSuite == nil {
+			return ignored, nil
+		}
+		status, ok := githubConclusions[p.CheckSuite.Conclusion]
+		if !ok {
+			return ignored, nil
+		}
+		suite := p.CheckSuite
+		url := p.Repository.HTMLURL + "/commit/" + suite.HeadSHA + "/checks"
+		return pipelineEvent(repo, suite.App.Name, suite.HeadBranch, status, url), nil
+
+	default:
+		return ignored, nil
+	}
+}
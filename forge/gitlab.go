@@ -0,0 +1,114 @@
+package forge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// gitlabPayload GitLab 事件中用到的字段
+type gitlabPayload struct {
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+	} `json:"project"`
+	User struct {
+		Username string `json:"username"`
+	} `json:"user"`
+
+	// Push Hook / Tag Push Hook
+	UserUsername string `json:"user_username"`
+	Ref          string `json:"ref"`
+	After        string `json:"after"`
+	TotalCommits int    `json:"total_commits_count"`
+	Commits      []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+	} `json:"commits"`
+
+	// Merge Request Hook / Issue Hook / Pipeline Hook
+	ObjectAttributes struct {
+		ID     int    `json:"id"`
+		IID    int    `json:"iid"`
+		Title  string `json:"title"`
+		URL    string `json:"url"`
+		Action string `json:"action"`
+		Status string `json:"status"`
+		Ref    string `json:"ref"`
+		Name   string `json:"name"`
+	} `json:"object_attributes"`
+}
+
+// gitlabActions 合并请求和议题的动作 -> 摘要动作
+var gitlabActions = map[string]string{
+	"open":   "opened",
+	"close":  "closed",
+	"merge":  "merged",
+	"reopen": "reopened",
+}
+
+// gitlabStatuses 流水线状态 -> 摘要状态，只通知已结束的流水线
+var gitlabStatuses = map[string]string{
+	"success":  "success",
+	"failed":   "failed",
+	"canceled": "canceled",
+}
+
+// gitlabZeroSHA 删除分支或标签时 after 的取值
+const gitlabZeroSHA = "0000000000000000000000000000000000000000"
+
+// parseGitLab 解析 GitLab 事件，eventType 为 X-Gitlab-Event 请求头
+//
+// 处理推送、标签推送、合并请求、议题和流水线；机密议题和其他事件只返回仓库名，不会发送到房间。
+func parseGitLab(eventType string, body []byte) (Event, error) {
+	var p gitlabPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return Event{}, fmt.Errorf("解析 GitLab 事件失败: %v", err)
+	}
+	repo := p.Project.PathWithNamespace
+	ignored := Event{Repo: repo}
+	attrs := p.ObjectAttributes
+
+	switch eventType {
+	case "Push Hook", "Tag Push Hook":
+		head, url := "", p.Project.WebURL
+		for _, commit := range p.Commits {
+			if commit.ID == p.After {
+				head, url = commit.Message, commit.URL
+			}
+		}
+		return pushEvent(repo, p.UserUsername, p.Ref, p.After == gitlabZeroSHA, p.TotalCommits, head, url), nil
+
+	case "Merge Request Hook":
+		action, ok := gitlabActions[attrs.Action]
+		if !ok {
+			return ignored, nil
+		}
+		return changeEvent(KindPullRequest, repo, p.User.Username, action, "merge_request",
+			fmt.Sprintf("!%d", attrs.IID), attrs.Title, attrs.URL), nil
+
+	case "Issue Hook":
+		action, ok := gitlabActions[attrs.Action]
+		if !ok {
+			return ignored, nil
+		}
+		return changeEvent(KindIssue, repo, p.User.Username, action, "issue",
+			fmt.Sprintf("#%d", attrs.IID), attrs.Title, attrs.URL), nil
+
+	case "Pipeline Hook":
+		status, ok := gitlabStatuses[attrs.Status]
+		if !ok {
+			return ignored, nil
+		}
+		name := attrs.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", attrs.ID)
+		}
+		url := strings.TrimSuffix(p.Project.WebURL, "/") + fmt.Sprintf("/-/pipelines/%d", attrs.ID)
+		return pipelineEvent(repo, name, attrs.Ref, status, url), nil
+
+	default:
+		return ignored, nil
+	}
+}
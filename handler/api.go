@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/nickname"
+	"chatroom/room"
+	"chatroom/session"
+	"chatroom/stream"
+	"chatroom/user"
+	"chatroom/utils"
+	"chatroom/webhook"
+)
+
+// OpenStream 打开房间的只读事件流，返回订阅和 lastEventID 之后需要补发的历史消息
+//
+// 只能订阅公开房间；lastEventID 为空时不补发。lastEventID 不在保留的历史记录中（已被淘汰或来自别的房间）时
+// 也不补发，reset 为 true，调用者需要告诉客户端无法续传、应重新同步。调用者结束时需要调用 CloseStream。
+func (ch *ConnectionHandler) OpenStream(roomName, lastEventID string) (sub *stream.Subscription, backlog []*message.Message, reset bool, err error) {
+	roomName, err = room.Normalize(roomName)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	r, exists, err := ch.rooms.Get(roomName)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if !exists {
+		return nil, nil, false, i18n.Errorf("error.room_not_found", i18n.Params{"room": roomName})
+	}
+	if !r.Open() {
+		return nil, nil, false, i18n.Errorf("error.stream_room", i18n.Params{"room": roomName})
+	}
+
+	// 先订阅再读取历史记录，补发和实时事件之间不会漏掉消息，重复的由调用者按消息ID去掉
+	sub = ch.streams.Subscribe(roomName)
+	if lastEventID == "" {
+		return sub, nil, false, nil
+	}
+	recent, err := ch.history.Recent(roomName, 0)
+	if err != nil {
+		ch.streams.Unsubscribe(sub)
+		return nil, nil, false, err
+	}
+	start := -1
+	for i, msg := range recent {
+		if msg.ID == lastEventID {
+			start = i + 1
+		}
+	}
+	if start < 0 {
+		return sub, nil, true, nil
+	}
+	for _, msg := range recent[start:] {
+		if !msg.Deleted && stream.Public(msg) {
+			msg.Room = roomName
+			backlog = append(backlog, msg)
+		}
+	}
+	return sub, backlog, false, nil
+}
+
+// roomOpen 判断房间当前是否公开，供只读事件流在分发每个事件时检查
+func (ch *ConnectionHandler) roomOpen(name string) bool {
+	r, exists, err := ch.rooms.Get(name)
+	if err != nil {
+		ch.logger.Error("读取房间 %s 失败: %v", name, err)
+		return false
+	}
+	return exists && r.Open()
+}
+
+// CloseStream 结束只读事件流
+func (ch *ConnectionHandler) CloseStream(sub *stream.Subscription) {
+	ch.streams.Unsubscribe(sub)
+}
+
+// OpenSession 创建 REST 接口会话，返回会话和实际使用的用户名
+//
+// 会话以用户身份出现在用户管理器中（客户端类型为 api），有在线状态，占用最大用户数；
+// 与TCP用户一样，超过超时时间没有请求时会话结束。
+func (ch *ConnectionHandler) OpenSession(name string) (*session.Session, string, error) {
+	s, err := ch.sessions.Create()
+	if err != nil {
+		return nil, "", err
+	}
+	currentUser, err := ch.userManager.CreateUser(s.UserID, name)
+	if err != nil {
+		ch.sessions.Remove(s.Token)
+		return nil, "", err
+	}
+	ch.userManager.SetUserLang(currentUser.ID, ch.config.Language)
+	// 以JSON接收事件，便于从中取出私聊
+	ch.userManager.SetUserMode(currentUser.ID, user.ModeJSON)
+	ch.userManager.SetUserClient(currentUser.ID, user.ClientAPI)
+
+	ch.userManager.BroadcastLocalized(currentUser.ID, message.FormatUserJoinMessage(currentUser.Name))
+	ch.notifyWebhook(webhook.Event{Type: webhook.TypeJoin, Room: currentUser.Room, User: currentUser.Name})
+	ch.logger.Info("REST 会话 %s (ID: %s) 已创建", currentUser.Name, currentUser.ID)
+
+	go ch.serveSession(s, currentUser)
+	return s, currentUser.Name, nil
+}
+
+// CloseSession 结束 REST 接口会话，会话用户离开聊天室
+func (ch *ConnectionHandler) CloseSession(token string) {
+	s, exists := ch.sessions.Remove(token)
+	if !exists {
+		return
+	}
+	if currentUser, exists := ch.userManager.GetUser(s.UserID); exists {
+		ch.CleanupUser(currentUser)
+	}
+}
+
+// Session 按令牌查找会话，并更新会话用户的最后活跃时间
+func (ch *ConnectionHandler) Session(token string) (*session.Session, *user.User, error) {
+	s, exists := ch.sessions.Get(token)
+	if !exists {
+		return nil, nil, session.ErrUnknown
+	}
+	currentUser, exists := ch.userManager.GetUser(s.UserID)
+	if !exists {
+		ch.sessions.Remove(token)
+		return nil, nil, session.ErrUnknown
+	}
+	if ch.userManager.UpdateUserLastSeen(currentUser.ID) {
+		ch.broadcastPresence(currentUser.ID)
+	}
+	return s, currentUser, nil
+}
+
+// serveSession 保存会话用户收到的私聊，并像 watchTimeout 一样监控超时和空闲状态
+func (ch *ConnectionHandler) serveSession(s *session.Session, currentUser *user.User) {
+	timeout, idleAfter, interval := ch.timeouts()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data, ok := <-currentUser.MsgChan:
+			if !ok {
+				return
+			}
+			// 其他通知（如加入离开、房间消息）通过接口读取，这里只保留私聊
+			var event message.Event
+			if json.Unmarshal([]byte(data), &event) == nil && event.Type == message.EventMessage &&
+				event.Message != nil && event.Message.Type == message.TypePrivate {
+				s.Deliver(event.Message)
+			}
+
+		case <-ticker.C:
+			snapshot, exists := ch.userManager.GetUserSnapshot(currentUser.ID)
+			if !exists {
+				return
+			}
+			if time.Since(snapshot.LastSeen) > timeout {
+				ch.logger.Info("REST 会话 %s 超时，自动结束", snapshot.Name)
+				ch.CloseSession(s.Token)
+				return
+			}
+			if ch.userManager.MarkIdle(currentUser.ID, idleAfter) {
+				ch.broadcastPresence(currentUser.ID)
+			}
+		}
+	}
+}
+
+// apiWait 把长轮询的等待时间限制在 maxAPIWait 和超时时间的一半以内，避免会话在等待中超时
+func (ch *ConnectionHandler) apiWait(wait time.Duration) time.Duration {
+	timeout, _, _ := ch.timeouts()
+	if wait > maxAPIWait {
+		wait = maxAPIWait
+	}
+	if wait > timeout/2 {
+		wait = timeout / 2
+	}
+	return wait
+}
+
+// apiText 清理通过 REST 接口发送的内容，换行视为空格
+func apiText(content string) string {
+	return utils.SanitizeInput(strings.ReplaceAll(content, "\n", " "))
+}
+
+// ListRooms 获取会话用户可以看到的房间，与 \rooms 相同：私密房间只对其中的用户和管理员显示
+func (ch *ConnectionHandler) ListRooms(currentUser *user.User) ([]room.Info, error) {
+	list, err := ch.rooms.List()
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]room.Info, 0, len(list))
+	for _, info := range list {
+		if info.Listed() || info.Name == currentUser.Room || currentUser.IsOperator() {
+			visible = append(visible, info)
+		}
+	}
+	return visible, nil
+}
+
+// ReadMessages 读取房间中序号大于 since 的消息（最多 maxAPIMessages 条），since 小于0时返回最近的消息，
+// 同时返回下一次读取使用的序号
+//
+// 已删除的消息默认不返回，tombstones 为 true 时以墓碑（内容为空，Deleted 为 true）返回，供客户端删除本地副本。
+// 没有新消息时最多等待 wait（长轮询），房间中有新消息或 ctx 结束时提前返回。
+// 非公开房间只有其中的用户和管理员可以读取，等待期间房间模式可能改变，每次读取前都重新检查。
+func (ch *ConnectionHandler) ReadMessages(ctx context.Context, currentUser *user.User, roomName string, since int64, wait time.Duration, tombstones bool) ([]*message.Message, int64, error) {
+	roomName, err := room.Normalize(roomName)
+	if err != nil {
+		return nil, since, err
+	}
+	if err := ch.checkReadable(currentUser, roomName); err != nil {
+		return nil, since, err
+	}
+	if since < 0 {
+		messages, err := ch.history.Recent(roomName, maxAPIMessages)
+		if err != nil {
+			return nil, 0, err
+		}
+		visible, next := visibleMessages(messages, 0, tombstones)
+		return visible, next, nil
+	}
+
+	// 先订阅再读取历史记录，等待期间到达的消息不会漏掉
+	sub := ch.streams.Watch(roomName)
+	defer ch.streams.Unsubscribe(sub)
+	timer := time.NewTimer(ch.apiWait(wait))
+	defer timer.Stop()
+
+	for {
+		messages, err := ch.history.Since(roomName, since, maxAPIMessages)
+		if err != nil {
+			return nil, since, err
+		}
+		visible, next := visibleMessages(messages, since, tombstones)
+		if len(visible) > 0 {
+			return visible, next, nil
+		}
+		// 新消息都已被删除时跳过它们继续等待
+		since = next
+		select {
+		case <-sub.Events():
+		case <-sub.Done():
+			return nil, since, nil
+		case <-timer.C:
+			return nil, since, nil
+		case <-ctx.Done():
+			return nil, since, nil
+		}
+		if err := ch.checkReadable(currentUser, roomName); err != nil {
+			return nil, since, err
+		}
+	}
+}
+
+// checkReadable 检查会话用户能否通过 REST 接口读取房间：公开房间，或者用户在该房间中，或者是管理员
+func (ch *ConnectionHandler) checkReadable(currentUser *user.User, roomName string) error {
+	r, exists, err := ch.rooms.Get(roomName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return i18n.Errorf("error.room_not_found", i18n.Params{"room": roomName})
+	}
+	if !r.Open() && roomName != currentUser.Room && !currentUser.IsOperator() {
+		return i18n.Errorf("error.api_room", i18n.Params{"room": roomName})
+	}
+	return nil
+}
+
+// visibleMessages 去掉已删除的消息（tombstones 为 true 时保留），返回剩下的消息和所有消息中最大的序号
+func visibleMessages(messages []*message.Message, since int64, tombstones bool) ([]*message.Message, int64) {
+	next := since
+	var visible []*message.Message
+	for _, msg := range messages {
+		if msg.Seq > next {
+			next = msg.Seq
+		}
+		if msg.Deleted && !tombstones {
+			continue
+		}
+		visible = append(visible, msg)
+	}
+	return visible, next
+}
+
+// PostMessage 以会话用户的身份在房间中发送消息，用户不在该房间时先加入（与 \join 相同的检查）
+func (ch *ConnectionHandler) PostMessage(currentUser *user.User, roomName, content string) (*message.Message, error) {
+	content = apiText(content)
+	if content == "" {
+		return nil, i18n.Errorf("error.api_empty", nil)
+	}
+	roomName, err := room.Normalize(roomName)
+	if err != nil {
+		return nil, err
+	}
+	if roomName != currentUser.Room {
+		if err := ch.handleJoin(currentUser, roomName, ""); err != nil {
+			return nil, err
+		}
+	}
+	if content, err = ch.filterContent(currentUser, content); err != nil {
+		return nil, err
+	}
+	return ch.postChat(currentUser, content), nil
+}
+
+// SendWhisper 以会话用户的身份发送私聊
+func (ch *ConnectionHandler) SendWhisper(currentUser *user.User, to, content string) error {
+	content = apiText(content)
+	if content == "" || strings.TrimSpace(to) == "" {
+		return i18n.Errorf("error.api_empty", nil)
+	}
+	content, err := ch.filterContent(currentUser, content)
+	if err != nil {
+		return err
+	}
+	return ch.handleWhisper(currentUser, strings.TrimSpace(to), content)
+}
+
+// ReadWhispers 读取会话收到的序号大于 since 的私聊，没有时最多等待 wait（长轮询）
+func (ch *ConnectionHandler) ReadWhispers(ctx context.Context, s *session.Session, since int64, wait time.Duration) []session.Whisper {
+	timer := time.NewTimer(ch.apiWait(wait))
+	defer timer.Stop()
+
+	for {
+		whispers, wake := s.Whispers(since)
+		if len(whispers) > 0 {
+			return whispers
+		}
+		select {
+		case <-wake:
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// ListUsers 获取本实例的在线用户（按用户名排序），与 \whois 相同，非公开房间只对同一房间的用户显示房间名
+func (ch *ConnectionHandler) ListUsers(currentUser *user.User) []user.User {
+	users := ch.userManager.GetAllUsers()
+	snapshots := make([]user.User, 0, len(users))
+	for _, u := range users {
+		snapshot, exists := ch.userManager.GetUserSnapshot(u.ID)
+		if !exists {
+			continue
+		}
+		if snapshot.Room != currentUser.Room {
+			if r, exists, err := ch.rooms.Get(snapshot.Room); err != nil || !exists || !r.Open() {
+				snapshot.Room = ""
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return nickname.Key(snapshots[i].Name) < nickname.Key(snapshots[j].Name)
+	})
+	return snapshots
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"chatroom/attachment"
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/user"
+	"chatroom/utils"
+)
+
+// handleSend 接收紧跟在命令之后的 cmd.Size 字节文件内容，保存并通知接收者
+func (ch *ConnectionHandler) handleSend(currentUser *user.User, reader *bufio.Reader, cmd message.Command) error {
+	if reader == nil {
+		return i18n.Errorf("error.send_unsupported", nil)
+	}
+
+	// 无论是否接受都要读完文件内容，保持协议同步
+	discard := func(err error) error {
+		io.CopyN(io.Discard, reader, cmd.Size)
+		return err
+	}
+
+	if cmd.Size > ch.attachments.MaxSize() {
+		return discard(i18n.Errorf("error.attachment_too_big", i18n.Params{
+			"size": utils.FormatSize(cmd.Size), "max": utils.FormatSize(ch.attachments.MaxSize()),
+		}))
+	}
+
+	meta := attachment.Attachment{
+		Name:   cmd.Content,
+		Size:   cmd.Size,
+		From:   currentUser.Name,
+		FromID: currentUser.ID,
+		To:     attachment.RoomTarget,
+		Room:   currentUser.Room,
+	}
+	if !strings.EqualFold(cmd.Target, attachment.RoomTarget) {
+		targetUser, exists := ch.userManager.FindUserByName(cmd.Target)
+		if !exists {
+			return discard(i18n.Errorf("error.user_offline", i18n.Params{"name": cmd.Target}))
+		}
+		if ch.userManager.IsIgnoring(targetUser.ID, currentUser.Name) {
+			return discard(i18n.Errorf("error.whisper_unavailable", i18n.Params{"name": targetUser.Name}))
+		}
+		meta.To = targetUser.Name
+		meta.ToID = targetUser.ID
+		meta.Room = ""
+	}
+
+	saved, err := ch.attachments.Put(meta, reader)
+	if err != nil {
+		ch.logger.Error("保存附件失败: %v", err)
+		return i18n.Errorf("error.attachment_failed", nil)
+	}
+
+	params := i18n.Params{
+		"from": saved.From, "to": saved.To, "name": saved.Name,
+		"size": utils.FormatSize(saved.Size), "id": saved.ID,
+	}
+	if saved.To == attachment.RoomTarget {
+		ch.userManager.BroadcastRoomLocalized(currentUser.Room, currentUser.ID, i18n.NewText("attachment.room", params))
+	} else {
+		ch.userManager.SendLocalized(saved.ToID, i18n.NewText("attachment.private", params))
+	}
+	ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("attachment.sent", params))
+
+	ch.logger.Info("用户 %s 向 %s 发送文件 %s (%d字节)", saved.From, saved.To, saved.ID, saved.Size)
+	return nil
+}
+
+// handleGet 下载文件
+//
+// 文件以一行 "FILE <下载ID> <字节数> <文件名>" 开头，后面紧跟文件内容和一个换行符。
+func (ch *ConnectionHandler) handleGet(currentUser *user.User, id string) error {
+	missing := i18n.Errorf("error.attachment_missing", i18n.Params{"id": id})
+
+	meta, err := ch.attachments.Get(id)
+	if err != nil || !meta.CanAccess(currentUser.ID, func(name string) bool { return ch.canReadRoom(currentUser, name) }) {
+		return missing
+	}
+
+	file, err := ch.attachments.Open(meta)
+	if err != nil {
+		ch.logger.Error("打开附件失败: %v", err)
+		return missing
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, meta.Size))
+	if err != nil {
+		ch.logger.Error("读取附件失败: %v", err)
+		return i18n.Errorf("error.attachment_failed", nil)
+	}
+
+	header := fmt.Sprintf("FILE %s %d %s\n", meta.ID, len(data), meta.Name)
+	return ch.userManager.SendRaw(currentUser.ID, header+string(data)+"\n")
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"strings"
+
+	"chatroom/bot"
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/user"
+	"chatroom/utils"
+)
+
+// handleHook 列出、创建或删除入站 webhook 机器人
+func (ch *ConnectionHandler) handleHook(currentUser *user.User, cmd message.Command) error {
+	switch cmd.Content {
+	case "add":
+		if cmd.Target == "" || len(cmd.Options) == 0 {
+			return i18n.Errorf("error.hook_usage", nil)
+		}
+		b, token, err := ch.bots.Create(cmd.Target, cmd.Options[0], currentUser.Name)
+		if err != nil {
+			return err
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("hook.created", i18n.Params{
+			"name": b.Name, "id": b.ID, "room": b.Room, "token": token,
+		}))
+		if ch.config.HTTPAddr == "" {
+			ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("hook.http_disabled", nil))
+		}
+		ch.logger.Info("管理员 %s 创建了机器人 %s (编号 %s)，房间 %s", currentUser.Name, b.Name, b.ID, b.Room)
+
+	case "remove":
+		if cmd.Target == "" {
+			return i18n.Errorf("error.hook_usage", nil)
+		}
+		b, err := ch.bots.Remove(cmd.Target)
+		if err != nil {
+			return err
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("hook.removed", i18n.Params{"name": b.Name, "id": b.ID}))
+		ch.logger.Info("管理员 %s 删除了机器人 %s (编号 %s)", currentUser.Name, b.Name, b.ID)
+
+	default:
+		list, err := ch.bots.List()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("hook.none", nil))
+			return nil
+		}
+		lines := []string{i18n.T(currentUser.Lang, "hook.list", i18n.Params{"count": len(list)})}
+		for _, b := range list {
+			lines = append(lines, i18n.T(currentUser.Lang, "hook.item", i18n.Params{
+				"id": b.ID, "name": b.Name, "room": b.Room, "creator": b.Creator, "time": formatJobTime(b.Created),
+			}))
+		}
+		ch.userManager.SendToUser(currentUser.ID, strings.Join(lines, "\n")+"\n")
+	}
+	return nil
+}
+
+// PostBotMessage 以令牌对应的机器人身份在其房间中发送消息，供入站 webhook 调用，每个非空行作为一条消息
+func (ch *ConnectionHandler) PostBotMessage(token, text string) ([]*message.Message, error) {
+	b, exists, err := ch.bots.Authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, bot.ErrUnknownToken
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, i18n.Errorf("error.hook_empty", nil)
+	}
+	if len(lines) > maxBotLines {
+		return nil, i18n.Errorf("error.hook_lines", i18n.Params{"max": maxBotLines})
+	}
+	return ch.SendBotMessages(b.Name, b.Room, lines)
+}
+
+// SendBotMessages 以机器人身份在房间中发送消息，每行一条
+//
+// 每行先去掉控制字符（包括终端转义序列的 ESC），去掉后为空的行不发送。
+// 消息带机器人标记，经过内容过滤后走正常的广播路径并保存到历史记录；任何一行被过滤规则拒绝时都不发送。
+// 机器人消息不触发出站 webhook，避免两端互相转发形成回环。
+func (ch *ConnectionHandler) SendBotMessages(name, roomName string, lines []string) ([]*message.Message, error) {
+	sanitized := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = utils.SanitizeInput(line); line != "" {
+			sanitized = append(sanitized, line)
+		}
+	}
+	if len(sanitized) == 0 {
+		return nil, i18n.Errorf("error.hook_empty", nil)
+	}
+	lines = sanitized
+
+	for i, line := range lines {
+		result := ch.filter.Apply(line)
+		for _, rule := range result.Flagged {
+			ch.logger.Warn("机器人 %s 的消息触发过滤规则 %s", name, rule)
+			ch.userManager.NotifyOperators(i18n.NewText("filter.flagged", i18n.Params{
+				"name": name, "rule": rule.String(), "content": line,
+			}))
+		}
+		if result.Rejected != nil {
+			ch.logger.Warn("机器人 %s 的消息被过滤规则 %s 拒绝", name, result.Rejected)
+			return nil, i18n.Errorf("error.filter_rejected", nil)
+		}
+		lines[i] = result.Content
+	}
+
+	messages := make([]*message.Message, 0, len(lines))
+	for _, line := range lines {
+		msg := message.NewMessage(message.TypeChat, name, line)
+		msg.Bot = true
+		msg.Room = roomName
+		if err := ch.history.Append(roomName, msg); err != nil {
+			ch.logger.Error("保存历史消息失败: %v", err)
+		}
+		ch.userManager.BroadcastMessage(msg)
+		messages = append(messages, msg)
+	}
+	ch.logger.Info("机器人 %s 在房间 %s 发送了 %d 条消息", name, roomName, len(messages))
+	return messages, nil
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"time"
+
+	"chatroom/dm"
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/user"
+	"chatroom/utils"
+	"chatroom/webhook"
+)
+
+// filterContent 对要发送的内容执行过滤规则，返回过滤后的内容
+//
+// 被禁言或内容被规则拒绝时返回错误；命中 flag 规则时通知管理员，命中 mute 规则时禁言发送者。
+func (ch *ConnectionHandler) filterContent(currentUser *user.User, content string) (string, error) {
+	if remaining := ch.userManager.MutedFor(currentUser.ID); remaining > 0 {
+		return "", i18n.Errorf("error.muted", i18n.Params{"duration": remaining.Round(time.Second)})
+	}
+
+	result := ch.filter.Apply(content)
+	for _, rule := range result.Flagged {
+		ch.logger.Warn("用户 %s 的消息触发过滤规则 %s", currentUser.Name, rule)
+		ch.userManager.NotifyOperators(i18n.NewText("filter.flagged", i18n.Params{
+			"name": currentUser.Name, "rule": rule.String(), "content": content,
+		}))
+		ch.notifyModeration(webhook.ActionFlag, currentUser, rule.String(), content)
+	}
+	if result.Rejected == nil {
+		return result.Content, nil
+	}
+
+	ch.logger.Warn("用户 %s 的消息被过滤规则 %s 拒绝", currentUser.Name, result.Rejected)
+	if result.MuteFor > 0 {
+		ch.notifyModeration(webhook.ActionMute, currentUser, result.Rejected.String(), content)
+		ch.userManager.MuteUser(currentUser.ID, time.Now().Add(result.MuteFor))
+		ch.userManager.NotifyOperators(i18n.NewText("filter.muted", i18n.Params{
+			"name": currentUser.Name, "rule": result.Rejected.String(), "duration": result.MuteFor.String(),
+		}))
+		return "", i18n.Errorf("error.filter_muted", i18n.Params{"duration": result.MuteFor})
+	}
+	ch.notifyModeration(webhook.ActionReject, currentUser, result.Rejected.String(), content)
+	return "", i18n.Errorf("error.filter_rejected", nil)
+}
+
+// postChat 在用户所在的房间发送聊天消息，内容需要已经过滤
+func (ch *ConnectionHandler) postChat(currentUser *user.User, content string) *message.Message {
+	chatMsg := message.NewMessage(message.TypeChat, currentUser.Name, content)
+	chatMsg.FromID = currentUser.ID
+	chatMsg.Room = currentUser.Room
+	if err := ch.history.Append(currentUser.Room, chatMsg); err != nil {
+		ch.logger.Error("保存历史消息失败: %v", err)
+	}
+	ch.userManager.BroadcastMessage(chatMsg)
+	ch.notifyChat(chatMsg)
+	ch.logger.Info("用户 %s 发送消息: %s", currentUser.Name, utils.TruncateString(content, 50))
+	return chatMsg
+}
+
+// notifyWebhook 标记事件所在房间是否公开后发送给 webhook，非公开房间的事件只投递给明确指定了该房间的 webhook
+func (ch *ConnectionHandler) notifyWebhook(e webhook.Event) {
+	if ch.webhooks.Count() == 0 {
+		return
+	}
+	e.Open = e.Room == ""
+	if r, exists, err := ch.rooms.Get(e.Room); err == nil && exists {
+		e.Open = r.Open()
+	}
+	ch.webhooks.Notify(e)
+}
+
+// notifyModeration 把过滤规则触发的管理动作发送给 webhook
+func (ch *ConnectionHandler) notifyModeration(action string, currentUser *user.User, rule, content string) {
+	ch.notifyWebhook(webhook.Event{
+		Type:   webhook.TypeModeration,
+		Room:   currentUser.Room,
+		User:   currentUser.Name,
+		Text:   content,
+		Action: action,
+		Reason: rule,
+	})
+}
+
+// notifyChat 把房间中的聊天消息发送给 webhook
+func (ch *ConnectionHandler) notifyChat(msg *message.Message) {
+	ch.notifyWebhook(webhook.Event{
+		Type:      webhook.TypeChat,
+		Room:      msg.Room,
+		User:      msg.From,
+		MessageID: msg.ID,
+		Text:      msg.Content,
+		Time:      msg.Timestamp,
+	})
+}
+
+// handleWhisper 处理私聊消息
+//
+// 目标用户可以连接在任意实例，不在本实例时经状态后端转发。
+func (ch *ConnectionHandler) handleWhisper(fromUser *user.User, targetName, content string) error {
+	// 查找目标用户
+	targetID, name, online := ch.userManager.LookupOnline(targetName)
+	if !online {
+		return i18n.Errorf("error.user_offline", i18n.Params{"name": targetName})
+	}
+
+	// 对方屏蔽了发送者时拒绝，错误信息不透露屏蔽关系
+	if ch.userManager.IgnoredBy(targetID, fromUser.Name) {
+		return i18n.Errorf("error.whisper_unavailable", i18n.Params{"name": name})
+	}
+
+	// 创建私聊消息
+	whisperMsg := message.NewPrivateMessage(fromUser.Name, name, content)
+	whisperMsg.FromID = fromUser.ID
+	whisperMsg.ToID = targetID
+
+	// 发送给目标用户
+	if err := ch.userManager.SendMessageToUser(targetID, whisperMsg); err != nil {
+		return i18n.Errorf("error.whisper_failed", i18n.Params{"error": i18n.Localize(fromUser.Lang, err)})
+	}
+
+	// 保存私聊记录，导出时只包含用户参与的私聊
+	if err := ch.history.Append(message.PrivateRoom, whisperMsg); err != nil {
+		ch.logger.Error("保存私聊消息失败: %v", err)
+	}
+
+	// 发送确认消息给发送者
+	confirmMsg := i18n.NewText("whisper.sent", i18n.Params{"to": name, "content": content})
+	ch.userManager.SendLocalized(fromUser.ID, confirmMsg)
+
+	// 目标用户离开时自动回复离开留言（只有本实例的用户知道在线状态）
+	if snapshot, exists := ch.userManager.GetUserSnapshot(targetID); exists && snapshot.Presence == user.PresenceAway {
+		if snapshot.StatusMessage != "" {
+			ch.userManager.SendLocalized(fromUser.ID, i18n.NewText("whisper.away_reply",
+				i18n.Params{"name": snapshot.Name, "message": snapshot.StatusMessage}))
+		} else {
+			ch.userManager.SendLocalized(fromUser.ID, i18n.NewText("whisper.away_reply_bare",
+				i18n.Params{"name": snapshot.Name}))
+		}
+	}
+
+	// 记录双方最近的私聊对象，之后可以用 \r 直接回复
+	ch.rememberTarget(fromUser.ID, dm.Target{Name: name})
+	ch.rememberTarget(targetID, dm.Target{Name: fromUser.Name})
+
+	ch.logger.Info("用户 %s 向 %s 发送私聊消息", fromUser.Name, name)
+	return nil
+}
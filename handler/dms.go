@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"chatroom/dm"
+	"chatroom/i18n"
+	"chatroom/user"
+)
+
+// handleDM 处理私聊群组命令
+//
+// \dm <用户1,用户2,...> [内容] 创建群组（可以同时发送第一条消息），\dm <代号> <内容> 在群组中发言，
+// \dm list 列出所在的群组，\dm add|remove <代号> <用户名> 增删成员，\dm leave <代号> 离开群组。
+func (ch *ConnectionHandler) handleDM(currentUser *user.User, target, content string) error {
+	switch action := strings.ToLower(target); action {
+	case "list":
+		return ch.listDMs(currentUser)
+	case "add", "remove":
+		args := strings.Fields(content)
+		if len(args) != 2 {
+			return i18n.Errorf("error.missing_arg", i18n.Params{"arg": "<handle> <name>", "usage": "\\dm " + action + " <handle> <name>"})
+		}
+		if action == "add" {
+			return ch.addDMMember(currentUser, args[0], args[1])
+		}
+		conversation, err := ch.dms.Find(args[0], currentUser.ID)
+		if err != nil {
+			return err
+		}
+		memberID, exists := conversation.Member(args[1])
+		if !exists {
+			return i18n.Errorf("error.dm_not_member", i18n.Params{"name": args[1], "handle": conversation.Handle})
+		}
+		return ch.removeDMMember(currentUser, conversation.Handle, memberID)
+	case "leave":
+		if content == "" {
+			return i18n.Errorf("error.missing_arg", i18n.Params{"arg": "<handle>", "usage": "\\dm leave <handle>"})
+		}
+		return ch.removeDMMember(currentUser, strings.TrimSpace(content), currentUser.ID)
+	}
+
+	// 用户所在群组的代号：在群组中发言
+	if ch.dms.IsHandle(target, currentUser.ID) {
+		if content == "" {
+			return i18n.Errorf("error.missing_arg", i18n.Params{"arg": "<text>", "usage": "\\dm <handle> <text>"})
+		}
+		conversation, err := ch.dms.Find(target, currentUser.ID)
+		if err != nil {
+			return err
+		}
+		if content, err = ch.filterContent(currentUser, content); err != nil {
+			return err
+		}
+		return ch.postDM(currentUser, conversation, content)
+	}
+
+	// 否则是逗号分隔的用户名列表：创建群组
+	return ch.openDM(currentUser, strings.Split(target, ","), content)
+}
+
+// openDM 创建私聊群组并通知所有成员，content 不为空时作为第一条消息发送
+func (ch *ConnectionHandler) openDM(currentUser *user.User, names []string, content string) error {
+	members := make(map[string]string)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, actual, online := ch.userManager.LookupOnline(name)
+		if !online {
+			return i18n.Errorf("error.user_offline", i18n.Params{"name": name})
+		}
+		// 对方屏蔽了创建者时拒绝，错误信息不透露屏蔽关系
+		if ch.userManager.IgnoredBy(id, currentUser.Name) {
+			return i18n.Errorf("error.dm_unavailable", i18n.Params{"name": actual})
+		}
+		members[id] = actual
+	}
+
+	conversation, err := ch.dms.Open(currentUser.ID, currentUser.Name, members)
+	if err != nil {
+		return err
+	}
+	ch.notifyDM(conversation, i18n.NewText("dm.opened", i18n.Params{
+		"handle": conversation.Handle, "by": currentUser.Name, "members": strings.Join(conversation.Names(), ", "),
+	}))
+	ch.logger.Info("用户 %s 创建了私聊群组 %s (%d 人)", currentUser.Name, conversation.Handle, len(conversation.Members))
+
+	if content == "" {
+		return nil
+	}
+	if content, err = ch.filterContent(currentUser, content); err != nil {
+		return err
+	}
+	return ch.postDM(currentUser, conversation, content)
+}
+
+// postDM 在私聊群组中发言，消息带 [DM:代号] 前缀发送给每个在线成员（包括发送者自己），内容需要已经过滤
+//
+// 屏蔽了发送者的成员不会收到；群组消息不保存到历史记录，日志中也不记录内容。
+func (ch *ConnectionHandler) postDM(currentUser *user.User, conversation dm.Conversation, content string) error {
+	line := fmt.Sprintf("%s %s: %s", conversation.Prefix(), currentUser.Name, content)
+
+	var offline []string
+	for id, name := range conversation.Members {
+		if id == currentUser.ID {
+			continue
+		}
+		if ch.userManager.IgnoredBy(id, currentUser.Name) {
+			continue
+		}
+		if err := ch.userManager.SendToUser(id, line); err != nil {
+			offline = append(offline, name)
+			continue
+		}
+		ch.rememberTarget(id, dm.Target{Handle: conversation.Handle})
+	}
+	sort.Strings(offline)
+
+	ch.userManager.SendToUser(currentUser.ID, line)
+	ch.rememberTarget(currentUser.ID, dm.Target{Handle: conversation.Handle})
+	if len(offline) > 0 {
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("dm.offline", i18n.Params{"names": strings.Join(offline, ", ")}))
+	}
+
+	ch.logger.Info("用户 %s 在私聊群组 %s 发送消息", currentUser.Name, conversation.Handle)
+	return nil
+}
+
+// addDMMember 把在线用户加入私聊群组，群组的任何成员都可以加人
+func (ch *ConnectionHandler) addDMMember(currentUser *user.User, handle, name string) error {
+	id, actual, online := ch.userManager.LookupOnline(name)
+	if !online {
+		return i18n.Errorf("error.user_offline", i18n.Params{"name": name})
+	}
+	if ch.userManager.IgnoredBy(id, currentUser.Name) {
+		return i18n.Errorf("error.dm_unavailable", i18n.Params{"name": actual})
+	}
+
+	conversation, err := ch.dms.Add(handle, currentUser.ID, id, actual)
+	if err != nil {
+		return err
+	}
+	ch.notifyDM(conversation, i18n.NewText("dm.added", i18n.Params{
+		"handle": conversation.Handle, "by": currentUser.Name, "name": actual, "members": strings.Join(conversation.Names(), ", "),
+	}))
+	ch.logger.Info("用户 %s 把 %s 加入私聊群组 %s", currentUser.Name, actual, conversation.Handle)
+	return nil
+}
+
+// removeDMMember 把用户移出私聊群组，移出自己即离开群组
+//
+// 被移出的用户和剩下的成员都会收到通知。
+func (ch *ConnectionHandler) removeDMMember(currentUser *user.User, handle, removedID string) error {
+	conversation, removed, err := ch.dms.Remove(handle, currentUser.ID, removedID)
+	if err != nil {
+		return err
+	}
+
+	var text i18n.Text
+	if removedID == currentUser.ID {
+		text = i18n.NewText("dm.left", i18n.Params{"handle": conversation.Handle, "name": removed})
+		ch.logger.Info("用户 %s 离开了私聊群组 %s", removed, conversation.Handle)
+	} else {
+		text = i18n.NewText("dm.removed", i18n.Params{"handle": conversation.Handle, "by": currentUser.Name, "name": removed})
+		ch.logger.Info("用户 %s 把 %s 移出私聊群组 %s", currentUser.Name, removed, conversation.Handle)
+	}
+	ch.userManager.SendLocalized(removedID, text)
+	ch.notifyDM(conversation, text)
+	return nil
+}
+
+// listDMs 列出用户所在的私聊群组
+func (ch *ConnectionHandler) listDMs(currentUser *user.User) error {
+	list, err := ch.dms.List(currentUser.ID)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("dm.none", nil))
+		return nil
+	}
+
+	lines := []string{i18n.T(currentUser.Lang, "dm.list", i18n.Params{"count": len(list)})}
+	for _, conversation := range list {
+		lines = append(lines, i18n.T(currentUser.Lang, "dm.item", i18n.Params{
+			"handle": conversation.Handle, "members": strings.Join(conversation.Names(), ", "),
+		}))
+	}
+	ch.userManager.SendToUser(currentUser.ID, strings.Join(lines, "\n"))
+	return nil
+}
+
+// handleRespond 回复最近的私聊对象：一对一私聊或私聊群组，收发都会更新
+func (ch *ConnectionHandler) handleRespond(currentUser *user.User, content string) error {
+	target, exists, err := ch.dms.LastTarget(currentUser.ID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return i18n.Errorf("error.dm_no_reply", nil)
+	}
+	if target.Handle == "" {
+		return ch.handleWhisper(currentUser, target.Name, content)
+	}
+	conversation, err := ch.dms.Find(target.Handle, currentUser.ID)
+	if err != nil {
+		return err
+	}
+	return ch.postDM(currentUser, conversation, content)
+}
+
+// notifyDM 向私聊群组的所有在线成员发送通知
+func (ch *ConnectionHandler) notifyDM(conversation dm.Conversation, text i18n.Text) {
+	for id := range conversation.Members {
+		ch.userManager.SendLocalized(id, text)
+	}
+}
+
+// rememberTarget 记录用户最近的私聊对象，失败只记录日志
+func (ch *ConnectionHandler) rememberTarget(userID string, target dm.Target) {
+	if err := ch.dms.Remember(userID, target); err != nil {
+		ch.logger.Error("记录私聊对象失败: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"chatroom/e2e"
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/user"
+)
+
+// handlePubKey 发布或查询端到端加密公钥
+//
+// \pubkey <加密公钥> <签名公钥> 发布自己的公钥，\pubkey <用户名> 查询他人的公钥（以 pubkey 事件返回，
+// 供客户端加密和验证签名），不带参数时显示自己已发布公钥的指纹。公钥在断开连接后失效。
+func (ch *ConnectionHandler) handlePubKey(currentUser *user.User, target, signKey string) error {
+
+	// 发布公钥
+	if signKey != "" {
+		key, err := e2e.ParsePublicKey(target, signKey)
+		if err != nil {
+			return i18n.Errorf("error.pubkey_invalid", nil)
+		}
+		if err := ch.keys.SetPublicKey(currentUser.ID, key.String()); err != nil {
+			return err
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("pubkey.published", i18n.Params{"fingerprint": key.Fingerprint()}))
+		ch.logger.Info("用户 %s 发布了端到端加密公钥 (%s)", currentUser.Name, key.Fingerprint())
+		return nil
+	}
+
+	// 查看自己的公钥
+	if target == "" {
+		key, exists, err := ch.publicKey(currentUser.ID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("pubkey.none", nil))
+			return nil
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("pubkey.own", i18n.Params{"fingerprint": key.Fingerprint()}))
+		return nil
+	}
+
+	// 查询他人的公钥
+	id, name, online := ch.userManager.LookupOnline(target)
+	if !online {
+		return i18n.Errorf("error.user_offline", i18n.Params{"name": target})
+	}
+	key, exists, err := ch.publicKey(id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return i18n.Errorf("error.pubkey_missing", i18n.Params{"name": name})
+	}
+	return ch.userManager.SendEventToUser(currentUser.ID, message.NewKeyEvent(message.Key{
+		User: name, Box: key.BoxString(), Sign: key.SignString(), Fingerprint: key.Fingerprint(),
+	}))
+}
+
+// handleSealed 转发端到端加密私聊
+//
+// 服务器只检查密封信封的格式并原样转发给接收者，不解密、不过滤、不保存到历史记录，日志中也不记录内容。
+// 双方都需要已发布公钥：发送者用接收者的公钥加密，接收者用发送者的公钥验证签名。
+func (ch *ConnectionHandler) handleSealed(currentUser *user.User, targetName, box string) error {
+	if !e2e.ValidBox(box) {
+		return i18n.Errorf("error.sealed_malformed", nil)
+	}
+	_, exists, err := ch.publicKey(currentUser.ID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return i18n.Errorf("error.pubkey_required", nil)
+	}
+
+	id, name, online := ch.userManager.LookupOnline(targetName)
+	if !online {
+		return i18n.Errorf("error.user_offline", i18n.Params{"name": targetName})
+	}
+	// 对方屏蔽了发送者时拒绝，错误信息不透露屏蔽关系
+	if ch.userManager.IgnoredBy(id, currentUser.Name) {
+		return i18n.Errorf("error.whisper_unavailable", i18n.Params{"name": name})
+	}
+	if _, exists, err = ch.publicKey(id); err != nil {
+		return err
+	}
+	if !exists {
+		return i18n.Errorf("error.pubkey_missing", i18n.Params{"name": name})
+	}
+
+	if err := ch.userManager.SendEventToUser(id, message.NewSealedEvent(currentUser.Name, name, box)); err != nil {
+		return i18n.Errorf("error.whisper_failed", i18n.Params{"error": i18n.Localize(currentUser.Lang, err)})
+	}
+	ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("sealed.sent", i18n.Params{"to": name}))
+	ch.logger.Info("用户 %s 向 %s 发送加密私聊", currentUser.Name, name)
+	return nil
+}
+
+// publicKey 获取在线用户发布的公钥
+func (ch *ConnectionHandler) publicKey(id string) (e2e.PublicKey, bool, error) {
+	value, err := ch.keys.PublicKey(id)
+	if err != nil || value == "" {
+		return e2e.PublicKey{}, false, err
+	}
+	key, err := e2e.DecodePublicKey(value)
+	if err != nil {
+		return e2e.PublicKey{}, false, nil
+	}
+	return key, true, nil
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"bytes"
+	"time"
+
+	"chatroom/attachment"
+	"chatroom/export"
+	"chatroom/i18n"
+	"chatroom/room"
+	"chatroom/user"
+	"chatroom/utils"
+)
+
+// handleExport 将聊天记录（包括用户参与的私聊）导出为文件，作为私人附件发送给用户
+//
+// 参数顺序不限，按取值识别：md/html/jsonl 为格式，时长或日期为起始时间，其他为房间名。
+// 未指定房间时导出用户当前所在的房间。
+func (ch *ConnectionHandler) handleExport(currentUser *user.User, options []string) error {
+	roomName := currentUser.Room
+	format := export.DefaultFormat
+	var since time.Time
+	for _, option := range options {
+		if f, ok := export.ParseFormat(option); ok {
+			format = f
+		} else if t, ok := export.ParseSince(option, time.Now()); ok {
+			since = t
+		} else {
+			roomName = option
+		}
+	}
+	name, err := room.Normalize(roomName)
+	if err != nil {
+		return err
+	}
+	// 非公开房间只能由房间内的用户导出，不透露房间是否存在
+	if !ch.canReadRoom(currentUser, name) {
+		return i18n.Errorf("error.export_room", i18n.Params{"room": name})
+	}
+
+	transcript, err := export.Load(ch.history, name, export.ByUserID(currentUser.ID), since, currentUser.Lang)
+	if err != nil {
+		ch.logger.Error("加载历史消息失败: %v", err)
+		return i18n.Errorf("error.export_failed", nil)
+	}
+	if len(transcript.Messages) == 0 {
+		return i18n.Errorf("error.export_empty", nil)
+	}
+
+	var buf bytes.Buffer
+	if err := transcript.Render(&buf, format); err != nil {
+		ch.logger.Error("导出聊天记录失败: %v", err)
+		return i18n.Errorf("error.export_failed", nil)
+	}
+	size := int64(buf.Len())
+	if size > ch.attachments.MaxSize() {
+		return i18n.Errorf("error.export_too_big", i18n.Params{
+			"size": utils.FormatSize(size), "max": utils.FormatSize(ch.attachments.MaxSize()),
+		})
+	}
+
+	saved, err := ch.attachments.Put(attachment.Attachment{
+		Name:   transcript.FileName(format),
+		Size:   size,
+		From:   currentUser.Name,
+		FromID: currentUser.ID,
+		To:     currentUser.Name,
+		ToID:   currentUser.ID,
+	}, &buf)
+	if err != nil {
+		ch.logger.Error("保存导出文件失败: %v", err)
+		return i18n.Errorf("error.export_failed", nil)
+	}
+
+	ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("export.ready", i18n.Params{
+		"count": len(transcript.Messages), "name": saved.Name, "size": utils.FormatSize(saved.Size), "id": saved.ID,
+	}))
+	ch.logger.Info("用户 %s 导出了 %d 条消息 (%s)", currentUser.Name, len(transcript.Messages), saved.ID)
+	return nil
+}
@@ -2,33 +2,109 @@ package handler
 
 import (
 	"bufio"
+	"crypto/subtle"
 	"fmt"
 	"net"
 	"strings"
 	"time"
 
+	"chatroom/attachment"
+	"chatroom/bot"
 	"chatroom/config"
+	"chatroom/dm"
+	"chatroom/filter"
+	"chatroom/history"
+	"chatroom/i18n"
 	"chatroom/message"
+	"chatroom/motd"
+	"chatroom/poll"
+	"chatroom/room"
+	"chatroom/scheduler"
+	"chatroom/search"
+	"chatroom/session"
+	"chatroom/store"
+	"chatroom/stream"
 	"chatroom/user"
 	"chatroom/utils"
+	"chatroom/webhook"
+)
+
+const (
+	maxSearchResults = 20                   // \search 最多显示的结果数
+	maxJobsPerUser   = 20                   // 每个用户最多同时保留的提醒和定时广播数
+	maxScheduleAhead = 366 * 24 * time.Hour // 提醒和定时广播最远的执行时间
+	pollTallyEvery   = 5 * time.Second      // 投票结果更新的最短间隔
+	maxBotLines      = 20                   // 机器人一次请求最多发送的消息条数
+	maxAPIMessages   = 100                  // REST 接口一次最多返回的消息数
+	maxAPIWait       = 30 * time.Second     // REST 接口长轮询的最长等待时间
 )
 
 // ConnectionHandler 连接处理器
 type ConnectionHandler struct {
 	userManager   *user.UserManager      // 用户管理器
+	history       *history.Store         // 历史消息存储
+	attachments   *attachment.Store      // 附件存储
+	filter        *filter.Filter         // 内容过滤器
+	index         *search.Index          // 消息搜索索引
+	scheduler     *scheduler.Scheduler   // 定时任务调度器
+	polls         *poll.Manager          // 投票管理器
+	dms           *dm.Manager            // 私聊群组管理器
+	bots          *bot.Manager           // 入站 webhook 机器人管理器
+	keys          store.KeyStore         // 在线用户发布的端到端加密公钥
+	streams       *stream.Hub            // 只读事件流分发器
+	sessions      *session.Manager       // REST 接口会话管理器
+	rooms         *room.Manager          // 房间管理器
+	motd          *motd.MOTD             // 每日消息
+	webhooks      *webhook.Dispatcher    // webhook 分发器
+	started       time.Time              // 服务器启动时间
 	commandParser *message.CommandParser // 命令解析器
 	logger        *utils.Logger          // 日志记录器
 	config        *config.Config         // 配置
 }
 
+// Deps 连接处理器依赖的组件，由服务器创建后传入
+type Deps struct {
+	Users       *user.UserManager    // 用户管理器
+	History     *history.Store       // 历史消息存储
+	Attachments *attachment.Store    // 附件存储
+	Filter      *filter.Filter       // 内容过滤器
+	Index       *search.Index        // 消息搜索索引
+	Scheduler   *scheduler.Scheduler // 定时任务调度器
+	Rooms       *room.Manager        // 房间管理器
+	DMs         *dm.Manager          // 私聊群组管理器
+	Bots        *bot.Manager         // 入站 webhook 机器人管理器
+	Keys        store.KeyStore       // 在线用户发布的端到端加密公钥
+	MOTD        *motd.MOTD           // 每日消息
+	Webhooks    *webhook.Dispatcher  // webhook 分发器
+	Logger      *utils.Logger        // 日志记录器
+	Config      *config.Config       // 配置
+}
+
 // NewConnectionHandler 创建新的连接处理器
-func NewConnectionHandler(userManager *user.UserManager, logger *utils.Logger, cfg *config.Config) *ConnectionHandler {
-	return &ConnectionHandler{
-		userManager:   userManager,
+func NewConnectionHandler(deps Deps) *ConnectionHandler {
+	ch := &ConnectionHandler{
+		userManager:   deps.Users,
+		history:       deps.History,
+		attachments:   deps.Attachments,
+		filter:        deps.Filter,
+		index:         deps.Index,
+		scheduler:     deps.Scheduler,
+		dms:           deps.DMs,
+		bots:          deps.Bots,
+		keys:          deps.Keys,
+		rooms:         deps.Rooms,
+		motd:          deps.MOTD,
+		webhooks:      deps.Webhooks,
+		started:       time.Now(),
 		commandParser: message.NewCommandParser(),
-		logger:        logger,
-		config:        cfg,
+		logger:        deps.Logger,
+		config:        deps.Config,
 	}
+	ch.polls = poll.NewManager(pollTallyEvery, ch.broadcastTally, ch.postPollResult)
+	ch.streams = stream.NewHub(ch.roomOpen)
+	ch.sessions = session.NewManager()
+	ch.userManager.AddEventListener(ch.streams.Observe)
+	return ch
 }
 
 // HandleConnection 处理客户端连接
@@ -39,26 +115,35 @@ func (ch *ConnectionHandler) HandleConnection(conn net.Conn) {
 	ch.logger.Info("客户端已连接: %s", clientAddr)
 
 	// 生成用户ID和默认用户名
-	userID := utils.GenerateUserID(conn)
+	userID := utils.GenerateUserID()
 	defaultUsername := utils.GenerateUsername(conn)
 
 	// 创建用户
 	currentUser, err := ch.userManager.CreateUser(userID, defaultUsername)
 	if err != nil {
 		ch.logger.Error("创建用户失败: %v", err)
-		conn.Write([]byte(fmt.Sprintf("错误: %s\n", err.Error())))
+		conn.Write([]byte(ch.formatError(ch.config.Language, err)))
 		return
 	}
+	ch.userManager.SetUserLang(currentUser.ID, ch.config.Language)
+	defer ch.CleanupUser(currentUser)
 
 	ch.logger.Info("用户 %s (ID: %s) 已创建", currentUser.Name, currentUser.ID)
 
 	// 发送欢迎消息
 	welcomeMsg := message.GetWelcomeMessage()
-	ch.userManager.SendToUser(currentUser.ID, welcomeMsg)
+	ch.userManager.SendLocalized(currentUser.ID, welcomeMsg)
+
+	// 发送每日消息和大厅话题
+	ch.sendMOTD(currentUser)
+	if lobby, exists, err := ch.rooms.Get(currentUser.Room); err == nil && exists {
+		ch.sendTopic(currentUser.ID, lobby)
+	}
 
 	// 广播用户加入消息
 	joinMsg := message.FormatUserJoinMessage(currentUser.Name)
-	ch.userManager.BroadcastToOthers(currentUser.ID, joinMsg)
+	ch.userManager.BroadcastLocalized(currentUser.ID, joinMsg)
+	ch.notifyWebhook(webhook.Event{Type: webhook.TypeJoin, Room: currentUser.Room, User: currentUser.Name})
 
 	// 启动消息写入协程
 	go ch.writeToClient(currentUser, conn)
@@ -86,8 +171,10 @@ func (ch *ConnectionHandler) handleClientMessages(currentUser *user.User, conn n
 			break
 		}
 
-		// 更新用户最后活跃时间
-		ch.userManager.UpdateUserLastSeen(currentUser.ID)
+		// 更新用户最后活跃时间，从空闲中恢复时广播状态变化
+		if ch.userManager.UpdateUserLastSeen(currentUser.ID) {
+			ch.broadcastPresence(currentUser.ID)
+		}
 
 		// 清理输入数据
 		input := utils.SanitizeInput(strings.TrimSpace(data))
@@ -95,12 +182,12 @@ func (ch *ConnectionHandler) handleClientMessages(currentUser *user.User, conn n
 			continue
 		}
 
-		ch.logger.Debug("收到来自 %s 的消息: %s", currentUser.Name, input)
+		ch.logger.Debug("收到来自 %s 的消息: %s", currentUser.Name, ch.loggableInput(input))
 
 		// 处理用户输入
-		if err := ch.processUserInput(currentUser, input); err != nil {
+		if err := ch.processUserInput(currentUser, input, reader); err != nil {
 			ch.logger.Error("处理用户输入失败: %v", err)
-			ch.userManager.SendToUser(currentUser.ID, fmt.Sprintf("错误: %s\n", err.Error()))
+			ch.userManager.SendToUser(currentUser.ID, ch.formatError(currentUser.Lang, err))
 		}
 
 		// 检查用户是否请求退出
@@ -114,24 +201,37 @@ func (ch *ConnectionHandler) handleClientMessages(currentUser *user.User, conn n
 }
 
 // processUserInput 处理用户输入
-func (ch *ConnectionHandler) processUserInput(currentUser *user.User, input string) error {
+//
+// reader 用于读取紧跟在 \send 命令之后的文件内容，为nil时不支持文件传输。
+func (ch *ConnectionHandler) processUserInput(currentUser *user.User, input string, reader *bufio.Reader) error {
 	// 解析命令
 	cmd, err := ch.commandParser.ParseCommand(input)
 	if err != nil {
 		return err
 	}
 
+	// 检查命令需要的角色
+	if cmd.Spec != nil && cmd.Spec.Role != "" && string(currentUser.Role) != cmd.Spec.Role {
+		return i18n.Errorf("error.permission_denied", i18n.Params{"command": "\\" + cmd.Spec.Name})
+	}
+
+	// 对要发送给其他用户的内容执行过滤规则
+	switch cmd.Type {
+	case message.CmdChat, message.CmdWhisper, message.CmdRespond, message.CmdReply, message.CmdEdit, message.CmdSchedule:
+		if cmd.Content, err = ch.filterContent(currentUser, cmd.Content); err != nil {
+			return err
+		}
+	}
+
 	// 处理不同类型的命令
 	switch cmd.Type {
 	case message.CmdChat:
 		// 普通聊天消息
-		chatMsg := message.NewMessage(message.TypeChat, currentUser.Name, cmd.Content)
-		ch.userManager.BroadcastToAll(chatMsg.FormatMessage())
-		ch.logger.Info("用户 %s 发送消息: %s", currentUser.Name, utils.TruncateString(cmd.Content, 50))
+		ch.postChat(currentUser, cmd.Content)
 
 	case message.CmdWho:
 		// 查询在线用户
-		userList := ch.userManager.GetUserList()
+		userList := ch.userManager.GetUserList(currentUser.Lang)
 		ch.userManager.SendToUser(currentUser.ID, userList)
 
 	case message.CmdRename:
@@ -147,26 +247,53 @@ func (ch *ConnectionHandler) processUserInput(currentUser *user.User, input stri
 		}
 
 		// 发送成功消息
-		ch.userManager.SendToUser(currentUser.ID, fmt.Sprintf("用户名已更改为: %s\n", currentUser.Name))
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("rename.ok", i18n.Params{"name": currentUser.Name}))
 
 		// 广播重命名消息
 		renameMsg := message.FormatUserRenameMessage(oldName, currentUser.Name)
-		ch.userManager.BroadcastToOthers(currentUser.ID, renameMsg)
+		ch.userManager.BroadcastLocalized(currentUser.ID, renameMsg)
+
+		// 私聊群组中显示新用户名
+		if err := ch.dms.Rename(currentUser.ID, currentUser.Name); err != nil {
+			ch.logger.Error("更新私聊群组成员失败: %v", err)
+		}
 
 	case message.CmdHelp:
 		// 显示帮助信息
-		helpMsg := message.GetHelpMessage()
-		ch.userManager.SendToUser(currentUser.ID, helpMsg+"\n")
+		if cmd.Content == "" {
+			ch.userManager.SendToUser(currentUser.ID, message.GetHelpMessage(currentUser.Lang))
+			return nil
+		}
+		helpMsg, err := message.GetCommandHelp(currentUser.Lang, cmd.Content)
+		if err != nil {
+			return err
+		}
+		ch.userManager.SendToUser(currentUser.ID, helpMsg)
 
 	case message.CmdTime:
 		// 显示当前时间
 		timeMsg := message.FormatTimeMessage()
-		ch.userManager.SendToUser(currentUser.ID, timeMsg+"\n")
+		ch.userManager.SendLocalized(currentUser.ID, timeMsg)
 
 	case message.CmdStats:
 		// 显示统计信息
 		statsMsg := message.FormatStatsMessage(ch.userManager.GetUserCount(), ch.config.MaxUsers)
-		ch.userManager.SendToUser(currentUser.ID, statsMsg+"\n")
+		ch.userManager.SendLocalized(currentUser.ID, statsMsg)
+
+	case message.CmdLang:
+		// 查看或切换界面语言
+		supported := strings.Join(i18n.Supported(), ", ")
+		if cmd.Content == "" {
+			ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("lang.current",
+				i18n.Params{"lang": currentUser.Lang, "supported": supported}))
+			return nil
+		}
+		lang, ok := i18n.Normalize(cmd.Content)
+		if !ok {
+			return i18n.Errorf("error.unsupported_lang", i18n.Params{"lang": cmd.Content, "supported": supported})
+		}
+		ch.userManager.SetUserLang(currentUser.ID, lang)
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("lang.changed", i18n.Params{"lang": lang}))
 
 	case message.CmdWhisper:
 		// 私聊消息
@@ -174,14 +301,301 @@ func (ch *ConnectionHandler) processUserInput(currentUser *user.User, input stri
 			return err
 		}
 
+	case message.CmdDM:
+		// 私聊群组
+		if err := ch.handleDM(currentUser, cmd.Target, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdRespond:
+		// 回复最近的私聊或私聊群组
+		if err := ch.handleRespond(currentUser, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdPubKey:
+		// 发布或查询端到端加密公钥
+		if err := ch.handlePubKey(currentUser, cmd.Target, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdSealed:
+		// 端到端加密私聊
+		if err := ch.handleSealed(currentUser, cmd.Target, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdHook:
+		// 管理入站 webhook 机器人（仅管理员）
+		if err := ch.handleHook(currentUser, cmd); err != nil {
+			return err
+		}
+
+	case message.CmdFormat:
+		// 切换输出模式
+		mode := user.OutputMode(cmd.Content)
+		ch.userManager.SetUserMode(currentUser.ID, mode)
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("format.changed", i18n.Params{"mode": mode}))
+
+	case message.CmdIDs:
+		// 显示或隐藏消息ID
+		showIDs := cmd.Content == "on"
+		ch.userManager.SetUserShowIDs(currentUser.ID, showIDs)
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("ids."+cmd.Content, nil))
+
+	case message.CmdOper:
+		// 管理员认证
+		if ch.config.OperatorPassword == "" {
+			return i18n.Errorf("error.oper_disabled", nil)
+		}
+		if subtle.ConstantTimeCompare([]byte(cmd.Content), []byte(ch.config.OperatorPassword)) != 1 {
+			ch.logger.Warn("用户 %s 管理员认证失败", currentUser.Name)
+			return i18n.Errorf("error.oper_denied", nil)
+		}
+		ch.userManager.SetUserRole(currentUser.ID, user.RoleOperator)
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("oper.ok", nil))
+		ch.logger.Info("用户 %s 成为管理员", currentUser.Name)
+
+	case message.CmdEdit:
+		// 编辑消息
+		if err := ch.handleEdit(currentUser, cmd.Target, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdDelete:
+		// 删除消息
+		if err := ch.handleDelete(currentUser, cmd.Target); err != nil {
+			return err
+		}
+
+	case message.CmdReact:
+		// 添加表情回应
+		if err := ch.handleReact(currentUser, cmd.Target, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdUnreact:
+		// 取消表情回应
+		if err := ch.handleUnreact(currentUser, cmd.Target, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdReactions:
+		// 查看表情回应
+		if err := ch.handleReactions(currentUser, cmd.Target); err != nil {
+			return err
+		}
+
+	case message.CmdReply:
+		// 回复消息
+		if err := ch.handleReply(currentUser, cmd.Target, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdThread:
+		// 查看话题
+		if err := ch.handleThread(currentUser, cmd.Target); err != nil {
+			return err
+		}
+
+	case message.CmdSend:
+		// 发送文件
+		if err := ch.handleSend(currentUser, reader, cmd); err != nil {
+			return err
+		}
+
+	case message.CmdGet:
+		// 下载文件
+		if err := ch.handleGet(currentUser, cmd.Target); err != nil {
+			return err
+		}
+
+	case message.CmdAway:
+		// 设置为离开状态
+		ch.setPresence(currentUser, user.PresenceAway, cmd.Content)
+
+	case message.CmdBusy:
+		// 设置为忙碌状态
+		ch.setPresence(currentUser, user.PresenceBusy, cmd.Content)
+
+	case message.CmdBack:
+		// 恢复在线状态
+		ch.setPresence(currentUser, user.PresenceOnline, "")
+
+	case message.CmdWhois:
+		// 查看用户详细信息
+		if err := ch.handleWhois(currentUser, cmd.Target); err != nil {
+			return err
+		}
+
+	case message.CmdIgnore:
+		// 屏蔽用户
+		name, err := ch.userManager.IgnoreUser(currentUser.ID, cmd.Target)
+		if err != nil {
+			return err
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("ignore.added", i18n.Params{"name": name}))
+
+	case message.CmdUnignore:
+		// 取消屏蔽用户
+		name, err := ch.userManager.UnignoreUser(currentUser.ID, cmd.Target)
+		if err != nil {
+			return err
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("ignore.removed", i18n.Params{"name": name}))
+
+	case message.CmdIgnores:
+		// 查看屏蔽列表
+		names := ch.userManager.GetIgnoredNames(currentUser.ID)
+		if len(names) == 0 {
+			ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("ignore.empty", nil))
+			return nil
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("ignore.list",
+			i18n.Params{"count": len(names), "names": strings.Join(names, ", ")}))
+
+	case message.CmdFilter:
+		// 查看或重新加载过滤规则（仅管理员）
+		if cmd.Content != "reload" {
+			ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("filter.status", i18n.Params{"count": ch.filter.Count()}))
+			return nil
+		}
+		count, err := ch.filter.Reload()
+		if err != nil {
+			ch.logger.Error("重新加载过滤规则失败: %v", err)
+			return i18n.Errorf("error.filter_reload", i18n.Params{"error": err.Error()})
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("filter.reloaded", i18n.Params{"count": count}))
+		ch.logger.Info("管理员 %s 重新加载了 %d 条过滤规则", currentUser.Name, count)
+
+	case message.CmdSearch:
+		// 搜索历史消息
+		if err := ch.handleSearch(currentUser, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdExport:
+		// 导出聊天记录
+		if err := ch.handleExport(currentUser, cmd.Options); err != nil {
+			return err
+		}
+
+	case message.CmdPoll:
+		// 发起或结束投票
+		if len(cmd.Options) == 2 && strings.EqualFold(cmd.Options[0], "close") {
+			id := strings.ToLower(strings.TrimPrefix(cmd.Options[1], "#"))
+			return ch.polls.Close(id, currentUser.ID, currentUser.IsOperator())
+		}
+		if err := ch.handlePoll(currentUser, cmd.Options); err != nil {
+			return err
+		}
+
+	case message.CmdVote:
+		// 投票
+		choices, err := ch.polls.Vote(cmd.Target, currentUser.Room, currentUser.ID, currentUser.Name, cmd.Content)
+		if err != nil {
+			return err
+		}
+		if len(choices) == 0 {
+			ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("poll.unvoted", i18n.Params{"id": cmd.Target}))
+			return nil
+		}
+		picks := make([]string, len(choices))
+		for i, choice := range choices {
+			picks[i] = fmt.Sprint(choice)
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("poll.voted", i18n.Params{
+			"id": cmd.Target, "choices": strings.Join(picks, ", "),
+		}))
+
+	case message.CmdRemind, message.CmdSchedule:
+		// 创建提醒或定时广播
+		if err := ch.handleSchedule(currentUser, cmd); err != nil {
+			return err
+		}
+
+	case message.CmdReminders:
+		// 查看或取消提醒和定时广播
+		if cmd.Content == "cancel" || cmd.Target != "" {
+			return ch.handleCancelJob(currentUser, cmd)
+		}
+		return ch.handleListJobs(currentUser)
+
+	case message.CmdJoin:
+		// 加入房间
+		if err := ch.handleJoin(currentUser, cmd.Target, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdLeave:
+		// 离开当前房间，回到大厅
+		if currentUser.Room == message.DefaultRoom {
+			return i18n.Errorf("error.room_leave_lobby", nil)
+		}
+		lobby, err := ch.rooms.Ensure(message.DefaultRoom)
+		if err != nil {
+			return err
+		}
+		if err := ch.moveToRoom(currentUser, lobby); err != nil {
+			return err
+		}
+
+	case message.CmdRooms:
+		// 查看房间列表
+		if err := ch.handleRooms(currentUser); err != nil {
+			return err
+		}
+
+	case message.CmdTopic:
+		// 查看或设置房间话题
+		if err := ch.handleTopic(currentUser, cmd.Content); err != nil {
+			return err
+		}
+
+	case message.CmdMode:
+		// 查看或修改房间模式
+		if err := ch.handleMode(currentUser, cmd); err != nil {
+			return err
+		}
+
+	case message.CmdInvite:
+		// 邀请用户加入当前房间
+		if err := ch.handleInvite(currentUser, cmd.Target); err != nil {
+			return err
+		}
+
+	case message.CmdRoomOp:
+		// 把用户设为当前房间的管理员
+		if err := ch.handleRoomOp(currentUser, cmd.Target); err != nil {
+			return err
+		}
+
+	case message.CmdMOTD:
+		// 查看或重新加载每日消息（重新加载仅管理员）
+		if cmd.Content != "reload" {
+			if !ch.sendMOTD(currentUser) {
+				ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("motd.none", nil))
+			}
+			return nil
+		}
+		if !currentUser.IsOperator() {
+			return i18n.Errorf("error.permission_denied", i18n.Params{"command": "\\motd reload"})
+		}
+		if err := ch.motd.Reload(); err != nil {
+			ch.logger.Error("重新加载每日消息失败: %v", err)
+			return i18n.Errorf("error.motd_reload", i18n.Params{"error": err.Error()})
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("motd.reloaded", nil))
+		ch.logger.Info("管理员 %s 重新加载了每日消息", currentUser.Name)
+
 	case message.CmdQuit:
 		// 退出聊天室
-		ch.userManager.SendToUser(currentUser.ID, "正在退出聊天室...\n")
-		close(currentUser.DoneChan)
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("quit", nil))
+		currentUser.Stop()
 		return nil
 
 	default:
-		return fmt.Errorf("未知命令类型")
+		return i18n.Errorf("error.unknown_cmd_type", nil)
 	}
 
 	return nil
@@ -206,7 +620,7 @@ func (ch *ConnectionHandler) writeToClient(currentUser *user.User, conn net.Conn
 			_, err := conn.Write([]byte(msg))
 			if err != nil {
 				ch.logger.Error("向用户 %s 发送消息失败: %v", currentUser.Name, err)
-				close(currentUser.DoneChan)
+				currentUser.Stop()
 				return
 			}
 
@@ -216,9 +630,28 @@ func (ch *ConnectionHandler) writeToClient(currentUser *user.User, conn net.Conn
 	}
 }
 
-// watchTimeout 监控用户超时
+// timeouts 获取用户超时时间、空闲时间和检查间隔
+func (ch *ConnectionHandler) timeouts() (timeout, idleAfter, interval time.Duration) {
+	timeout = time.Duration(ch.config.Timeout) * time.Second
+	idleAfter = time.Duration(ch.config.IdleTimeout) * time.Second
+
+	// 检查间隔取两者中较小值的一半，避免空闲状态延迟过久才被发现
+	interval = timeout
+	if idleAfter < interval {
+		interval = idleAfter
+	}
+	interval /= 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return timeout, idleAfter, interval
+}
+
+// watchTimeout 监控用户超时和空闲状态
 func (ch *ConnectionHandler) watchTimeout(currentUser *user.User, timeoutChan chan bool) {
-	ticker := time.NewTicker(time.Duration(ch.config.Timeout) * time.Second)
+	timeout, idleAfter, interval := ch.timeouts()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -227,12 +660,15 @@ func (ch *ConnectionHandler) watchTimeout(currentUser *user.User, timeoutChan ch
 			// 检查用户是否超时
 			if user, exists := ch.userManager.GetUser(currentUser.ID); exists {
 				timeSinceLastSeen := time.Since(user.LastSeen)
-				if timeSinceLastSeen > time.Duration(ch.config.Timeout)*time.Second {
+				if timeSinceLastSeen > timeout {
 					ch.logger.Info("用户 %s 超时，自动断开连接", currentUser.Name)
 					timeoutChan <- true
-					close(currentUser.DoneChan)
+					currentUser.Stop()
 					return
 				}
+				if ch.userManager.MarkIdle(currentUser.ID, idleAfter) {
+					ch.broadcastPresence(currentUser.ID)
+				}
 			} else {
 				// 用户已被移除
 				return
@@ -244,36 +680,31 @@ func (ch *ConnectionHandler) watchTimeout(currentUser *user.User, timeoutChan ch
 	}
 }
 
-// handleWhisper 处理私聊消息
-func (ch *ConnectionHandler) handleWhisper(fromUser *user.User, targetName, content string) error {
-	// 查找目标用户
-	var targetUser *user.User
-	users := ch.userManager.GetAllUsers()
-	for _, user := range users {
-		if user.Name == targetName {
-			targetUser = user
-			break
-		}
+// loggableInput 返回可以写入调试日志的输入，私聊和管理员认证等命令只记录命令名和长度
+func (ch *ConnectionHandler) loggableInput(input string) string {
+	cmd, err := ch.commandParser.ParseCommand(input)
+	if err != nil || cmd.Spec == nil {
+		return input
 	}
-
-	if targetUser == nil {
-		return fmt.Errorf("用户 %s 不在线", targetName)
+	switch cmd.Type {
+	case message.CmdWhisper, message.CmdDM, message.CmdRespond, message.CmdSealed, message.CmdOper:
+		return fmt.Sprintf("\\%s (已隐藏 %d 字节)", cmd.Spec.Name, len(input))
 	}
+	return input
+}
 
-	// 创建私聊消息
-	whisperMsg := message.NewPrivateMessage(fromUser.Name, targetUser.Name, content)
-
-	// 发送给目标用户
-	if err := ch.userManager.SendToUser(targetUser.ID, whisperMsg.FormatMessage()); err != nil {
-		return fmt.Errorf("发送私聊消息失败: %v", err)
+// sendMOTD 向用户发送替换变量后的每日消息，没有配置每日消息时返回false
+func (ch *ConnectionHandler) sendMOTD(currentUser *user.User) bool {
+	text := ch.motd.Render(motd.Vars{
+		Name:   currentUser.Name,
+		Online: ch.userManager.OnlineCount(),
+		Uptime: time.Since(ch.started),
+	})
+	if text == "" {
+		return false
 	}
-
-	// 发送确认消息给发送者
-	confirmMsg := fmt.Sprintf("[私聊] -> %s: %s\n", targetUser.Name, content)
-	ch.userManager.SendToUser(fromUser.ID, confirmMsg)
-
-	ch.logger.Info("用户 %s 向 %s 发送私聊消息", fromUser.Name, targetUser.Name)
-	return nil
+	ch.userManager.SendToUser(currentUser.ID, text)
+	return true
 }
 
 // CleanupUser 清理用户资源
@@ -282,7 +713,28 @@ func (ch *ConnectionHandler) CleanupUser(currentUser *user.User) {
 	if removedUser, exists := ch.userManager.RemoveUser(currentUser.ID); exists {
 		// 广播用户离开消息
 		leaveMsg := message.FormatUserLeaveMessage(removedUser.Name)
-		ch.userManager.BroadcastToAll(leaveMsg)
+		ch.userManager.BroadcastLocalized("", leaveMsg)
+		ch.notifyWebhook(webhook.Event{Type: webhook.TypeLeave, Room: removedUser.Room, User: removedUser.Name})
 		ch.logger.Info("用户 %s 已离开聊天室", removedUser.Name)
 	}
+	if _, err := ch.scheduler.Forget(currentUser.ID); err != nil {
+		ch.logger.Error("取消用户 %s 的提醒失败: %v", currentUser.Name, err)
+	}
+	if err := ch.rooms.Forget(currentUser.ID); err != nil {
+		ch.logger.Error("撤销用户 %s 的房间权限失败: %v", currentUser.Name, err)
+	}
+
+	// 退出所有私聊群组并通知剩下的成员
+	conversations, err := ch.dms.Forget(currentUser.ID)
+	if err != nil {
+		ch.logger.Error("用户 %s 退出私聊群组失败: %v", currentUser.Name, err)
+	}
+	for _, conversation := range conversations {
+		ch.notifyDM(conversation, i18n.NewText("dm.left", i18n.Params{"handle": conversation.Handle, "name": currentUser.Name}))
+	}
+}
+
+// formatError 按语言格式化发送给客户端的错误信息
+func (ch *ConnectionHandler) formatError(lang string, err error) string {
+	return i18n.T(lang, "error.prefix", i18n.Params{"error": i18n.Localize(lang, err)}) + "\n"
 }
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"strings"
+	"time"
+
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/scheduler"
+	"chatroom/user"
+	"chatroom/utils"
+)
+
+// handleSchedule 创建个人提醒或定时广播，管理员可以用定时表达式创建重复广播
+func (ch *ConnectionHandler) handleSchedule(currentUser *user.User, cmd message.Command) error {
+	job := scheduler.Job{Kind: scheduler.KindReminder, Owner: currentUser.Name, OwnerID: currentUser.ID, Text: cmd.Content}
+	if cmd.Type == message.CmdSchedule {
+		job.Kind = scheduler.KindBroadcast
+		job.Room = currentUser.Room
+	}
+
+	now := time.Now()
+	if cmd.Type == message.CmdSchedule && scheduler.IsCron(cmd.Target) {
+		if !currentUser.IsOperator() {
+			return i18n.Errorf("error.cron_operator", nil)
+		}
+		cron, err := scheduler.ParseCron(cmd.Target)
+		if err != nil {
+			return i18n.Errorf("error.cron_invalid", i18n.Params{"cron": cmd.Target, "error": err.Error()})
+		}
+		job.Kind = scheduler.KindRecurring
+		job.Cron = cron.String()
+		if job.At = cron.Next(now); job.At.IsZero() {
+			return i18n.Errorf("error.cron_never", i18n.Params{"cron": cmd.Target})
+		}
+	} else {
+		at, ok := scheduler.ParseWhen(cmd.Target, now)
+		if !ok {
+			return i18n.Errorf("error.schedule_time", i18n.Params{"value": cmd.Target})
+		}
+		if !at.After(now) {
+			return i18n.Errorf("error.schedule_past", i18n.Params{"time": formatJobTime(at)})
+		}
+		if at.Sub(now) > maxScheduleAhead {
+			return i18n.Errorf("error.schedule_too_far", nil)
+		}
+		job.At = at
+	}
+
+	jobs, err := ch.scheduler.Jobs()
+	if err != nil {
+		ch.logger.Error("加载定时任务失败: %v", err)
+		return i18n.Errorf("error.schedule_failed", nil)
+	}
+	count := 0
+	for _, existing := range jobs {
+		if existing.OwnedBy(currentUser.ID) {
+			count++
+		}
+	}
+	if count >= maxJobsPerUser {
+		return i18n.Errorf("error.schedule_limit", i18n.Params{"max": maxJobsPerUser})
+	}
+
+	job, err = ch.scheduler.Add(job)
+	if err != nil {
+		ch.logger.Error("保存定时任务失败: %v", err)
+		return i18n.Errorf("error.schedule_failed", nil)
+	}
+
+	params := i18n.Params{"id": job.ID, "time": formatJobTime(job.At), "text": job.Text, "cron": job.Cron, "room": job.Room}
+	ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("job.added."+string(job.Kind), params))
+	ch.logger.Info("用户 %s 创建了定时任务 %s (%s)，执行时间 %s", currentUser.Name, job.ID, job.Kind, formatJobTime(job.At))
+	return nil
+}
+
+// handleListJobs 列出用户自己的提醒和定时广播，管理员可以看到所有任务
+func (ch *ConnectionHandler) handleListJobs(currentUser *user.User) error {
+	jobs, err := ch.scheduler.Jobs()
+	if err != nil {
+		ch.logger.Error("加载定时任务失败: %v", err)
+		return i18n.Errorf("error.schedule_failed", nil)
+	}
+
+	lang := currentUser.Lang
+	var lines []string
+	for _, job := range jobs {
+		if !job.OwnedBy(currentUser.ID) && !currentUser.IsOperator() {
+			continue
+		}
+		key := "job.item"
+		if job.Kind == scheduler.KindRecurring {
+			key = "job.item_cron"
+		}
+		lines = append(lines, i18n.T(lang, key, i18n.Params{
+			"id":    job.ID,
+			"kind":  i18n.T(lang, "job.kind."+string(job.Kind), nil),
+			"time":  formatJobTime(job.At),
+			"cron":  job.Cron,
+			"owner": job.Owner,
+			"text":  utils.TruncateString(job.Text, 60),
+		}))
+	}
+
+	if len(lines) == 0 {
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("job.empty", nil))
+		return nil
+	}
+	header := i18n.T(lang, "job.header", i18n.Params{"count": len(lines)})
+	ch.userManager.SendToUser(currentUser.ID, header+"\n"+strings.Join(lines, "\n"))
+	return nil
+}
+
+// handleCancelJob 取消自己的提醒或定时广播，管理员可以取消任何任务
+func (ch *ConnectionHandler) handleCancelJob(currentUser *user.User, cmd message.Command) error {
+	if cmd.Target == "" {
+		return i18n.Errorf("error.missing_arg", i18n.Params{"arg": "<id>", "usage": cmd.Spec.Usage()})
+	}
+
+	missing := i18n.Errorf("error.job_not_found", i18n.Params{"id": cmd.Target})
+	job, exists, err := ch.scheduler.Find(cmd.Target)
+	if err != nil {
+		ch.logger.Error("加载定时任务失败: %v", err)
+		return i18n.Errorf("error.schedule_failed", nil)
+	}
+	if !exists {
+		return missing
+	}
+	if !job.OwnedBy(currentUser.ID) && !currentUser.IsOperator() {
+		return missing
+	}
+
+	removed, err := ch.scheduler.Cancel(job.ID)
+	if err != nil {
+		ch.logger.Error("取消定时任务失败: %v", err)
+		return i18n.Errorf("error.schedule_failed", nil)
+	}
+	if !removed {
+		// 在查找和取消之间已经执行
+		return missing
+	}
+
+	ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("job.cancelled", i18n.Params{"id": job.ID}))
+	ch.logger.Info("用户 %s 取消了定时任务 %s", currentUser.Name, job.ID)
+	return nil
+}
+
+// RunJob 执行到期的定时任务，由调度器回调
+//
+// 提醒发送给创建者的连接，创建者已离开时返回 scheduler.ErrUndelivered；
+// 定时广播以创建者的名义作为广播消息发送到创建时所在的房间并保存到历史记录。
+func (ch *ConnectionHandler) RunJob(job scheduler.Job) error {
+	if job.Kind == scheduler.KindReminder {
+		text := i18n.NewText("job.remind", i18n.Params{"text": job.Text, "time": formatJobTime(job.At)})
+		if job.OwnerID == "" || ch.userManager.SendLocalized(job.OwnerID, text) != nil {
+			return scheduler.ErrUndelivered
+		}
+		return nil
+	}
+
+	// 广播只发送到创建时所在的房间，早期保存的任务没有记录房间，发送到大厅
+	room := job.Room
+	if room == "" {
+		room = message.DefaultRoom
+	}
+	broadcastMsg := message.NewMessage(message.TypeBroadcast, job.Owner, job.Text)
+	broadcastMsg.Room = room
+	if err := ch.history.Append(room, broadcastMsg); err != nil {
+		ch.logger.Error("保存历史消息失败: %v", err)
+	}
+	ch.userManager.BroadcastMessage(broadcastMsg)
+	return nil
+}
+
+// formatJobTime 格式化定时任务的执行时间
+func formatJobTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"strings"
+
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/user"
+	"chatroom/webhook"
+)
+
+// handleEdit 编辑自己的消息
+func (ch *ConnectionHandler) handleEdit(currentUser *user.User, id, content string) error {
+	msg, err := ch.modifyMessage(currentUser.Room, id, func(msg *message.Message) error {
+		if msg.FromID != currentUser.ID {
+			return i18n.Errorf("error.not_message_owner", nil)
+		}
+		msg.Content = content
+		msg.Edited = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ch.userManager.BroadcastEvent(message.NewEditEvent(msg, currentUser.Name))
+	ch.logger.Info("用户 %s 编辑了消息 %s", currentUser.Name, msg.ID)
+	return nil
+}
+
+// handleDelete 删除消息，管理员可以删除任何人的消息
+func (ch *ConnectionHandler) handleDelete(currentUser *user.User, id string) error {
+	var content string
+	msg, err := ch.modifyMessage(currentUser.Room, id, func(msg *message.Message) error {
+		if msg.FromID != currentUser.ID && !currentUser.IsOperator() {
+			return i18n.Errorf("error.not_message_owner", nil)
+		}
+		content = msg.Content
+		msg.Content = ""
+		msg.Deleted = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ch.userManager.BroadcastEvent(message.NewDeleteEvent(msg, currentUser.Name))
+	ch.logger.Info("用户 %s 删除了消息 %s", currentUser.Name, msg.ID)
+	if msg.FromID != currentUser.ID {
+		ch.notifyWebhook(webhook.Event{
+			Type:      webhook.TypeModeration,
+			Room:      msg.Room,
+			User:      msg.From,
+			MessageID: msg.ID,
+			Text:      content,
+			Action:    webhook.ActionDelete,
+			By:        currentUser.Name,
+		})
+	}
+	return nil
+}
+
+// handleReact 对消息添加表情回应
+func (ch *ConnectionHandler) handleReact(currentUser *user.User, id, input string) error {
+	emoji, ok := message.ResolveEmoji(input)
+	if !ok {
+		return i18n.Errorf("error.invalid_emoji", i18n.Params{"emoji": input})
+	}
+
+	msg, err := ch.modifyMessage(currentUser.Room, id, func(msg *message.Message) error {
+		if !msg.AddReaction(emoji, currentUser.ID, currentUser.Name) {
+			return i18n.Errorf("error.already_reacted", i18n.Params{"emoji": emoji})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ch.userManager.BroadcastEvent(message.NewReactionEvent(msg, currentUser.Name, emoji, true))
+	return nil
+}
+
+// handleUnreact 取消对消息的表情回应，未指定表情时取消所有回应
+func (ch *ConnectionHandler) handleUnreact(currentUser *user.User, id, input string) error {
+	emoji := ""
+	if input != "" {
+		resolved, ok := message.ResolveEmoji(input)
+		if !ok {
+			return i18n.Errorf("error.invalid_emoji", i18n.Params{"emoji": input})
+		}
+		emoji = resolved
+	}
+
+	var removed []string
+	msg, err := ch.modifyMessage(currentUser.Room, id, func(msg *message.Message) error {
+		removed = msg.RemoveReaction(emoji, currentUser.ID)
+		if len(removed) == 0 {
+			return i18n.Errorf("error.not_reacted", nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range removed {
+		ch.userManager.BroadcastEvent(message.NewReactionEvent(msg, currentUser.Name, e, false))
+	}
+	return nil
+}
+
+// handleReactions 列出消息的表情回应及回应者
+func (ch *ConnectionHandler) handleReactions(currentUser *user.User, id string) error {
+	msg, err := ch.findMessage(currentUser.Room, id)
+	if err != nil {
+		return err
+	}
+	if len(msg.Reactions) == 0 {
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("reactions.none", i18n.Params{"id": msg.ID}))
+		return nil
+	}
+
+	lang := currentUser.Lang
+	online := ch.userManager.OnlineNames()
+	result := i18n.T(lang, "reactions.header", i18n.Params{"id": msg.ID}) + "\n"
+	for _, emoji := range msg.ReactionEmojis() {
+		names := msg.ReactorNames(emoji, online)
+		result += i18n.T(lang, "reactions.item", i18n.Params{
+			"emoji": emoji, "count": len(names), "names": strings.Join(names, ", "),
+		}) + "\n"
+	}
+	ch.userManager.SendToUser(currentUser.ID, result)
+	return nil
+}
+
+// handleReply 回复消息
+func (ch *ConnectionHandler) handleReply(currentUser *user.User, id, content string) error {
+	parent, err := ch.findMessage(currentUser.Room, id)
+	if err != nil {
+		return err
+	}
+
+	reply := message.NewReply(parent, currentUser.Name, content)
+	reply.FromID = currentUser.ID
+	reply.Room = currentUser.Room
+	if err := ch.history.Append(currentUser.Room, reply); err != nil {
+		ch.logger.Error("保存历史消息失败: %v", err)
+	}
+	ch.userManager.BroadcastMessage(reply)
+	ch.notifyChat(reply)
+	return nil
+}
+
+// handleThread 显示消息及其所有回复（包括回复的回复）
+func (ch *ConnectionHandler) handleThread(currentUser *user.User, id string) error {
+	messages, err := ch.history.Recent(currentUser.Room, 0)
+	if err != nil {
+		return err
+	}
+
+	var root *message.Message
+	for _, msg := range messages {
+		if msg.ID == id {
+			root = msg
+			break
+		}
+	}
+	if root == nil {
+		return i18n.Errorf("error.message_not_found", i18n.Params{"id": id})
+	}
+
+	// 历史记录按时间顺序排列，回复总是出现在父消息之后
+	inThread := map[string]bool{root.ID: true}
+	var replies []*message.Message
+	for _, msg := range messages {
+		if msg.ReplyTo != "" && inThread[msg.ReplyTo] {
+			inThread[msg.ID] = true
+			replies = append(replies, msg)
+		}
+	}
+
+	lang := currentUser.Lang
+	result := i18n.T(lang, "thread.header", i18n.Params{"id": root.ID, "count": len(replies)}) + "\n"
+	result += ch.formatHistoryMessage(lang, root)
+	for _, reply := range replies {
+		result += "  " + ch.formatHistoryMessage(lang, reply)
+	}
+	ch.userManager.SendToUser(currentUser.ID, result)
+	return nil
+}
+
+// formatHistoryMessage 格式化一条历史消息，总是显示消息ID
+func (ch *ConnectionHandler) formatHistoryMessage(lang string, msg *message.Message) string {
+	if msg.Deleted {
+		return i18n.T(lang, "history.deleted", i18n.Params{"id": msg.ID, "from": msg.From}) + "\n"
+	}
+	return msg.FormatMessage(lang, true)
+}
+
+// findMessage 在房间的历史记录中查找未删除的消息
+func (ch *ConnectionHandler) findMessage(room, id string) (*message.Message, error) {
+	msg, exists, err := ch.history.Find(room, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, i18n.Errorf("error.message_not_found", i18n.Params{"id": id})
+	}
+	if msg.Deleted {
+		return nil, i18n.Errorf("error.message_deleted", i18n.Params{"id": id})
+	}
+	return msg, nil
+}
+
+// modifyMessage 原地修改房间历史记录中未删除的消息
+//
+// 返回的消息带有房间名，广播修改事件时只发送给该房间的用户（早期保存的消息没有记录房间）。
+func (ch *ConnectionHandler) modifyMessage(room, id string, fn func(msg *message.Message) error) (*message.Message, error) {
+	msg, err := ch.history.Modify(room, id, func(msg *message.Message) error {
+		if msg.Deleted {
+			return i18n.Errorf("error.message_deleted", i18n.Params{"id": id})
+		}
+		return fn(msg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, i18n.Errorf("error.message_not_found", i18n.Params{"id": id})
+	}
+	msg.Room = room
+	return msg, nil
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"strings"
+
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/poll"
+	"chatroom/user"
+	"chatroom/utils"
+)
+
+// handlePoll 发起投票并通知所有用户
+func (ch *ConnectionHandler) handlePoll(currentUser *user.User, args []string) error {
+	// 问题和选项会广播给所有人，同样经过内容过滤
+	for i, arg := range args {
+		filtered, err := ch.filterContent(currentUser, arg)
+		if err != nil {
+			return err
+		}
+		args[i] = filtered
+	}
+
+	p, err := ch.polls.Create(currentUser.ID, currentUser.Name, currentUser.Room, args)
+	if err != nil {
+		return err
+	}
+
+	ch.userManager.BroadcastRoomLocalized(p.Room, "", i18n.NewText("poll.created", i18n.Params{
+		"id": p.ID, "creator": p.Creator, "question": p.Question, "options": p.Render(),
+	}))
+	mode := "single"
+	if p.Multi {
+		mode = "multi"
+	}
+	if p.Anonymous {
+		mode += "_anon"
+	}
+	ch.userManager.BroadcastRoomLocalized(p.Room, "", i18n.NewText("poll.hint."+mode, i18n.Params{"id": p.ID}))
+	if !p.Deadline.IsZero() {
+		ch.userManager.BroadcastRoomLocalized(p.Room, "", i18n.NewText("poll.deadline", i18n.Params{
+			"id": p.ID, "time": p.Deadline.Local().Format("2006-01-02 15:04:05"),
+		}))
+	}
+
+	ch.logger.Info("用户 %s 发起投票 %s: %s", currentUser.Name, p.ID, utils.TruncateString(p.Question, 50))
+	return nil
+}
+
+// broadcastTally 向发起投票的房间广播当前结果，由投票管理器按间隔合并后回调
+func (ch *ConnectionHandler) broadcastTally(p poll.Poll) {
+	ch.userManager.BroadcastRoomLocalized(p.Room, "", i18n.NewText("poll.tally", i18n.Params{
+		"id": p.ID, "question": p.Question, "count": p.Tally().Voters, "options": p.Render(),
+	}))
+}
+
+// postPollResult 以系统消息公布投票的最终结果并保存到历史记录
+func (ch *ConnectionHandler) postPollResult(p poll.Poll) {
+	lang := ch.config.Language
+	params := i18n.Params{
+		"id": p.ID, "question": p.Question, "count": p.Tally().Voters, "options": p.Render(),
+	}
+	key := "poll.result_none"
+	if winners := p.Winners(); len(winners) > 0 {
+		key = "poll.result"
+		params["winner"] = strings.Join(winners, " / ")
+	}
+
+	resultMsg := message.NewSystemMessage(i18n.T(lang, key, params))
+	resultMsg.Room = p.Room
+	if err := ch.history.Append(p.Room, resultMsg); err != nil {
+		ch.logger.Error("保存历史消息失败: %v", err)
+	}
+	ch.userManager.BroadcastMessage(resultMsg)
+	ch.logger.Info("投票 %s 已结束，%d 人参与", p.ID, p.Tally().Voters)
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"strings"
+	"time"
+
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/user"
+)
+
+// setPresence 手动设置在线状态并广播
+func (ch *ConnectionHandler) setPresence(currentUser *user.User, presence user.Presence, statusMessage string) {
+	ch.userManager.SetPresence(currentUser.ID, presence, statusMessage)
+	ch.broadcastPresence(currentUser.ID)
+	ch.logger.Info("用户 %s 的状态变为 %s", currentUser.Name, presence)
+}
+
+// broadcastPresence 向所有用户广播某个用户当前的在线状态
+func (ch *ConnectionHandler) broadcastPresence(userID string) {
+	snapshot, exists := ch.userManager.GetUserSnapshot(userID)
+	if !exists {
+		return
+	}
+	ch.userManager.BroadcastEvent(message.NewPresenceEvent(
+		snapshot.Name, string(snapshot.CurrentPresence()), snapshot.StatusMessage))
+}
+
+// handleWhois 显示用户的详细信息
+func (ch *ConnectionHandler) handleWhois(currentUser *user.User, targetName string) error {
+	target, exists := ch.userManager.FindUserByName(targetName)
+	if !exists {
+		return i18n.Errorf("error.user_offline", i18n.Params{"name": targetName})
+	}
+	snapshot, exists := ch.userManager.GetUserSnapshot(target.ID)
+	if !exists {
+		return i18n.Errorf("error.user_offline", i18n.Params{"name": targetName})
+	}
+
+	// 用户在非公开房间时，只有同一房间的人能看到房间名
+	roomName := snapshot.Room
+	if snapshot.Room != currentUser.Room {
+		if r, exists, err := ch.rooms.Get(snapshot.Room); err != nil || !exists || !r.Open() {
+			roomName = i18n.T(currentUser.Lang, "room.hidden", nil)
+		}
+	}
+
+	lang := currentUser.Lang
+	lines := []string{
+		i18n.T(lang, "whois.header", i18n.Params{"name": snapshot.Name}),
+		i18n.T(lang, "whois.presence", i18n.Params{
+			"presence": i18n.T(lang, "presence."+string(snapshot.CurrentPresence()), nil),
+		}),
+	}
+	if snapshot.StatusMessage != "" {
+		lines = append(lines, i18n.T(lang, "whois.status", i18n.Params{"message": snapshot.StatusMessage}))
+	}
+	lines = append(lines,
+		i18n.T(lang, "whois.joined", i18n.Params{
+			"time":     snapshot.JoinTime.Format("2006-01-02 15:04:05"),
+			"duration": time.Since(snapshot.JoinTime).Round(time.Second),
+		}),
+		i18n.T(lang, "whois.idle", i18n.Params{"duration": time.Since(snapshot.LastSeen).Round(time.Second)}),
+		i18n.T(lang, "whois.role", i18n.Params{"role": i18n.T(lang, "role."+string(snapshot.Role), nil)}),
+		i18n.T(lang, "whois.rooms", i18n.Params{"rooms": roomName}),
+		i18n.T(lang, "whois.client", i18n.Params{"client": snapshot.ClientType}),
+	)
+
+	ch.userManager.SendToUser(currentUser.ID, strings.Join(lines, "\n")+"\n")
+	return nil
+}
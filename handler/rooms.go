@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"strings"
+
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/room"
+	"chatroom/user"
+	"chatroom/utils"
+	"chatroom/webhook"
+)
+
+// canReadRoom 判断用户能否读取房间的内容（历史记录、附件）：公开房间所有人可读，其他房间只有其中的用户可读
+func (ch *ConnectionHandler) canReadRoom(currentUser *user.User, name string) bool {
+	r, exists, err := ch.rooms.Get(name)
+	if err != nil || !exists {
+		return false
+	}
+	return r.Open() || name == currentUser.Room
+}
+
+// sendTopic 向用户显示房间话题及设置者，没有话题时不发送
+func (ch *ConnectionHandler) sendTopic(userID string, r room.Room) {
+	if r.Topic == "" {
+		return
+	}
+	ch.userManager.SendLocalized(userID, i18n.NewText("room.topic", i18n.Params{
+		"room": r.Name, "topic": r.Topic, "by": r.TopicBy, "time": r.TopicAt.Local().Format("2006-01-02 15:04"),
+	}))
+}
+
+// handleJoin 加入房间，房间不存在时创建并成为房间管理员
+//
+// 仅限邀请和密码保护的房间先检查邀请和密码，服务器管理员可以加入任何房间。
+func (ch *ConnectionHandler) handleJoin(currentUser *user.User, name, key string) error {
+	name, err := room.Normalize(name)
+	if err != nil {
+		return err
+	}
+	if name == currentUser.Room {
+		return i18n.Errorf("error.room_already_in", i18n.Params{"room": name})
+	}
+
+	r, exists, err := ch.rooms.Get(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if r, err = ch.rooms.Create(name, currentUser.ID, currentUser.Name); err != nil {
+			return err
+		}
+		ch.logger.Info("用户 %s 创建了房间 %s", currentUser.Name, name)
+	} else if !currentUser.IsOperator() {
+		if err := r.CheckJoin(currentUser.ID, key); err != nil {
+			ch.logger.Warn("用户 %s 加入房间 %s 被拒绝 (%s)", currentUser.Name, name, r.CurrentMode())
+			return err
+		}
+	}
+	return ch.moveToRoom(currentUser, r)
+}
+
+// currentRoomOperator 获取当前房间的信息，用户不是房间管理员（或服务器管理员）时返回错误
+func (ch *ConnectionHandler) currentRoomOperator(currentUser *user.User, command string) (room.Room, error) {
+	r, err := ch.rooms.Ensure(currentUser.Room)
+	if err != nil {
+		return room.Room{}, err
+	}
+	if !r.IsOperator(currentUser.ID) && !currentUser.IsOperator() {
+		return room.Room{}, i18n.Errorf("error.room_not_operator", i18n.Params{"command": "\\" + command, "room": r.Name})
+	}
+	return r, nil
+}
+
+// handleMode 查看当前房间的模式，房间管理员可以修改模式
+func (ch *ConnectionHandler) handleMode(currentUser *user.User, cmd message.Command) error {
+	if cmd.Content == "" {
+		r, err := ch.rooms.Ensure(currentUser.Room)
+		if err != nil {
+			return err
+		}
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("room.mode."+string(r.CurrentMode()), i18n.Params{"room": r.Name}))
+		return nil
+	}
+
+	if currentUser.Room == message.DefaultRoom {
+		return i18n.Errorf("error.room_lobby_mode", nil)
+	}
+	if _, err := ch.currentRoomOperator(currentUser, "mode"); err != nil {
+		return err
+	}
+	mode, _ := room.ParseMode(cmd.Content)
+	key := ""
+	if len(cmd.Options) > 0 {
+		key = cmd.Options[0]
+	}
+	r, err := ch.rooms.SetMode(currentUser.Room, mode, key)
+	if err != nil {
+		return err
+	}
+
+	ch.userManager.BroadcastRoomLocalized(r.Name, "", i18n.NewText("room.mode_changed."+string(mode),
+		i18n.Params{"name": currentUser.Name, "room": r.Name}))
+	ch.logger.Info("用户 %s 将房间 %s 设为 %s", currentUser.Name, r.Name, mode)
+	return nil
+}
+
+// handleInvite 邀请用户加入当前房间，被邀请的用户不受仅限邀请和密码的限制
+func (ch *ConnectionHandler) handleInvite(currentUser *user.User, name string) error {
+	r, err := ch.currentRoomOperator(currentUser, "invite")
+	if err != nil {
+		return err
+	}
+	if r.Name == message.DefaultRoom {
+		return i18n.Errorf("error.room_lobby_invite", nil)
+	}
+	// 邀请绑定到被邀请用户当前的连接，只能邀请在线用户
+	userID, actual, online := ch.userManager.LookupOnline(name)
+	if !online {
+		return i18n.Errorf("error.user_offline", i18n.Params{"name": name})
+	}
+	name = actual
+	if r, err = ch.rooms.Invite(r.Name, userID, name); err != nil {
+		return err
+	}
+
+	params := i18n.Params{"name": name, "by": currentUser.Name, "room": r.Name}
+	if !ch.userManager.IgnoredBy(userID, currentUser.Name) {
+		ch.userManager.SendLocalized(userID, i18n.NewText("room.invite_received", params))
+	}
+	ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("room.invite_sent", params))
+	ch.logger.Info("用户 %s 邀请 %s 加入房间 %s", currentUser.Name, name, r.Name)
+	return nil
+}
+
+// handleRoomOp 把用户设为当前房间的管理员，并通知房间内的所有人
+func (ch *ConnectionHandler) handleRoomOp(currentUser *user.User, name string) error {
+	r, err := ch.currentRoomOperator(currentUser, "roomop")
+	if err != nil {
+		return err
+	}
+	if r.Name == message.DefaultRoom {
+		return i18n.Errorf("error.room_lobby_op", nil)
+	}
+	userID, actual, online := ch.userManager.LookupOnline(name)
+	if !online {
+		return i18n.Errorf("error.user_offline", i18n.Params{"name": name})
+	}
+	name = actual
+	if r.IsOperator(userID) {
+		return i18n.Errorf("error.room_already_op", i18n.Params{"name": name, "room": r.Name})
+	}
+	if r, err = ch.rooms.AddOperator(r.Name, userID, name); err != nil {
+		return err
+	}
+
+	ch.userManager.BroadcastRoomLocalized(r.Name, "", i18n.NewText("room.op_added",
+		i18n.Params{"name": name, "by": currentUser.Name, "room": r.Name}))
+	ch.logger.Info("用户 %s 把 %s 设为房间 %s 的管理员", currentUser.Name, name, r.Name)
+	return nil
+}
+
+// moveToRoom 把用户移到另一个房间，通知两个房间的成员并向用户显示新房间的话题
+func (ch *ConnectionHandler) moveToRoom(currentUser *user.User, r room.Room) error {
+	oldRoom := currentUser.Room
+	if err := ch.userManager.SetUserRoom(currentUser.ID, r.Name); err != nil {
+		return err
+	}
+
+	ch.userManager.BroadcastRoomLocalized(oldRoom, currentUser.ID, i18n.NewText("room.left",
+		i18n.Params{"name": currentUser.Name, "room": oldRoom}))
+	ch.userManager.BroadcastRoomLocalized(r.Name, currentUser.ID, i18n.NewText("room.joined",
+		i18n.Params{"name": currentUser.Name, "room": r.Name}))
+	ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("room.entered", i18n.Params{"room": r.Name}))
+	ch.sendTopic(currentUser.ID, r)
+	ch.notifyWebhook(webhook.Event{Type: webhook.TypeLeave, Room: oldRoom, User: currentUser.Name})
+	ch.notifyWebhook(webhook.Event{Type: webhook.TypeJoin, Room: r.Name, User: currentUser.Name})
+
+	ch.logger.Info("用户 %s 从房间 %s 进入房间 %s", currentUser.Name, oldRoom, r.Name)
+	return nil
+}
+
+// handleRooms 列出房间的在线人数、模式和话题，标出用户当前所在的房间
+//
+// 私密房间只对房间内的用户和服务器管理员列出。
+func (ch *ConnectionHandler) handleRooms(currentUser *user.User) error {
+	list, err := ch.rooms.List()
+	if err != nil {
+		ch.logger.Error("加载房间列表失败: %v", err)
+		return err
+	}
+
+	lang := currentUser.Lang
+	var lines []string
+	for _, info := range list {
+		if !info.Listed() && info.Name != currentUser.Room && !currentUser.IsOperator() {
+			continue
+		}
+		marker := " "
+		if info.Name == currentUser.Room {
+			marker = "*"
+		}
+		tag := ""
+		if mode := info.CurrentMode(); mode != room.ModePublic {
+			tag = " " + i18n.T(lang, "room.tag."+string(mode), nil)
+		}
+		key := "room.item"
+		if info.Topic != "" {
+			key = "room.item_topic"
+		}
+		lines = append(lines, i18n.T(lang, key, i18n.Params{
+			"current": marker, "room": info.Name, "count": info.Members, "mode": tag, "topic": info.Topic,
+		}))
+	}
+	header := i18n.T(lang, "room.list", i18n.Params{"count": len(lines)})
+	ch.userManager.SendToUser(currentUser.ID, header+"\n"+strings.Join(lines, "\n")+"\n")
+	return nil
+}
+
+// handleTopic 查看或设置当前房间的话题，"-" 表示清除话题，修改后通知房间内所有成员
+func (ch *ConnectionHandler) handleTopic(currentUser *user.User, text string) error {
+	r, err := ch.rooms.Ensure(currentUser.Room)
+	if err != nil {
+		return err
+	}
+	if text == "" {
+		if r.Topic == "" {
+			ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("room.no_topic", i18n.Params{"room": r.Name}))
+			return nil
+		}
+		ch.sendTopic(currentUser.ID, r)
+		return nil
+	}
+
+	topic := ""
+	if text != "-" {
+		// 话题会显示给所有加入房间的人，同样经过内容过滤
+		if topic, err = ch.filterContent(currentUser, text); err != nil {
+			return err
+		}
+	}
+	if r, err = ch.rooms.SetTopic(r.Name, topic, currentUser.Name); err != nil {
+		return err
+	}
+
+	key := "room.topic_changed"
+	if topic == "" {
+		key = "room.topic_cleared"
+	}
+	ch.userManager.BroadcastRoomLocalized(r.Name, "", i18n.NewText(key, i18n.Params{
+		"name": currentUser.Name, "room": r.Name, "topic": topic,
+	}))
+	ch.logger.Info("用户 %s 将房间 %s 的话题设置为: %s", currentUser.Name, r.Name, utils.TruncateString(topic, 50))
+	return nil
+}
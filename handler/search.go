@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"strings"
+
+	"chatroom/i18n"
+	"chatroom/search"
+	"chatroom/user"
+	"chatroom/utils"
+)
+
+// handleSearch 搜索历史消息，按时间从新到旧列出结果
+func (ch *ConnectionHandler) handleSearch(currentUser *user.User, input string) error {
+	query, err := search.ParseQuery(input)
+	if err != nil {
+		return err
+	}
+	if query.IsEmpty() {
+		return i18n.Errorf("error.search_empty", nil)
+	}
+
+	// 非公开房间的消息只对房间内的用户可见
+	list, err := ch.rooms.List()
+	if err != nil {
+		return err
+	}
+	query.Hidden = make(map[string]bool)
+	for _, info := range list {
+		if !info.Open() && info.Name != currentUser.Room {
+			query.Hidden[info.Name] = true
+		}
+	}
+
+	hits, total := ch.index.Search(query, maxSearchResults)
+	lang := currentUser.Lang
+	if total == 0 {
+		ch.userManager.SendLocalized(currentUser.ID, i18n.NewText("search.none", nil))
+		return nil
+	}
+
+	lines := []string{i18n.T(lang, "search.header", i18n.Params{"count": total})}
+	for _, hit := range hits {
+		lines = append(lines, i18n.T(lang, "search.item", i18n.Params{
+			"id":      hit.Message.ID,
+			"time":    hit.Message.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			"room":    hit.Room,
+			"from":    hit.Message.From,
+			"content": utils.TruncateString(hit.Message.Content, 80),
+		}))
+	}
+	if total > len(hits) {
+		lines = append(lines, i18n.T(lang, "search.more", i18n.Params{"count": total - len(hits)}))
+	}
+	ch.userManager.SendToUser(currentUser.ID, strings.Join(lines, "\n")+"\n")
+	return nil
+}
@@ -0,0 +1,158 @@
+package history
+
+import (
+	"fmt"
+
+	"chatroom/message"
+	"chatroom/store"
+)
+
+// Store 历史消息存储
+type Store struct {
+	backend store.HistoryStore // 状态后端
+	maxSize int                // 每个房间保留的最大消息数
+}
+
+// NewStore 创建新的历史消息存储
+func NewStore(backend store.HistoryStore, maxSize int) *Store {
+	return &Store{
+		backend: backend,
+		maxSize: maxSize,
+	}
+}
+
+// swapAttempts 写回消息时历史记录被其他实例改变后重试的次数
+const swapAttempts = 5
+
+// Append 追加一条消息，消息还没有序号时分配房间内的下一个序号
+//
+// 消息ID有48位随机数，房间内保留的消息中重复的概率可以忽略，追加时不再逐条检查。
+func (s *Store) Append(room string, msg *message.Message) error {
+	if msg.Seq == 0 {
+		seq, err := s.backend.NextSeq(room)
+		if err != nil {
+			return err
+		}
+		msg.Seq = seq
+	}
+	entry, err := msg.Encode()
+	if err != nil {
+		return err
+	}
+	return s.backend.AppendHistory(room, entry, s.maxSize)
+}
+
+// Recent 获取最近 limit 条消息（按时间顺序），limit<=0 表示全部
+func (s *Store) Recent(room string, limit int) ([]*message.Message, error) {
+	entries, err := s.backend.History(room, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*message.Message, 0, len(entries))
+	for _, entry := range entries {
+		msg, err := message.DecodeMessage(entry)
+		if err != nil {
+			// 跳过无法解析的记录
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// Since 获取序号大于 since 的消息（按时间顺序），最多 limit 条，limit<=0 表示全部
+//
+// 早期保存的消息没有序号，不会被返回。
+func (s *Store) Since(room string, since int64, limit int) ([]*message.Message, error) {
+	recent, err := s.Recent(room, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*message.Message
+	for _, msg := range recent {
+		if msg.Seq <= since {
+			continue
+		}
+		messages = append(messages, msg)
+		if limit > 0 && len(messages) == limit {
+			break
+		}
+	}
+	return messages, nil
+}
+
+// Find 按消息ID查找消息
+func (s *Store) Find(room, id string) (*message.Message, bool, error) {
+	_, _, msg, err := s.find(room, id)
+	if err != nil || msg == nil {
+		return nil, false, err
+	}
+	return msg, true, nil
+}
+
+// Update 原地更新一条消息（按消息ID匹配）
+func (s *Store) Update(room string, msg *message.Message) error {
+	updated, err := s.Modify(room, msg.ID, func(existing *message.Message) error {
+		*existing = *msg
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if updated == nil {
+		return fmt.Errorf("消息 %s 不存在", msg.ID)
+	}
+	return nil
+}
+
+// Modify 查找消息并在修改后原地写回
+//
+// 写回时比较该位置的记录是否仍是读取时的内容，其他实例同时修改了这条消息，或者追加消息使列表被截断、
+// 位置移动时重新读取，对最新的内容再次执行 fn，不会覆盖别的消息或丢失别人的修改。
+// 消息不存在时返回 (nil, nil)；fn 返回错误时不写回。
+func (s *Store) Modify(room, id string, fn func(msg *message.Message) error) (*message.Message, error) {
+	for attempt := 0; attempt < swapAttempts; attempt++ {
+		index, entry, msg, err := s.find(room, id)
+		if err != nil || msg == nil {
+			return nil, err
+		}
+		if err := fn(msg); err != nil {
+			return nil, err
+		}
+
+		updated, err := msg.Encode()
+		if err != nil {
+			return nil, err
+		}
+		swapped, err := s.backend.SwapHistory(room, index, entry, updated)
+		if err != nil {
+			return nil, err
+		}
+		if swapped {
+			return msg, nil
+		}
+	}
+	return nil, fmt.Errorf("消息 %s 被同时修改的次数过多", id)
+}
+
+// find 查找消息及其在历史记录中的位置和原始记录
+func (s *Store) find(room, id string) (int, string, *message.Message, error) {
+	entries, err := s.backend.History(room, 0)
+	if err != nil {
+		return 0, "", nil, err
+	}
+
+	// 从最新的消息开始查找
+	for i := len(entries) - 1; i >= 0; i-- {
+		msg, err := message.DecodeMessage(entries[i])
+		if err != nil {
+			continue
+		}
+		if msg.ID == id {
+			return i, entries[i], msg, nil
+		}
+	}
+	return 0, "", nil, nil
+}
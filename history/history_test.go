@@ -0,0 +1,83 @@
+package history
+
+import (
+	"testing"
+
+	"chatroom/message"
+	"chatroom/store"
+)
+
+// racingBackend 在第一次写回之前模拟另一个实例追加消息，使历史记录被截断、位置移动
+type racingBackend struct {
+	*store.MemoryBackend
+	max    int
+	raced  bool
+	swaps  int
+	insert func() string
+}
+
+// SwapHistory 第一次调用时先追加一条记录
+func (b *racingBackend) SwapHistory(room string, index int, old, entry string) (bool, error) {
+	b.swaps++
+	if !b.raced {
+		b.raced = true
+		if err := b.AppendHistory(room, b.insert(), b.max); err != nil {
+			return false, err
+		}
+	}
+	return b.MemoryBackend.SwapHistory(room, index, old, entry)
+}
+
+func TestModifyAfterTrim(t *testing.T) {
+	const max = 3
+	backend := &racingBackend{MemoryBackend: store.NewMemoryBackend(), max: max}
+	s := NewStore(backend, max)
+
+	var ids []string
+	for _, content := range []string{"一", "二", "三"} {
+		msg := message.NewMessage(message.TypeChat, "alice", content)
+		if err := s.Append("lobby", msg); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, msg.ID)
+	}
+	backend.insert = func() string {
+		entry, _ := message.NewMessage(message.TypeChat, "bob", "四").Encode()
+		return entry
+	}
+
+	// 编辑第二条消息时另一个实例追加了消息，第二条移到了第一条的位置
+	edited, err := s.Modify("lobby", ids[1], func(msg *message.Message) error {
+		msg.Content = "已编辑"
+		return nil
+	})
+	if err != nil || edited == nil {
+		t.Fatalf("Modify = %v, %v", edited, err)
+	}
+	if backend.swaps != 2 {
+		t.Errorf("写回 %d 次, want 2（位置移动后重试）", backend.swaps)
+	}
+
+	recent, _ := s.Recent("lobby", 0)
+	var contents []string
+	for _, msg := range recent {
+		contents = append(contents, msg.Content)
+	}
+	if len(contents) != 3 || contents[0] != "已编辑" || contents[1] != "三" || contents[2] != "四" {
+		t.Fatalf("历史记录 %v, want [已编辑 三 四]", contents)
+	}
+	if msg, found, _ := s.Find("lobby", ids[2]); !found || msg.Content != "三" {
+		t.Fatalf("相邻的消息被改写: %+v", msg)
+	}
+}
+
+func TestModifyMissing(t *testing.T) {
+	s := NewStore(store.NewMemoryBackend(), 10)
+	msg, err := s.Modify("lobby", "nope", func(*message.Message) error {
+		t.Fatal("消息不存在时不应调用 fn")
+		return nil
+	})
+	if msg != nil || err != nil {
+		t.Fatalf("Modify = %v, %v, want nil, nil", msg, err)
+	}
+}
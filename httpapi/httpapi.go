@@ -0,0 +1,680 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chatroom/bot"
+	"chatroom/config"
+	"chatroom/forge"
+	"chatroom/handler"
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/room"
+	"chatroom/session"
+	"chatroom/user"
+	"chatroom/utils"
+)
+
+// 请求限制
+const (
+	maxBodyBytes      = 16 * 1024        // 入站 webhook 请求体的最大字节数
+	maxRepoBodyBytes  = 2 * 1024 * 1024  // 仓库事件请求体的最大字节数
+	readHeaderTimeout = 10 * time.Second // 读取请求头的超时
+	heartbeatInterval = 30 * time.Second // 只读事件流的心跳间隔，防止代理断开空闲连接
+)
+
+// Server HTTP接口
+//
+// 与TCP聊天服务共用同一个连接处理器，通过HTTP发送的消息走正常的广播路径。
+// 错误信息按服务器默认语言渲染，以 {"error": "..."} 返回。
+type Server struct {
+	config  *config.Config             // 配置
+	handler *handler.ConnectionHandler // 连接处理器
+	repos   *forge.Router              // 仓库事件路由
+	logger  *utils.Logger              // 日志记录器
+	server  *http.Server               // HTTP服务
+}
+
+// NewServer 创建HTTP接口
+func NewServer(cfg *config.Config, connectionHandler *handler.ConnectionHandler, repos *forge.Router, logger *utils.Logger) *Server {
+	s := &Server{
+		config:  cfg,
+		handler: connectionHandler,
+		repos:   repos,
+		logger:  logger,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hooks/", s.handleHook)
+	mux.HandleFunc("/repos/", s.handleRepo)
+	mux.HandleFunc("/rooms/", s.handleRooms)
+	mux.HandleFunc("/rooms", s.handleRoomList)
+	mux.HandleFunc("/sessions", s.handleSessions)
+	mux.HandleFunc("/whispers", s.handleWhispers)
+	mux.HandleFunc("/users", s.handleUsers)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: readHeaderTimeout}
+	return s
+}
+
+// Start 开始监听，请求在后台处理
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.config.HTTPAddr)
+	if err != nil {
+		return fmt.Errorf("启动HTTP接口失败: %v", err)
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("HTTP接口已停止: %v", err)
+		}
+	}()
+	s.logger.Info("HTTP接口启动成功，监听地址: %s", s.config.HTTPAddr)
+	return nil
+}
+
+// Close 关闭HTTP接口
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// handleHook 处理入站 webhook：POST /hooks/<令牌>
+//
+// 请求体可以是纯文本，也可以是 JSON {"text": "..."}（Content-Type 为 application/json 时），
+// 每个非空行作为一条消息，以令牌对应的机器人身份发送到其房间。
+func (s *Server) handleHook(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/hooks/")
+	if token == "" || strings.Contains(token, "/") {
+		s.writeError(w, http.StatusNotFound, i18n.Errorf("error.http_not_found", nil))
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.writeError(w, http.StatusMethodNotAllowed, i18n.Errorf("error.http_method", i18n.Params{"method": http.MethodPost}))
+		return
+	}
+
+	text, status, err := readText(w, r)
+	if err != nil {
+		s.writeError(w, status, err)
+		return
+	}
+
+	messages, err := s.handler.PostBotMessage(token, text)
+	if err != nil {
+		s.writeError(w, statusFor(err), err)
+		return
+	}
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ids": ids})
+}
+
+// handleRepo 处理 GitHub/GitLab 的仓库事件：POST /repos/github 或 /repos/gitlab
+//
+// 按仓库找到路由并验证平台的签名，把事件的一行摘要以平台名作为机器人发送到路由的房间；
+// 没有通过验证的路由时返回401，不需要通知的事件（如 ping）返回202。
+func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request) {
+	provider := strings.TrimPrefix(r.URL.Path, "/repos/")
+	if provider != forge.GitHub && provider != forge.GitLab {
+		s.writeError(w, http.StatusNotFound, i18n.Errorf("error.http_not_found", nil))
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.writeError(w, http.StatusMethodNotAllowed, i18n.Errorf("error.http_method", i18n.Params{"method": http.MethodPost}))
+		return
+	}
+
+	body, status, err := readBody(w, r, maxRepoBodyBytes)
+	if err != nil {
+		s.writeError(w, status, err)
+		return
+	}
+	event, err := forge.Parse(provider, r.Header.Get(forge.EventHeader(provider)), body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, i18n.Errorf("error.http_json", nil))
+		return
+	}
+
+	rooms, verified := s.repos.Rooms(provider, r.Header, body, event)
+	if !verified {
+		s.logger.Warn("%s 事件没有通过验证，仓库 %s", provider, event.Repo)
+		s.writeError(w, http.StatusUnauthorized, i18n.Errorf("error.forge_signature", nil))
+		return
+	}
+	if len(rooms) == 0 {
+		writeJSON(w, http.StatusAccepted, map[string]bool{"ignored": true})
+		return
+	}
+
+	summary := event.Summary(s.config.Language)
+	ids := make([]string, 0, len(rooms))
+	for _, roomName := range rooms {
+		messages, err := s.handler.SendBotMessages(forge.Name(provider), roomName, []string{summary})
+		if err != nil {
+			s.writeError(w, statusFor(err), err)
+			return
+		}
+		for _, msg := range messages {
+			ids = append(ids, msg.ID)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ids": ids})
+}
+
+// handleRooms 处理房间相关的请求：/rooms/<房间>/<资源>
+func (s *Server) handleRooms(w http.ResponseWriter, r *http.Request) {
+	roomName, resource, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/rooms/"), "/")
+	switch resource {
+	case "events":
+		s.handleEvents(w, r, roomName)
+	case "messages":
+		s.handleMessages(w, r, roomName)
+	default:
+		s.writeError(w, http.StatusNotFound, i18n.Errorf("error.http_not_found", nil))
+	}
+}
+
+// handleEvents 以 Server-Sent Events 推送公开房间的聊天事件：GET /rooms/<房间>/events
+//
+// 需要配置的访问令牌，只能放在 Authorization: Bearer <令牌> 中，不接受查询参数，避免令牌出现在访问日志里。
+// 新消息以消息ID作为事件ID，带 Last-Event-ID（或 ?last_event_id=）重连时从保留的历史记录补发之后的消息，
+// 该ID已不在历史记录中时不补发，先发送一个 reset 事件；编辑、删除和表情回应事件不带ID。私聊不会出现在流中，订阅者也不是用户，不占用最大用户数。
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request, roomName string) {
+	if s.config.StreamToken == "" {
+		s.writeError(w, http.StatusNotFound, i18n.Errorf("error.stream_disabled", nil))
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.writeError(w, http.StatusMethodNotAllowed, i18n.Errorf("error.http_method", i18n.Params{"method": http.MethodGet}))
+		return
+	}
+	if !s.streamAuthorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="chatroom"`)
+		s.writeError(w, http.StatusUnauthorized, i18n.Errorf("error.stream_token", nil))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, errors.New("响应不支持流式输出"))
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	sub, backlog, reset, err := s.handler.OpenStream(roomName, lastEventID)
+	if err != nil {
+		s.writeError(w, statusFor(err), err)
+		return
+	}
+	defer s.handler.CloseStream(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if reset {
+		writeReset(w, lastEventID)
+	}
+	replayed := make(map[string]bool, len(backlog))
+	for _, msg := range backlog {
+		writeEvent(w, message.NewMessageEvent(msg))
+		replayed[msg.ID] = true
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Done():
+			// 跟不上事件时断开，客户端重连后从历史记录补发
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event := <-sub.Events():
+			if event.Type == message.EventMessage && replayed[event.Message.ID] {
+				continue
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// streamAuthorized 检查只读事件流的访问令牌
+func (s *Server) streamAuthorized(r *http.Request) bool {
+	return tokenMatches(bearerToken(r), s.config.StreamToken)
+}
+
+// bearerToken 获取 Authorization: Bearer <令牌> 中的令牌
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+}
+
+// tokenMatches 以固定时间比较令牌
+func tokenMatches(token, expected string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// roomInfo REST 接口返回的房间信息
+type roomInfo struct {
+	Name    string    `json:"name"`              // 房间名
+	Topic   string    `json:"topic,omitempty"`   // 话题
+	Mode    room.Mode `json:"mode"`              // 房间模式
+	Members int       `json:"members"`           // 在线成员数
+	Current bool      `json:"current,omitempty"` // 是否为会话用户所在的房间
+}
+
+// userInfo REST 接口返回的用户信息
+type userInfo struct {
+	Name     string        `json:"name"`             // 用户名
+	Presence user.Presence `json:"presence"`         // 在线状态
+	Status   string        `json:"status,omitempty"` // 状态留言
+	Room     string        `json:"room,omitempty"`   // 所在房间，非公开房间只对同一房间的用户显示
+	Client   string        `json:"client"`           // 客户端类型 (tcp 或 api)
+	Joined   time.Time     `json:"joined"`           // 加入时间
+}
+
+// handleSessions 创建或结束 REST 接口会话
+//
+// POST /sessions 用配置的访问令牌和 {"name": "..."} 创建会话，返回会话令牌，之后的请求以 Authorization: Bearer <会话令牌> 认证；
+// DELETE /sessions 结束当前会话。
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if s.config.APIToken == "" {
+		s.writeError(w, http.StatusNotFound, i18n.Errorf("error.api_disabled", nil))
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		if !tokenMatches(bearerToken(r), s.config.APIToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chatroom"`)
+			s.writeError(w, http.StatusUnauthorized, i18n.Errorf("error.api_token", nil))
+			return
+		}
+		var req struct {
+			Name string `json:"name"`
+		}
+		if status, err := readJSON(w, r, &req); err != nil {
+			s.writeError(w, status, err)
+			return
+		}
+		sess, name, err := s.handler.OpenSession(req.Name)
+		if err != nil {
+			s.writeError(w, statusFor(err), err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"token": sess.Token, "name": name, "timeout": s.config.Timeout})
+
+	case http.MethodDelete:
+		sess, _, ok := s.session(w, r)
+		if !ok {
+			return
+		}
+		s.handler.CloseSession(sess.Token)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		s.writeError(w, http.StatusMethodNotAllowed, i18n.Errorf("error.http_method", i18n.Params{"method": "POST, DELETE"}))
+	}
+}
+
+// handleRoomList 列出会话用户可以看到的房间：GET /rooms
+func (s *Server) handleRoomList(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, http.MethodGet) {
+		return
+	}
+	_, currentUser, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	list, err := s.handler.ListRooms(currentUser)
+	if err != nil {
+		s.writeError(w, statusFor(err), err)
+		return
+	}
+	rooms := make([]roomInfo, 0, len(list))
+	for _, info := range list {
+		rooms = append(rooms, roomInfo{
+			Name:    info.Name,
+			Topic:   info.Topic,
+			Mode:    info.CurrentMode(),
+			Members: info.Members,
+			Current: info.Name == currentUser.Room,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"rooms": rooms})
+}
+
+// handleMessages 读取或发送房间中的消息
+//
+// GET /rooms/<房间>/messages?since=<序号>&wait=<等待时间> 返回序号大于 since 的消息和下一次请求使用的 next，
+// 没有新消息时最多等待 wait（如 30s，长轮询）；省略 since 时返回最近的消息。
+// 已删除的消息不返回，带 tombstones=true 时以内容为空、deleted 为 true 的墓碑返回。
+// POST /rooms/<房间>/messages 发送一条消息（纯文本或 {"text": "..."}），会话用户不在该房间时先加入。
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request, roomName string) {
+	if !s.allow(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	_, currentUser, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		text, status, err := readText(w, r)
+		if err != nil {
+			s.writeError(w, status, err)
+			return
+		}
+		msg, err := s.handler.PostMessage(currentUser, roomName, text)
+		if err != nil {
+			s.writeError(w, statusFor(err), err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"message": msg})
+		return
+	}
+
+	since, wait, err := pollParams(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tombstones := false
+	if value := r.URL.Query().Get("tombstones"); value != "" {
+		if tombstones, err = strconv.ParseBool(value); err != nil {
+			s.writeError(w, http.StatusBadRequest, i18n.Errorf("error.api_tombstones", i18n.Params{"value": value}))
+			return
+		}
+	}
+	messages, next, err := s.handler.ReadMessages(r.Context(), currentUser, roomName, since, wait, tombstones)
+	if err != nil {
+		s.writeError(w, statusFor(err), err)
+		return
+	}
+	if next < 0 {
+		next = 0
+	}
+	if messages == nil {
+		messages = []*message.Message{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"messages": messages, "next": next})
+}
+
+// handleWhispers 发送或读取私聊
+//
+// POST /whispers 以 {"to": "<用户名>", "text": "..."} 发送私聊；
+// GET /whispers?since=<序号>&wait=<等待时间> 返回会话收到的序号大于 since 的私聊（长轮询），会话只保留最近的私聊。
+func (s *Server) handleWhispers(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	sess, currentUser, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var req struct {
+			To   string `json:"to"`
+			Text string `json:"text"`
+		}
+		if status, err := readJSON(w, r, &req); err != nil {
+			s.writeError(w, status, err)
+			return
+		}
+		if err := s.handler.SendWhisper(currentUser, req.To, req.Text); err != nil {
+			s.writeError(w, statusFor(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	since, wait, err := pollParams(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if since < 0 {
+		since = 0
+	}
+	whispers := s.handler.ReadWhispers(r.Context(), sess, since, wait)
+	next := since
+	if len(whispers) > 0 {
+		next = whispers[len(whispers)-1].Seq
+	}
+	if whispers == nil {
+		whispers = []session.Whisper{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"whispers": whispers, "next": next})
+}
+
+// handleUsers 列出本实例的在线用户：GET /users
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, http.MethodGet) {
+		return
+	}
+	_, currentUser, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	snapshots := s.handler.ListUsers(currentUser)
+	users := make([]userInfo, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		users = append(users, userInfo{
+			Name:     snapshot.Name,
+			Presence: snapshot.CurrentPresence(),
+			Status:   snapshot.StatusMessage,
+			Room:     snapshot.Room,
+			Client:   snapshot.ClientType,
+			Joined:   snapshot.JoinTime,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"users": users})
+}
+
+// session 验证 REST 接口会话，失败时写入错误响应
+func (s *Server) session(w http.ResponseWriter, r *http.Request) (*session.Session, *user.User, bool) {
+	if s.config.APIToken == "" {
+		s.writeError(w, http.StatusNotFound, i18n.Errorf("error.api_disabled", nil))
+		return nil, nil, false
+	}
+	sess, currentUser, err := s.handler.Session(bearerToken(r))
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="chatroom"`)
+		s.writeError(w, statusFor(err), err)
+		return nil, nil, false
+	}
+	return sess, currentUser, true
+}
+
+// allow 检查请求方法，不允许时写入405响应
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	allowed := strings.Join(methods, ", ")
+	w.Header().Set("Allow", allowed)
+	s.writeError(w, http.StatusMethodNotAllowed, i18n.Errorf("error.http_method", i18n.Params{"method": allowed}))
+	return false
+}
+
+// pollParams 解析长轮询的 since 和 wait 参数，省略 since 时返回-1
+//
+// wait 可以是时长（如 30s）或秒数，超过上限时由处理器截断。
+func pollParams(r *http.Request) (int64, time.Duration, error) {
+	query := r.URL.Query()
+	since := int64(-1)
+	if value := query.Get("since"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, i18n.Errorf("error.api_since", i18n.Params{"value": value})
+		}
+		since = n
+	}
+
+	var wait time.Duration
+	if value := query.Get("wait"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			seconds, convErr := strconv.Atoi(value)
+			if convErr != nil {
+				return 0, 0, i18n.Errorf("error.api_wait", i18n.Params{"value": value})
+			}
+			d = time.Duration(seconds) * time.Second
+		}
+		if d < 0 {
+			return 0, 0, i18n.Errorf("error.api_wait", i18n.Params{"value": value})
+		}
+		wait = d
+	}
+	return since, wait, nil
+}
+
+// readJSON 读取JSON请求体，出错时返回响应状态
+func readJSON(w http.ResponseWriter, r *http.Request, value interface{}) (int, error) {
+	body, status, err := readBody(w, r, maxBodyBytes)
+	if err != nil {
+		return status, err
+	}
+	if err := json.Unmarshal(body, value); err != nil {
+		return http.StatusBadRequest, i18n.Errorf("error.http_json", nil)
+	}
+	return http.StatusOK, nil
+}
+
+// writeEvent 以 SSE 格式写入一个事件，新消息以消息ID作为事件ID
+func writeEvent(w io.Writer, event *message.Event) error {
+	if event.Type == message.EventMessage {
+		if _, err := fmt.Fprintf(w, "id: %s\n", event.Message.ID); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, strings.TrimSuffix(event.FormatJSON(), "\n"))
+	return err
+}
+
+// writeReset 写入 reset 事件：Last-Event-ID 已不在保留的历史记录中，中间的消息无法补发，客户端需要重新同步
+func writeReset(w io.Writer, lastEventID string) error {
+	data, err := json.Marshal(map[string]string{"last_event_id": lastEventID})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: reset\ndata: %s\n\n", data)
+	return err
+}
+
+// readBody 读取不超过 limit 字节的请求体，出错时返回响应状态
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, int, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, http.StatusRequestEntityTooLarge, i18n.Errorf("error.http_too_large", i18n.Params{"max": limit})
+		}
+		return nil, http.StatusBadRequest, i18n.Errorf("error.http_body", nil)
+	}
+	return body, http.StatusOK, nil
+}
+
+// readText 读取请求体中的文本，出错时返回响应状态
+func readText(w http.ResponseWriter, r *http.Request) (string, int, error) {
+	body, status, err := readBody(w, r, maxBodyBytes)
+	if err != nil {
+		return "", status, err
+	}
+
+	text := string(body)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		var payload struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return "", http.StatusBadRequest, i18n.Errorf("error.http_json", nil)
+		}
+		text = payload.Text
+	}
+	if !utf8.ValidString(text) {
+		return "", http.StatusBadRequest, i18n.Errorf("error.http_body", nil)
+	}
+	return text, http.StatusOK, nil
+}
+
+// errorStatus 需要特定响应状态的可本地化错误
+var errorStatus = map[string]int{
+	"error.room_name":      http.StatusNotFound,
+	"error.room_not_found": http.StatusNotFound,
+	"error.stream_room":    http.StatusForbidden,
+	"error.api_room":       http.StatusForbidden,
+	"error.room_full":      http.StatusServiceUnavailable,
+	"error.user_offline":   http.StatusNotFound,
+}
+
+// statusFor 根据处理器返回的错误选择响应状态：机器人令牌无效为404，会话无效为401，
+// 可本地化的错误是请求本身的问题（如内容被过滤），其他错误是服务器内部错误
+func statusFor(err error) int {
+	if errors.Is(err, bot.ErrUnknownToken) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, session.ErrUnknown) {
+		return http.StatusUnauthorized
+	}
+	if e, ok := err.(*i18n.Error); ok {
+		if status, exists := errorStatus[e.Key]; exists {
+			return status
+		}
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError 返回错误，内部错误只记录日志，不把细节返回给调用者
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	switch {
+	case status == http.StatusInternalServerError:
+		s.logger.Error("处理HTTP请求失败: %v", err)
+		err = i18n.Errorf("error.http_internal", nil)
+	case errors.Is(err, bot.ErrUnknownToken):
+		err = i18n.Errorf("error.bot_token", nil)
+	case errors.Is(err, session.ErrUnknown):
+		err = i18n.Errorf("error.api_session", nil)
+	}
+	writeJSON(w, status, map[string]string{"error": i18n.Localize(s.config.Language, err)})
+}
+
+// writeJSON 以JSON返回响应
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
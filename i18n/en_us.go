@@ -0,0 +1,425 @@
+package i18n
+
+// enUS 英文消息目录
+var enUS = map[string]string{
+	"welcome": "Welcome to Go Chatroom!\nType \\help to see available commands.\nStart chatting by typing a message!",
+
+	"user.join":   "User [{name}] joined the chatroom",
+	"user.leave":  "User [{name}] left the chatroom",
+	"user.rename": "User [{old}] is now known as [{new}]",
+	"time":        "Current time: {time}",
+	"stats":       "Chatroom stats: {count}/{max} users",
+
+	"message.system":    "[System] {content}",
+	"message.private":   "[Whisper] {from} -> {to}: {content}",
+	"message.broadcast": "[Broadcast] {from}: {content}",
+	"whisper.sent":      "[Whisper] -> {to}: {content}",
+
+	"userlist.empty":        "No users online",
+	"userlist.header#one":   "{count} user online:",
+	"userlist.header#other": "{count} users online:",
+	"userlist.item":         "- {name} (ID: {id}, online for {duration}, {presence})",
+
+	"rename.ok":    "Your name is now: {name}",
+	"quit":         "Leaving the chatroom...",
+	"lang.current": "Current language: {lang}, available: {supported}",
+	"lang.changed": "Interface language switched to: {lang}",
+	"server.full":  "The chatroom is full, please try again later",
+
+	"error.prefix":            "Error: {error}",
+	"error.invalid_command":   "Invalid command",
+	"error.unknown_command":   "Unknown command: {command}",
+	"error.unknown_cmd_type":  "Unknown command type",
+	"error.unsupported_lang":  "Unsupported language: {lang}, available: {supported}",
+	"error.room_full":         "The chatroom is full, no more users can join",
+	"error.user_id_exists":    "User ID already exists",
+	"error.user_not_found":    "User does not exist",
+	"error.name_taken":        "That name is already taken",
+	"error.channel_full":      "User message queue is full",
+	"error.user_offline":      "User {name} is not online",
+	"error.whisper_failed":    "Failed to send private message: {error}",
+	"error.name_empty":        "Name must not be empty",
+	"error.name_invalid_char": "Name contains an invalid character: {char}",
+	"error.name_too_wide":     "Name is too long: display width {width}, at most {max}",
+	"error.name_reserved":     "The name {name} is reserved",
+	"error.name_confusable":   "Name looks too similar to online user [{name}]",
+
+	"event.edit":     "[edited] #{id} [{from}] {content}",
+	"event.delete":   "[deleted] #{id} message from {from} was deleted by {by}",
+	"format.changed": "Output mode switched to: {mode}",
+	"ids.on":         "Message IDs are now shown",
+	"ids.off":        "Message IDs are now hidden",
+	"oper.ok":        "You are now an operator",
+
+	"error.oper_disabled":     "Operator authentication is not enabled on this server",
+	"error.oper_denied":       "Wrong operator password",
+	"error.message_not_found": "Message #{id} does not exist",
+	"error.message_deleted":   "Message #{id} has been deleted",
+	"error.not_message_owner": "You can only edit or delete your own messages",
+
+	"event.react":          "{emoji} {count} on #{id}",
+	"reactions.none":       "Message #{id} has no reactions yet",
+	"reactions.header":     "Reactions to message #{id}:",
+	"reactions.item#one":   "  {emoji} ({count} person): {names}",
+	"reactions.item#other": "  {emoji} ({count} people): {names}",
+
+	"error.invalid_emoji":   "Invalid emoji: {emoji}",
+	"error.already_reacted": "You already reacted with {emoji} to this message",
+	"error.not_reacted":     "You have not reacted to this message",
+
+	"message.reply":       "[{from}] ↪ reply to {parent} \"{quote}\" {content}",
+	"thread.header#one":   "Thread #{id} ({count} reply):",
+	"history.deleted":     "#{id} [{from}] (message deleted)",
+	"thread.header#other": "Thread #{id} ({count} replies):",
+
+	"attachment.room":    "{from} shared file {name} ({size}) with the room, type \\get {id} to download",
+	"attachment.private": "{from} sent you file {name} ({size}), type \\get {id} to download",
+	"attachment.sent":    "File {name} ({size}) sent to {to}, download ID: {id}",
+
+	"error.send_unsupported":   "File transfer is not supported on this connection",
+	"error.attachment_too_big": "File too large: {size}, at most {max}",
+	"error.attachment_failed":  "File transfer failed",
+	"error.attachment_missing": "File {id} does not exist or you may not download it",
+
+	"presence.online":         "online",
+	"presence.away":           "away",
+	"presence.busy":           "busy",
+	"presence.idle":           "idle",
+	"event.presence":          "{name} is now {presence}",
+	"event.presence_message":  "{name} is now {presence} ({message})",
+	"whisper.away_reply":      "[auto-reply] {name} is away: {message}",
+	"whisper.away_reply_bare": "[auto-reply] {name} is away",
+
+	"whois.header":   "User {name}:",
+	"whois.presence": "  Presence: {presence}",
+	"whois.status":   "  Status message: {message}",
+	"whois.joined":   "  Joined: {time} (online for {duration})",
+	"whois.idle":     "  Idle for: {duration}",
+	"whois.role":     "  Role: {role}",
+	"whois.rooms":    "  Rooms: {rooms}",
+	"whois.client":   "  Client: {client}",
+	"role.member":    "member",
+	"role.operator":  "operator",
+
+	"ignore.added":      "Ignoring {name} for this session; you will no longer see their messages",
+	"ignore.removed":    "No longer ignoring {name}",
+	"ignore.empty":      "You are not ignoring anyone",
+	"ignore.list#one":   "Ignoring {count} user: {names}",
+	"ignore.list#other": "Ignoring {count} users: {names}",
+
+	"error.ignore_self":         "You cannot ignore yourself",
+	"error.already_ignored":     "You are already ignoring {name}",
+	"error.not_ignored":         "You are not ignoring {name}",
+	"error.whisper_unavailable": "Cannot whisper to {name}",
+
+	"filter.status#one":     "{count} filter rule is active",
+	"filter.status#other":   "{count} filter rules are active",
+	"filter.reloaded#one":   "Reloaded {count} filter rule",
+	"filter.reloaded#other": "Reloaded {count} filter rules",
+	"filter.flagged":        "[moderation] Message from {name} matched rule {rule}: {content}",
+	"filter.muted":          "[moderation] {name} matched rule {rule} and was muted for {duration}",
+
+	"error.filter_reload":   "Failed to reload filter rules: {error}",
+	"error.filter_rejected": "Your message contains disallowed content and was not sent",
+	"error.filter_muted":    "Your message contains disallowed content; you are muted for {duration}",
+	"error.muted":           "You are muted for another {duration}",
+
+	"cmd.who":       "List online users",
+	"cmd.whois":     "Show details about a user",
+	"cmd.rename":    "Change your name",
+	"cmd.whisper":   "Send a private message",
+	"cmd.dm":        "Group direct messages: open, post and manage members",
+	"cmd.r":         "Reply to your most recent whisper or group DM",
+	"cmd.pubkey":    "Publish or look up an end-to-end encryption key",
+	"cmd.ewhisper":  "Send an end-to-end encrypted whisper (needs an E2E-capable client)",
+	"cmd.hook":      "Manage incoming webhook bots",
+	"cmd.time":      "Show the current time",
+	"cmd.stats":     "Show chatroom statistics",
+	"cmd.lang":      "Show or change the interface language",
+	"cmd.format":    "Change the output mode",
+	"cmd.ids":       "Show or hide message IDs",
+	"cmd.edit":      "Edit one of your messages",
+	"cmd.delete":    "Delete one of your messages (operators can delete any)",
+	"cmd.react":     "React to a message with an emoji",
+	"cmd.unreact":   "Remove your reaction",
+	"cmd.reactions": "Show reactions on a message",
+	"cmd.reply":     "Reply to a message",
+	"cmd.thread":    "Show a message and all its replies",
+	"cmd.send":      "Send a file (content follows the command)",
+	"cmd.export":    "Export the conversation, including your whispers",
+	"cmd.get":       "Download a file",
+	"cmd.join":      "Join a room, creating it if needed",
+	"cmd.leave":     "Leave the current room and return to the lobby",
+	"cmd.rooms":     "List rooms",
+	"cmd.topic":     "Show or set the topic of the current room",
+	"cmd.mode":      "Show or change the mode of the current room (room operators)",
+	"cmd.invite":    "Invite a user to the current room (room operators)",
+	"cmd.roomop":    "Make a user an operator of the current room (room operators)",
+	"cmd.motd":      "Show the message of the day; operators can reload it",
+	"cmd.search":    "Search message history",
+	"cmd.away":      "Mark yourself away",
+	"cmd.busy":      "Mark yourself busy",
+	"cmd.back":      "Mark yourself online again",
+	"cmd.ignore":    "Hide messages from a user",
+	"cmd.unignore":  "Stop ignoring a user",
+	"cmd.ignores":   "List ignored users",
+	"cmd.poll":      "Start a poll, or close one",
+	"cmd.vote":      "Vote in a poll",
+	"cmd.remind":    "Set a personal reminder",
+	"cmd.schedule":  "Schedule a broadcast to the current room (operators can use cron schedules)",
+	"cmd.reminders": "List or cancel reminders and scheduled broadcasts",
+	"cmd.oper":      "Authenticate as operator",
+	"cmd.filter":    "Show or reload filter rules",
+	"cmd.help":      "Show help, or detailed usage of a command",
+	"cmd.quit":      "Leave the chatroom",
+
+	"cmd.whisper.detail":   "Spacing in the message is preserved. If the recipient is away you get their away message as an auto-reply.",
+	"cmd.rename.detail":    "Names cannot contain whitespace and are compared ignoring case and look-alike characters.",
+	"cmd.send.detail":      "Quote file names containing spaces, e.g. \\send room \"my file.txt\" 12.\nThe file content must follow the command line.",
+	"cmd.react.detail":     "The emoji can be a Unicode emoji or a shortcode such as :+1:.",
+	"cmd.edit.detail":      "The message ID may start with #; spacing in the new text is preserved.",
+	"cmd.reply.detail":     "The message ID may start with #; spacing in the reply is preserved.",
+	"cmd.lang.detail":      "Without an argument, shows the current and supported languages.",
+	"cmd.filter.detail":    "Without an argument, shows the number of active rules; reload re-reads the rule file.",
+	"cmd.join.detail":      "The room name may start with #; it can contain letters, digits, - and _ and is case-insensitive. Append the key when joining a room that requires one. Chat, replies and polls only reach people in the same room.",
+	"cmd.topic.detail":     "Without an argument, shows the current topic; \\topic - clears it. The topic is shown when joining the room and in \\rooms.",
+	"cmd.mode.detail":      "public: listed and open to everyone; private: hidden from the room list; invite: only invited users can join; password: joining requires a key, e.g. \\mode password s3cret.\nThe user who creates a room is its operator; server operators can manage any room.",
+	"cmd.motd.detail":      "The message of the day is shown on connect; reload re-reads the file (operators only, or send SIGHUP to the server).",
+	"cmd.dm.detail":        "\\dm alice,bob [text] opens a conversation and gives it a short handle (the same member set reuses it); \\dm <handle> <text> posts to it.\n\\dm list shows your conversations, \\dm add|remove <handle> <name> changes members, \\dm leave <handle> leaves. Group DMs are not kept in history.",
+	"cmd.r.detail":         "Goes to whoever you last whispered with, or the group DM you last posted to or received from.",
+	"cmd.pubkey.detail":    "\\pubkey <box-key> <sign-key> publishes your keys (X25519 and Ed25519, base64) until you disconnect;\n\\pubkey <name> looks up someone else's, \\pubkey shows your own fingerprint. The bundled client publishes and looks up keys automatically.",
+	"cmd.ewhisper.detail":  "The argument is a sealed box produced by the client; the server relays it as-is without decrypting or storing it. With the bundled client just type \\ewhisper <name> <text> and it encrypts locally.",
+	"cmd.hook.detail":      "list (default) shows all bots; add <name> <room> creates a bot that posts to that room and shows its token once; remove <id> deletes a bot and revokes its token.\nExternal programs POST plain text or JSON {\"text\": \"...\"} to /hooks/<token> on the HTTP interface; each non-empty line becomes a message.",
+	"cmd.search.detail":    "All terms must match; Chinese text is indexed as character bigrams.\nFilters: from:<name> in:<room> before:<date> after:<date>, dates as 2006-01-02 or 2006-01-02T15:04.",
+	"cmd.export.detail":    "Arguments may be given in any order: room defaults to the current room; since is a duration such as 30m, 24h or 7d, or a date such as 2006-01-02; format is md (default), html or jsonl.\nThe file is delivered as an attachment only you can download.",
+	"cmd.poll.detail":      "Quote the question and options if they contain spaces; append a duration (e.g. 10m), multi (multiple choice) and anon (anonymous).\nExample: \\poll \"Move the weekly meeting?\" Thursday Friday 1h multi. \\poll close <ID> closes a poll early (creator or operator); the result is posted as a system message.",
+	"cmd.vote.detail":      "In multiple-choice polls separate several numbers with commas, e.g. \\vote 1a2b3c 1,3.",
+	"cmd.remind.detail":    "The time can be a duration such as 10m, 1h30m or 2d, a time such as 15:04 (tomorrow if already past), or 2006-01-02T15:04.\nReminders belong to this connection and are cancelled when you leave the chat.",
+	"cmd.schedule.detail":  "Times work as for \\remind. Operators can use a quoted five-field cron schedule (minute hour day month weekday) or a shortcut such as @daily or @hourly,\ne.g. \\schedule \"30 9 * * 1-5\" Standup time!",
+	"cmd.reminders.detail": "Lists your reminders and scheduled broadcasts (operators see all jobs); cancel <ID> cancels one.",
+	"cmd.help.detail":      "Quote arguments or escape spaces with a backslash. Input starting with \\\\ is sent as chat (e.g. \\\\who sends the text \\who).",
+
+	"help.header":  "Available commands:",
+	"help.footer":  "Type \\help <command> for detailed usage.",
+	"help.usage":   "Usage: {usage}",
+	"help.aliases": "Aliases: {aliases}",
+	"help.role":    "Requires role: {role}",
+
+	"error.missing_arg":        "Missing argument {arg}. Usage: {usage}",
+	"error.extra_args":         "Too many arguments. Usage: {usage}",
+	"error.invalid_arg":        "Invalid value for {arg}: {value}. Usage: {usage}",
+	"error.unterminated_quote": "Unterminated quote",
+	"error.permission_denied":  "You are not allowed to use {command}",
+
+	"search.none":         "No matching messages",
+	"search.header#one":   "Found {count} message:",
+	"search.header#other": "Found {count} messages:",
+	"search.item":         "#{id} [{time}] {from}: {content}",
+	"search.more#one":     "...and {count} older result; narrow your search",
+	"search.more#other":   "...and {count} older results; narrow your search",
+
+	"error.search_empty": "Enter search terms or filters",
+	"error.search_date":  "Invalid date: {value}; use 2006-01-02 or 2006-01-02T15:04",
+
+	"export.title":       "Chat transcript: {room}",
+	"export.created":     "Exported at: {time}",
+	"export.since":       "Since: {time}",
+	"export.count#one":   "{count} message",
+	"export.count#other": "{count} messages",
+	"export.private":     "[whisper {from} -> {to}]",
+	"export.edited":      "(edited)",
+	"export.deleted":     "(message deleted)",
+	"export.ready#one":   "Exported {count} message to {name} ({size}), type \\get {id} to download",
+	"export.ready#other": "Exported {count} messages to {name} ({size}), type \\get {id} to download",
+
+	"error.export_room":    "Room {room} does not exist",
+	"error.export_empty":   "There are no messages to export",
+	"error.export_too_big": "Export too large: {size}, at most {max}; specify a start time",
+	"error.export_failed":  "Failed to export the transcript",
+
+	"job.added.remind":   "I will remind you at {time}: {text} (#{id})",
+	"job.added.schedule": "Will broadcast to #{room} at {time}: {text} (#{id})",
+	"job.added.cron":     "Created recurring broadcast #{id} for #{room} ({cron}), next run: {time}",
+	"job.remind":         "[reminder] {text}",
+	"job.kind.remind":    "reminder",
+	"job.kind.schedule":  "broadcast",
+	"job.kind.cron":      "recurring",
+	"job.item":           "#{id} [{kind}] {time} {owner}: {text}",
+	"job.item_cron":      "#{id} [{kind}] {cron}, next {time} {owner}: {text}",
+	"job.empty":          "No pending reminders or scheduled broadcasts",
+	"job.header#one":     "{count} pending job:",
+	"job.header#other":   "{count} pending jobs:",
+	"job.cancelled":      "Cancelled #{id}",
+
+	"error.schedule_time":    "Invalid time: {value}; use a duration such as 10m, 2h or 1d, a time such as 15:04, or 2006-01-02T15:04",
+	"error.schedule_past":    "The time {time} has already passed",
+	"error.schedule_too_far": "Jobs can be scheduled at most one year ahead",
+	"error.schedule_limit":   "You can keep at most {max} reminders and scheduled broadcasts",
+	"error.schedule_failed":  "Failed to save the scheduled job",
+	"error.cron_operator":    "Only operators can create recurring broadcasts",
+	"error.cron_invalid":     "Invalid schedule {cron}: {error}",
+	"error.cron_never":       "The schedule {cron} never fires",
+	"error.job_not_found":    "Job #{id} does not exist",
+
+	"poll.created":          "[poll #{id}] {creator} asks: {question}\n{options}",
+	"poll.hint.single":      "Single choice: type \\vote {id} <n> to vote; vote again to change your vote",
+	"poll.hint.multi":       "Multiple choice: type \\vote {id} <n>[,<n>...] to vote; pick an option again to remove it",
+	"poll.hint.single_anon": "Anonymous, single choice: type \\vote {id} <n> to vote; voters are not shown",
+	"poll.hint.multi_anon":  "Anonymous, multiple choice: type \\vote {id} <n>[,<n>...] to vote; voters are not shown",
+	"poll.deadline":         "Poll #{id} closes at {time}",
+	"poll.tally#one":        "[poll #{id}] {question} ({count} voter so far)\n{options}",
+	"poll.tally#other":      "[poll #{id}] {question} ({count} voters so far)\n{options}",
+	"poll.result#one":       "Poll #{id} closed: {question} ({count} voter)\n{options}\nResult: {winner}",
+	"poll.result#other":     "Poll #{id} closed: {question} ({count} voters)\n{options}\nResult: {winner}",
+	"poll.result_none":      "Poll #{id} closed: {question}; nobody voted",
+	"poll.voted":            "Your choice in poll #{id}: {choices}",
+	"poll.unvoted":          "Withdrew your vote in poll #{id}",
+
+	"error.poll_question":  "A poll needs a question",
+	"error.poll_option":    "Invalid or duplicate option: {option}",
+	"error.poll_options":   "A poll needs {min} to {max} options",
+	"error.poll_duration":  "Poll duration must be positive and at most {max}",
+	"error.poll_not_found": "Poll #{id} does not exist or has closed",
+	"error.poll_choice":    "Invalid option number: {choice}, expected 1 to {max}",
+	"error.poll_single":    "This is a single-choice poll; pick one option",
+	"error.poll_not_owner": "Only the creator or an operator can close this poll",
+
+	"room.entered":       "You are now in #{room}",
+	"room.joined":        "{name} joined #{room}",
+	"room.left":          "{name} left #{room}",
+	"room.topic":         "Topic for #{room}: {topic} (set by {by} at {time})",
+	"room.no_topic":      "#{room} has no topic",
+	"room.topic_changed": "{name} changed the topic of #{room} to: {topic}",
+	"room.topic_cleared": "{name} cleared the topic of #{room}",
+	"room.list#one":      "{count} room:",
+	"room.list#other":    "{count} rooms:",
+	"room.item":          "{current} #{room}{mode} ({count} online)",
+	"room.item_topic":    "{current} #{room}{mode} ({count} online): {topic}",
+
+	"motd.none":     "No message of the day is set",
+	"motd.reloaded": "Reloaded the message of the day",
+
+	"error.room_name":        "Invalid room name: {name}; use letters, digits, - and _, at most {max} characters",
+	"error.room_already_in":  "You are already in #{room}",
+	"error.room_leave_lobby": "You are already in the lobby",
+	"error.topic_too_long":   "The topic must be at most {max} characters",
+	"error.motd_reload":      "Failed to reload the message of the day: {error}",
+
+	"room.hidden":                "(non-public room)",
+	"room.tag.private":           "[private]",
+	"room.tag.invite":            "[invite only]",
+	"room.tag.password":          "[key required]",
+	"room.mode.public":           "#{room} is a public room",
+	"room.mode.private":          "#{room} is private and hidden from the room list",
+	"room.mode.invite":           "#{room} is invite only",
+	"room.mode.password":         "#{room} requires a key to join",
+	"room.mode_changed.public":   "{name} made #{room} public",
+	"room.mode_changed.private":  "{name} made #{room} private; it no longer appears in the room list",
+	"room.mode_changed.invite":   "{name} made #{room} invite only",
+	"room.mode_changed.password": "{name} set a key for #{room}",
+	"room.invite_sent":           "Invited {name} to #{room}",
+	"room.invite_received":       "{by} invited you to #{room}; type \\join {room} to join",
+	"room.op_added":              "{by} made {name} an operator of #{room}",
+
+	"error.room_invite_only":  "#{room} is invite only",
+	"error.room_key_required": "#{room} requires a key; use \\join {room} <key>",
+	"error.room_bad_key":      "Wrong key for #{room}",
+	"error.room_key_missing":  "Please provide a key, e.g. \\mode password <key>",
+	"error.room_lobby_mode":   "The lobby is always public",
+	"error.room_lobby_invite": "The lobby is always public; no invitation is needed",
+	"error.room_lobby_op":     "The lobby is managed by server operators",
+	"error.room_not_operator": "Only operators of #{room} can use {command}",
+	"error.room_already_op":   "{name} is already an operator of #{room}",
+
+	"dm.opened":     "[DM:{handle}] {by} opened a conversation with {members}. Use \\dm {handle} <text> to post",
+	"dm.added":      "[DM:{handle}] {by} added {name}; members: {members}",
+	"dm.removed":    "[DM:{handle}] {by} removed {name}",
+	"dm.left":       "[DM:{handle}] {name} left the conversation",
+	"dm.offline":    "Not delivered to offline members: {names}",
+	"dm.none":       "You are not in any group DMs",
+	"dm.list#one":   "You are in {count} group DM:",
+	"dm.list#other": "You are in {count} group DMs:",
+	"dm.item":       "  [DM:{handle}] {members}",
+
+	"error.dm_members":        "A group DM needs 1 to {max} other members",
+	"error.dm_not_found":      "No group DM {handle}",
+	"error.dm_already_member": "{name} is already in group DM {handle}",
+	"error.dm_not_member":     "{name} is not in group DM {handle}",
+	"error.dm_full":           "A group DM can have at most {max} members",
+	"error.dm_unavailable":    "Cannot send direct messages to {name}",
+	"error.dm_no_reply":       "Nobody to reply to yet",
+
+	"event.pubkey": "Key fingerprint of {name}: {fingerprint}",
+	"event.sealed": "[Encrypted] {from} -> {to}: (use an E2E-capable client to read this)",
+
+	"pubkey.published": "Encryption key published, fingerprint: {fingerprint}",
+	"pubkey.own":       "Your key fingerprint: {fingerprint}",
+	"pubkey.none":      "You have not published a key",
+	"sealed.sent":      "[Encrypted] -> {to}: (sent)",
+
+	"error.pubkey_invalid":   "Invalid key: expected a base64 X25519 box key and Ed25519 signing key",
+	"error.pubkey_missing":   "{name} has not published a key, cannot send an encrypted whisper",
+	"error.pubkey_required":  "Publish your own key with \\pubkey first; the recipient needs it to verify the signature",
+	"error.sealed_malformed": "Encrypted whispers need an E2E-capable client; this is not a valid sealed box",
+
+	"e2e.enabled":       "End-to-end encryption enabled, key fingerprint: {fingerprint} (key file {path})",
+	"e2e.received":      "[Encrypted] {from} -> you: {text}",
+	"e2e.bad_signature": "Dropped an encrypted whisper from {from}: bad signature",
+	"e2e.undecryptable": "Could not decrypt an encrypted whisper from {from}",
+	"e2e.key_changed":   "Warning: the key of {name} changed, new fingerprint: {fingerprint}",
+	"e2e.seal_failed":   "Encryption failed: {error}",
+
+	// Incoming webhook bots
+	"message.bot":              "[bot {from}] {content}",
+	"export.bot":               "[bot]",
+	"hook.created":             "Created bot {name} (id {id}) posting to #{room}\nThe token is shown only once, keep it safe: {token}\nExternal programs post with POST /hooks/{token}",
+	"hook.http_disabled":       "Note: the HTTP interface is disabled; bots can post only after a listen address is set with -http",
+	"hook.removed":             "Deleted bot {name} (id {id}); its token no longer works",
+	"hook.none":                "There are no bots yet",
+	"hook.list#one":            "{count} bot:",
+	"hook.list#other":          "{count} bots:",
+	"hook.item":                "  {id} {name} → #{room} (created by {creator} at {time})",
+	"error.hook_usage":         "Usage: \\hook add <name> <room> or \\hook remove <id>",
+	"error.bot_not_found":      "No bot with id {id}",
+	"error.bot_token":          "Invalid token",
+	"error.hook_empty":         "The message is empty",
+	"error.hook_lines#one":     "At most {max} line can be sent at once",
+	"error.hook_lines#other":   "At most {max} lines can be sent at once",
+	"error.http_not_found":     "Not found",
+	"error.http_method":        "Only {method} requests are supported",
+	"error.http_too_large":     "The request body exceeds {max} bytes",
+	"error.http_body":          "Could not read the request body; it must be UTF-8 text",
+	"error.http_json":          "Invalid JSON request body",
+	"error.http_internal":      "Internal server error",
+	"forge.push#one":           "[{repo}] {user} pushed {count} commit to {branch}: {title} {url}",
+	"forge.push#other":         "[{repo}] {user} pushed {count} commits to {branch}: {title} {url}",
+	"forge.tag":                "[{repo}] {user} pushed tag {tag} {url}",
+	"forge.tag_deleted":        "[{repo}] {user} deleted tag {tag}",
+	"forge.branch_deleted":     "[{repo}] {user} deleted branch {branch}",
+	"forge.branch_updated":     "[{repo}] {user} updated branch {branch} {url}",
+	"forge.change":             "[{repo}] {user} {verb} {noun} {ref}: {title} {url}",
+	"forge.verb.opened":        "opened",
+	"forge.verb.closed":        "closed",
+	"forge.verb.merged":        "merged",
+	"forge.verb.reopened":      "reopened",
+	"forge.noun.pull_request":  "pull request",
+	"forge.noun.merge_request": "merge request",
+	"forge.noun.issue":         "issue",
+	"forge.pipeline":           "[{repo}] Pipeline {name} on {branch} {status} {url}",
+	"forge.status.success":     "succeeded",
+	"forge.status.failed":      "failed",
+	"forge.status.canceled":    "was canceled",
+	"error.forge_signature":    "Signature verification failed, or no route is configured for this repository",
+	"error.room_not_found":     "Room #{room} does not exist",
+	"error.stream_room":        "#{room} is not a public room and cannot be streamed",
+	"error.stream_disabled":    "The read-only event stream is disabled",
+	"error.stream_token":       "Missing or invalid access token",
+	"error.api_disabled":       "The REST API is disabled",
+	"error.api_token":          "Missing or invalid access token",
+	"error.api_session":        "The session is invalid or has expired; create a new one",
+	"error.api_room":           "#{room} is not a public room; only its members can read it",
+	"error.api_empty":          "Missing recipient or text",
+	"error.api_since":          "Invalid sequence number: {value}",
+	"error.api_wait":           "Invalid wait time: {value}, e.g. 30s",
+	"error.api_tombstones":     "Invalid tombstones value: {value}, expected true or false",
+}
@@ -0,0 +1,167 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLang 默认语言
+const DefaultLang = "zh-CN"
+
+// Params 消息参数，在消息模板中以 {name} 形式引用
+type Params map[string]interface{}
+
+// catalogs 已注册的消息目录 (语言代码 -> 消息键 -> 模板)
+var catalogs = map[string]map[string]string{
+	"zh-CN": zhCN,
+	"en-US": enUS,
+}
+
+// pluralRules 各语言的复数规则，返回复数形式后缀
+var pluralRules = map[string]func(n float64) string{
+	"zh-CN": func(n float64) string { return "other" },
+	"en-US": func(n float64) string {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+}
+
+// Supported 获取支持的语言列表
+func Supported() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Normalize 规范化语言代码，例如 "en"、"en_us" 规范化为 "en-US"
+func Normalize(code string) (string, bool) {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "_", "-"))
+	if code == "" {
+		return "", false
+	}
+	for lang := range catalogs {
+		lower := strings.ToLower(lang)
+		if code == lower || code == strings.SplitN(lower, "-", 2)[0] {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// T 按语言翻译消息
+//
+// 参数中包含 count 时按该语言的复数规则选择 key#one / key#other 形式。
+// 找不到翻译时依次回退到默认语言和消息键本身。
+func T(lang, key string, params Params) string {
+	template, ok := lookup(lang, key, params)
+	if !ok {
+		template, ok = lookup(DefaultLang, key, params)
+	}
+	if !ok {
+		template = key
+	}
+	return format(template, params)
+}
+
+// Has 判断语言（或默认语言）的消息目录中是否有指定的键
+func Has(lang, key string) bool {
+	if _, ok := lookup(lang, key, nil); ok {
+		return true
+	}
+	_, ok := lookup(DefaultLang, key, nil)
+	return ok
+}
+
+// lookup 在指定语言的目录中查找消息模板
+func lookup(lang, key string, params Params) (string, bool) {
+	catalog, ok := catalogs[lang]
+	if !ok {
+		return "", false
+	}
+
+	if count, ok := toFloat(params["count"]); ok {
+		if rule, ok := pluralRules[lang]; ok {
+			if template, ok := catalog[key+"#"+rule(count)]; ok {
+				return template, true
+			}
+		}
+		if template, ok := catalog[key+"#other"]; ok {
+			return template, true
+		}
+	}
+
+	template, ok := catalog[key]
+	return template, ok
+}
+
+// format 替换模板中的 {name} 占位符
+func format(template string, params Params) string {
+	if len(params) == 0 || !strings.Contains(template, "{") {
+		return template
+	}
+
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// toFloat 将数字参数转换为float64
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// Text 可本地化的文本，在发送给具体用户时才按其语言渲染
+type Text struct {
+	Key    string `json:"key"`              // 消息键
+	Params Params `json:"params,omitempty"` // 消息参数
+}
+
+// NewText 创建可本地化的文本
+func NewText(key string, params Params) Text {
+	return Text{Key: key, Params: params}
+}
+
+// Render 按语言渲染文本
+func (t Text) Render(lang string) string {
+	return T(lang, t.Key, t.Params)
+}
+
+// Error 可本地化的错误
+type Error struct {
+	Text
+}
+
+// Errorf 创建可本地化的错误
+func Errorf(key string, params Params) error {
+	return &Error{Text: NewText(key, params)}
+}
+
+// Error 以默认语言返回错误信息
+func (e *Error) Error() string {
+	return e.Render(DefaultLang)
+}
+
+// Localize 按语言渲染错误信息，非本地化错误原样返回
+func Localize(lang string, err error) string {
+	if e, ok := err.(*Error); ok {
+		return e.Render(lang)
+	}
+	return err.Error()
+}
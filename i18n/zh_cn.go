@@ -0,0 +1,407 @@
+package i18n
+
+// zhCN 简体中文消息目录
+var zhCN = map[string]string{
+	"welcome": "欢迎来到Go聊天室!\n输入 \\help 查看可用命令。\n输入消息开始聊天吧！",
+
+	"user.join":   "用户 [{name}] 加入了聊天室",
+	"user.leave":  "用户 [{name}] 离开了聊天室",
+	"user.rename": "用户 [{old}] 将昵称改为 [{new}]",
+	"time":        "当前时间: {time}",
+	"stats":       "聊天室统计: 当前用户 {count}/{max}",
+
+	"message.system":    "[系统] {content}",
+	"message.private":   "[私聊] {from} -> {to}: {content}",
+	"message.broadcast": "[广播] {from}: {content}",
+	"whisper.sent":      "[私聊] -> {to}: {content}",
+
+	"userlist.empty":        "当前没有在线用户",
+	"userlist.header#other": "当前在线用户 ({count}人):",
+	"userlist.item":         "- {name} (ID: {id}, 在线时长: {duration}, 状态: {presence})",
+
+	"rename.ok":    "用户名已更改为: {name}",
+	"quit":         "正在退出聊天室...",
+	"lang.current": "当前语言: {lang}，可选: {supported}",
+	"lang.changed": "界面语言已切换为: {lang}",
+	"server.full":  "聊天室已满，请稍后再试",
+
+	"error.prefix":            "错误: {error}",
+	"error.invalid_command":   "无效命令",
+	"error.unknown_command":   "未知命令: {command}",
+	"error.unknown_cmd_type":  "未知命令类型",
+	"error.unsupported_lang":  "不支持的语言: {lang}，可选: {supported}",
+	"error.room_full":         "聊天室已满，无法加入新用户",
+	"error.user_id_exists":    "用户ID已存在",
+	"error.user_not_found":    "用户不存在",
+	"error.name_taken":        "用户名已被使用",
+	"error.channel_full":      "用户消息通道已满",
+	"error.user_offline":      "用户 {name} 不在线",
+	"error.whisper_failed":    "发送私聊消息失败: {error}",
+	"error.name_empty":        "用户名不能为空",
+	"error.name_invalid_char": "用户名包含无效字符: {char}",
+	"error.name_too_wide":     "用户名过长: 显示宽度 {width}，最多 {max}",
+	"error.name_reserved":     "用户名 {name} 为保留名称",
+	"error.name_confusable":   "用户名与在线用户 [{name}] 过于相似",
+
+	"event.edit":     "[已编辑] #{id} [{from}] {content}",
+	"event.delete":   "[已删除] #{id} 来自 {from} 的消息已被 {by} 删除",
+	"format.changed": "输出模式已切换为: {mode}",
+	"ids.on":         "已开启消息ID显示",
+	"ids.off":        "已关闭消息ID显示",
+	"oper.ok":        "你已成为管理员",
+
+	"error.oper_disabled":     "服务器未启用管理员认证",
+	"error.oper_denied":       "管理员密码错误",
+	"error.message_not_found": "消息 #{id} 不存在",
+	"error.message_deleted":   "消息 #{id} 已被删除",
+	"error.not_message_owner": "只能编辑或删除自己的消息",
+
+	"event.react":          "{emoji} {count} · #{id}",
+	"reactions.none":       "消息 #{id} 还没有表情回应",
+	"reactions.header":     "消息 #{id} 的表情回应:",
+	"reactions.item#other": "  {emoji} ({count}人): {names}",
+
+	"error.invalid_emoji":   "无效的表情: {emoji}",
+	"error.already_reacted": "你已经用 {emoji} 回应过这条消息",
+	"error.not_reacted":     "你还没有回应过这条消息",
+
+	"message.reply":       "[{from}] ↪ 回复 {parent}「{quote}」 {content}",
+	"history.deleted":     "#{id} [{from}] （消息已删除）",
+	"thread.header#other": "话题 #{id} ({count}条回复):",
+
+	"attachment.room":    "{from} 向聊天室发送了文件 {name} ({size})，输入 \\get {id} 下载",
+	"attachment.private": "{from} 向你发送了文件 {name} ({size})，输入 \\get {id} 下载",
+	"attachment.sent":    "文件 {name} ({size}) 已发送给 {to}，下载ID: {id}",
+
+	"error.send_unsupported":   "当前连接不支持文件传输",
+	"error.attachment_too_big": "文件过大: {size}，最大 {max}",
+	"error.attachment_failed":  "文件传输失败",
+	"error.attachment_missing": "文件 {id} 不存在或无权下载",
+
+	"presence.online":         "在线",
+	"presence.away":           "离开",
+	"presence.busy":           "忙碌",
+	"presence.idle":           "空闲",
+	"event.presence":          "{name} 的状态变为: {presence}",
+	"event.presence_message":  "{name} 的状态变为: {presence} ({message})",
+	"whisper.away_reply":      "[自动回复] {name} 暂时离开: {message}",
+	"whisper.away_reply_bare": "[自动回复] {name} 暂时离开",
+
+	"whois.header":   "用户 {name}:",
+	"whois.presence": "  状态: {presence}",
+	"whois.status":   "  状态留言: {message}",
+	"whois.joined":   "  加入时间: {time} (在线 {duration})",
+	"whois.idle":     "  空闲时长: {duration}",
+	"whois.role":     "  角色: {role}",
+	"whois.rooms":    "  所在房间: {rooms}",
+	"whois.client":   "  客户端类型: {client}",
+	"role.member":    "成员",
+	"role.operator":  "管理员",
+
+	"ignore.added":   "已屏蔽 {name}，本次连接内你将不再收到其消息",
+	"ignore.removed": "已取消屏蔽 {name}",
+	"ignore.empty":   "屏蔽列表为空",
+	"ignore.list":    "已屏蔽 {count} 个用户: {names}",
+
+	"error.ignore_self":         "不能屏蔽自己",
+	"error.already_ignored":     "已经屏蔽了 {name}",
+	"error.not_ignored":         "没有屏蔽 {name}",
+	"error.whisper_unavailable": "无法向 {name} 发送私聊",
+
+	"filter.status":   "当前共有 {count} 条过滤规则",
+	"filter.reloaded": "已重新加载 {count} 条过滤规则",
+	"filter.flagged":  "[审核] {name} 的消息触发规则 {rule}: {content}",
+	"filter.muted":    "[审核] {name} 触发规则 {rule}，已被禁言 {duration}",
+
+	"error.filter_reload":   "重新加载过滤规则失败: {error}",
+	"error.filter_rejected": "消息包含不允许的内容，未发送",
+	"error.filter_muted":    "消息包含不允许的内容，你已被禁言 {duration}",
+	"error.muted":           "你已被禁言，剩余 {duration}",
+
+	"cmd.who":       "查看在线用户列表",
+	"cmd.whois":     "查看用户详细信息",
+	"cmd.rename":    "修改用户名",
+	"cmd.whisper":   "发送私聊消息",
+	"cmd.dm":        "私聊群组：创建、发言和管理成员",
+	"cmd.r":         "回复最近的私聊或私聊群组",
+	"cmd.pubkey":    "发布或查询端到端加密公钥",
+	"cmd.ewhisper":  "发送端到端加密私聊（需要支持加密的客户端）",
+	"cmd.hook":      "管理入站 webhook 机器人",
+	"cmd.time":      "显示当前时间",
+	"cmd.stats":     "显示聊天室统计信息",
+	"cmd.lang":      "查看或切换界面语言",
+	"cmd.format":    "切换输出模式",
+	"cmd.ids":       "显示或隐藏消息ID",
+	"cmd.edit":      "编辑自己的消息",
+	"cmd.delete":    "删除自己的消息（管理员可删除任意消息）",
+	"cmd.react":     "对消息添加表情回应",
+	"cmd.unreact":   "取消表情回应",
+	"cmd.reactions": "查看消息的表情回应",
+	"cmd.reply":     "回复消息",
+	"cmd.thread":    "查看消息及其所有回复",
+	"cmd.send":      "发送文件（命令后紧跟文件内容）",
+	"cmd.export":    "导出聊天记录（包括你参与的私聊）",
+	"cmd.get":       "下载文件",
+	"cmd.join":      "加入房间，房间不存在时创建",
+	"cmd.leave":     "离开当前房间，回到大厅",
+	"cmd.rooms":     "查看房间列表",
+	"cmd.topic":     "查看或设置当前房间的话题",
+	"cmd.mode":      "查看或修改当前房间的模式（房间管理员）",
+	"cmd.invite":    "邀请用户加入当前房间（房间管理员）",
+	"cmd.roomop":    "把用户设为当前房间的管理员（房间管理员）",
+	"cmd.motd":      "查看每日消息，管理员可以重新加载",
+	"cmd.search":    "搜索历史消息",
+	"cmd.away":      "设置为离开状态",
+	"cmd.busy":      "设置为忙碌状态",
+	"cmd.back":      "恢复在线状态",
+	"cmd.ignore":    "屏蔽用户的消息",
+	"cmd.unignore":  "取消屏蔽",
+	"cmd.ignores":   "查看屏蔽列表",
+	"cmd.poll":      "发起投票，或用 close 结束投票",
+	"cmd.vote":      "投票",
+	"cmd.remind":    "设置个人提醒",
+	"cmd.schedule":  "定时向当前房间广播消息（管理员可以使用定时表达式重复广播）",
+	"cmd.reminders": "查看或取消提醒和定时广播",
+	"cmd.oper":      "管理员认证",
+	"cmd.filter":    "查看或重新加载过滤规则",
+	"cmd.help":      "显示帮助信息，指定命令时显示详细用法",
+	"cmd.quit":      "退出聊天室",
+
+	"cmd.whisper.detail":   "私聊内容原样保留空格。对方离开时会收到其离开留言作为自动回复。",
+	"cmd.rename.detail":    "用户名不能包含空白，比较时忽略大小写和外观相同的字符。",
+	"cmd.send.detail":      "文件名包含空格时用引号括起来，例如 \\send room \"my file.txt\" 12。\n命令行之后紧跟指定字节数的文件内容。",
+	"cmd.react.detail":     "表情可以是Unicode表情或 :+1: 等短代码。",
+	"cmd.edit.detail":      "消息ID可以带 # 前缀，新内容原样保留空格。",
+	"cmd.reply.detail":     "消息ID可以带 # 前缀，回复内容原样保留空格。",
+	"cmd.lang.detail":      "不带参数时显示当前语言和支持的语言。",
+	"cmd.filter.detail":    "不带参数时显示当前规则数量，reload 从规则文件重新加载。",
+	"cmd.join.detail":      "房间名可以带 # 前缀，只能包含字母、数字、- 和 _，不区分大小写。需要密码的房间在房间名后跟密码。聊天、回复和投票都只发送给同一房间的人。",
+	"cmd.topic.detail":     "不带参数时显示当前话题，\\topic - 清除话题。话题在加入房间时和 \\rooms 中显示。",
+	"cmd.mode.detail":      "public 公开；private 不出现在房间列表中；invite 只有被邀请的用户可以加入；password 加入时需要密码，例如 \\mode password s3cret。\n创建房间的用户是房间管理员，服务器管理员可以管理任何房间。",
+	"cmd.motd.detail":      "每日消息在连接时显示，reload 从文件重新加载（仅管理员，也可以向服务器发送 SIGHUP）。",
+	"cmd.dm.detail":        "\\dm alice,bob [内容] 创建群组并得到代号（成员相同的群组会直接复用），\\dm <代号> <内容> 在群组中发言。\n\\dm list 列出所在的群组，\\dm add|remove <代号> <用户名> 增删成员，\\dm leave <代号> 离开群组。群组消息不保存到历史记录。",
+	"cmd.r.detail":         "收到或发出私聊、群组消息后，\\r 会发送给同一个对象。",
+	"cmd.pubkey.detail":    "\\pubkey <加密公钥> <签名公钥> 发布自己的公钥（X25519 和 Ed25519，base64 编码），断开连接后失效；\n\\pubkey <用户名> 查询他人的公钥，\\pubkey 显示自己的公钥指纹。自带的客户端会自动发布和查询。",
+	"cmd.ewhisper.detail":  "参数是客户端加密签名后的密封信封，服务器原样转发，不解密也不保存。使用自带的客户端时输入 \\ewhisper <用户名> <内容> 即可，客户端在本地加密。",
+	"cmd.hook.detail":      "list（默认）列出所有机器人；add <名字> <房间> 创建在该房间发言的机器人并显示令牌，令牌只显示一次；remove <编号> 删除机器人，令牌立即失效。\n外部程序向 HTTP 接口的 POST /hooks/<令牌> 发送纯文本或 JSON {\"text\": \"...\"}，每个非空行作为一条消息。",
+	"cmd.search.detail":    "搜索词之间是“并且”的关系，中文按相邻两字索引。\n过滤条件: from:<用户名> in:<房间> before:<日期> after:<日期>，日期格式为 2006-01-02 或 2006-01-02T15:04。",
+	"cmd.export.detail":    "参数顺序不限: 房间默认为当前房间；起始时间可以是 30m、24h、7d 等时长或 2006-01-02 等日期；格式为 md（默认）、html 或 jsonl。\n导出的文件作为只有你能下载的附件发送。",
+	"cmd.poll.detail":      "问题和选项含空格时用引号括起来，末尾可以跟投票时长（如 10m）、multi（多选）和 anon（匿名）。\n例如 \\poll \"周会改到哪天\" 周四 周五 1h multi。\\poll close <ID> 提前结束投票（发起者或管理员），结果以系统消息公布。",
+	"cmd.vote.detail":      "多选投票可以用逗号分隔多个编号，例如 \\vote 1a2b3c 1,3。",
+	"cmd.remind.detail":    "时间可以是 10m、1h30m、2d 等时长，15:04（已过去则为明天）或 2006-01-02T15:04。\n提醒属于本次连接，离开聊天室时自动取消。",
+	"cmd.schedule.detail":  "时间格式同 \\remind。管理员可以使用五段式定时表达式（分 时 日 月 星期，需用引号括起来）或 @daily、@hourly 等简写，\n例如 \\schedule \"30 9 * * 1-5\" 站会时间到了。",
+	"cmd.reminders.detail": "列出你的提醒和定时广播（管理员可以看到所有任务），cancel <ID> 取消任务。",
+	"cmd.help.detail":      "参数中的空格可以用引号或反斜杠转义，以 \\\\ 开头的输入作为普通聊天发送（例如 \\\\who 发送文字 \\who）。",
+
+	"help.header":  "可用命令:",
+	"help.footer":  "输入 \\help <命令> 查看详细用法。",
+	"help.usage":   "用法: {usage}",
+	"help.aliases": "别名: {aliases}",
+	"help.role":    "需要角色: {role}",
+
+	"error.missing_arg":        "缺少参数 {arg}，用法: {usage}",
+	"error.extra_args":         "参数过多，用法: {usage}",
+	"error.invalid_arg":        "参数 {arg} 的值无效: {value}，用法: {usage}",
+	"error.unterminated_quote": "引号没有闭合",
+	"error.permission_denied":  "你没有权限使用 {command}",
+
+	"search.none":   "没有找到匹配的消息",
+	"search.header": "找到 {count} 条消息:",
+	"search.item":   "#{id} [{time}] {from}: {content}",
+	"search.more":   "……还有 {count} 条更早的结果，请缩小搜索范围",
+
+	"error.search_empty": "请输入搜索词或过滤条件",
+	"error.search_date":  "无效的日期: {value}，格式为 2006-01-02 或 2006-01-02T15:04",
+
+	"export.title":   "聊天记录: {room}",
+	"export.created": "导出时间: {time}",
+	"export.since":   "起始时间: {time}",
+	"export.count":   "消息数: {count}",
+	"export.private": "[私聊 {from} -> {to}]",
+	"export.edited":  "（已编辑）",
+	"export.deleted": "（消息已删除）",
+	"export.ready":   "已导出 {count} 条消息到文件 {name} ({size})，输入 \\get {id} 下载",
+
+	"error.export_room":    "房间 {room} 不存在",
+	"error.export_empty":   "没有可以导出的消息",
+	"error.export_too_big": "导出文件过大: {size}，最大 {max}，请指定起始时间",
+	"error.export_failed":  "导出聊天记录失败",
+
+	"job.added.remind":   "将在 {time} 提醒你: {text} (#{id})",
+	"job.added.schedule": "将在 {time} 向 #{room} 广播: {text} (#{id})",
+	"job.added.cron":     "已创建 #{room} 的定期广播 #{id} ({cron})，下次执行: {time}",
+	"job.remind":         "[提醒] {text}",
+	"job.kind.remind":    "提醒",
+	"job.kind.schedule":  "定时广播",
+	"job.kind.cron":      "定期广播",
+	"job.item":           "#{id} [{kind}] {time} {owner}: {text}",
+	"job.item_cron":      "#{id} [{kind}] {cron}，下次 {time} {owner}: {text}",
+	"job.empty":          "没有待执行的提醒或定时广播",
+	"job.header":         "待执行的任务 ({count}个):",
+	"job.cancelled":      "已取消 #{id}",
+
+	"error.schedule_time":    "无效的时间: {value}，可以是 10m、2h、1d 等时长，15:04 或 2006-01-02T15:04",
+	"error.schedule_past":    "时间 {time} 已经过去",
+	"error.schedule_too_far": "最多只能安排一年以内的任务",
+	"error.schedule_limit":   "最多只能同时保留 {max} 个提醒和定时广播",
+	"error.schedule_failed":  "保存定时任务失败",
+	"error.cron_operator":    "只有管理员可以创建定期广播",
+	"error.cron_invalid":     "无效的定时表达式 {cron}: {error}",
+	"error.cron_never":       "定时表达式 {cron} 永远不会执行",
+	"error.job_not_found":    "任务 #{id} 不存在",
+
+	"poll.created":          "[投票 #{id}] {creator} 发起投票: {question}\n{options}",
+	"poll.hint.single":      "单选，输入 \\vote {id} <编号> 投票，再次投票可以改票",
+	"poll.hint.multi":       "多选，输入 \\vote {id} <编号>[,<编号>...] 投票，再次选择同一选项可以取消",
+	"poll.hint.single_anon": "匿名单选，输入 \\vote {id} <编号> 投票，不公开投票者",
+	"poll.hint.multi_anon":  "匿名多选，输入 \\vote {id} <编号>[,<编号>...] 投票，不公开投票者",
+	"poll.deadline":         "投票 #{id} 将于 {time} 截止",
+	"poll.tally":            "[投票 #{id}] {question}（{count}人已投票）\n{options}",
+	"poll.result":           "投票 #{id} 已结束: {question}（{count}人参与）\n{options}\n结果: {winner}",
+	"poll.result_none":      "投票 #{id} 已结束: {question}，没有人投票",
+	"poll.voted":            "已在投票 #{id} 中选择: {choices}",
+	"poll.unvoted":          "已撤回在投票 #{id} 中的选择",
+
+	"error.poll_question":  "投票需要一个问题",
+	"error.poll_option":    "无效或重复的选项: {option}",
+	"error.poll_options":   "投票需要 {min} 到 {max} 个选项",
+	"error.poll_duration":  "投票时长必须大于0且不超过 {max}",
+	"error.poll_not_found": "投票 #{id} 不存在或已结束",
+	"error.poll_choice":    "无效的选项编号: {choice}，应为 1 到 {max}",
+	"error.poll_single":    "这是单选投票，只能选择一个选项",
+	"error.poll_not_owner": "只有发起者或管理员可以结束投票",
+
+	"room.entered":       "你已进入房间 #{room}",
+	"room.joined":        "{name} 进入了房间 #{room}",
+	"room.left":          "{name} 离开了房间 #{room}",
+	"room.topic":         "#{room} 的话题: {topic}（{by} 设置于 {time}）",
+	"room.no_topic":      "#{room} 还没有话题",
+	"room.topic_changed": "{name} 将 #{room} 的话题改为: {topic}",
+	"room.topic_cleared": "{name} 清除了 #{room} 的话题",
+	"room.list":          "共有 {count} 个房间:",
+	"room.item":          "{current} #{room}{mode}（{count} 人在线）",
+	"room.item_topic":    "{current} #{room}{mode}（{count} 人在线）: {topic}",
+
+	"motd.none":     "没有设置每日消息",
+	"motd.reloaded": "已重新加载每日消息",
+
+	"error.room_name":        "无效的房间名: {name}，只能包含字母、数字、- 和 _，最多 {max} 个字符",
+	"error.room_already_in":  "你已经在房间 #{room} 中",
+	"error.room_leave_lobby": "你已经在大厅中",
+	"error.topic_too_long":   "话题不能超过 {max} 个字符",
+	"error.motd_reload":      "重新加载每日消息失败: {error}",
+
+	"room.hidden":                "（非公开房间）",
+	"room.tag.private":           "[私密]",
+	"room.tag.invite":            "[仅限邀请]",
+	"room.tag.password":          "[需要密码]",
+	"room.mode.public":           "#{room} 是公开房间",
+	"room.mode.private":          "#{room} 是私密房间，不出现在房间列表中",
+	"room.mode.invite":           "#{room} 仅限邀请加入",
+	"room.mode.password":         "#{room} 加入时需要密码",
+	"room.mode_changed.public":   "{name} 将 #{room} 设为公开房间",
+	"room.mode_changed.private":  "{name} 将 #{room} 设为私密房间，不再出现在房间列表中",
+	"room.mode_changed.invite":   "{name} 将 #{room} 设为仅限邀请",
+	"room.mode_changed.password": "{name} 为 #{room} 设置了密码",
+	"room.invite_sent":           "已邀请 {name} 加入 #{room}",
+	"room.invite_received":       "{by} 邀请你加入 #{room}，输入 \\join {room} 加入",
+	"room.op_added":              "{by} 将 {name} 设为 #{room} 的管理员",
+
+	"error.room_invite_only":  "#{room} 仅限邀请加入",
+	"error.room_key_required": "#{room} 需要密码，请使用 \\join {room} <密码>",
+	"error.room_bad_key":      "#{room} 的密码错误",
+	"error.room_key_missing":  "请提供密码，例如 \\mode password <密码>",
+	"error.room_lobby_mode":   "大厅总是公开的，不能修改模式",
+	"error.room_lobby_invite": "大厅总是公开的，不需要邀请",
+	"error.room_lobby_op":     "大厅由服务器管理员管理",
+	"error.room_not_operator": "只有 #{room} 的管理员可以使用 {command}",
+	"error.room_already_op":   "{name} 已经是 #{room} 的管理员",
+
+	"dm.opened":  "[DM:{handle}] {by} 创建了私聊群组，成员: {members}。使用 \\dm {handle} <内容> 发言",
+	"dm.added":   "[DM:{handle}] {by} 把 {name} 加入了群组，成员: {members}",
+	"dm.removed": "[DM:{handle}] {by} 把 {name} 移出了群组",
+	"dm.left":    "[DM:{handle}] {name} 离开了群组",
+	"dm.offline": "以下成员不在线，没有收到消息: {names}",
+	"dm.none":    "你不在任何私聊群组中",
+	"dm.list":    "你在 {count} 个私聊群组中:",
+	"dm.item":    "  [DM:{handle}] {members}",
+
+	"error.dm_members":        "私聊群组需要 1 到 {max} 个其他成员",
+	"error.dm_not_found":      "没有找到私聊群组 {handle}",
+	"error.dm_already_member": "{name} 已经在私聊群组 {handle} 中",
+	"error.dm_not_member":     "{name} 不在私聊群组 {handle} 中",
+	"error.dm_full":           "私聊群组最多 {max} 人",
+	"error.dm_unavailable":    "无法与 {name} 私聊",
+	"error.dm_no_reply":       "还没有可以回复的私聊",
+
+	"event.pubkey": "{name} 的公钥指纹: {fingerprint}",
+	"event.sealed": "[加密私聊] {from} -> {to}: (需要支持端到端加密的客户端才能阅读)",
+
+	"pubkey.published": "已发布端到端加密公钥，指纹: {fingerprint}",
+	"pubkey.own":       "你的公钥指纹: {fingerprint}",
+	"pubkey.none":      "你还没有发布公钥",
+	"sealed.sent":      "[加密私聊] -> {to}: (已加密发送)",
+
+	"error.pubkey_invalid":   "公钥格式错误，需要 base64 编码的 X25519 加密公钥和 Ed25519 签名公钥",
+	"error.pubkey_missing":   "{name} 没有发布公钥，无法发送加密私聊",
+	"error.pubkey_required":  "请先用 \\pubkey 发布自己的公钥，对方需要用它验证签名",
+	"error.sealed_malformed": "加密私聊需要支持端到端加密的客户端，内容不是有效的密封信封",
+
+	"e2e.enabled":       "端到端加密已启用，公钥指纹: {fingerprint} (密钥文件 {path})",
+	"e2e.received":      "[加密私聊] {from} -> 你: {text}",
+	"e2e.bad_signature": "丢弃了来自 {from} 的加密私聊: 签名验证失败",
+	"e2e.undecryptable": "无法解密来自 {from} 的加密私聊",
+	"e2e.key_changed":   "警告: {name} 的公钥已改变，新指纹: {fingerprint}",
+	"e2e.seal_failed":   "加密失败: {error}",
+
+	// 入站 webhook 机器人
+	"message.bot":              "[机器人 {from}] {content}",
+	"export.bot":               "[机器人]",
+	"hook.created":             "已创建机器人 {name}（编号 {id}），在房间 #{room} 发言\n令牌只显示这一次，请妥善保存: {token}\n外部程序向 POST /hooks/{token} 发送消息",
+	"hook.http_disabled":       "注意: HTTP 接口未启用，使用 -http 指定监听地址后机器人才能发言",
+	"hook.removed":             "已删除机器人 {name}（编号 {id}），其令牌已失效",
+	"hook.none":                "还没有机器人",
+	"hook.list":                "共有 {count} 个机器人:",
+	"hook.item":                "  {id} {name} → #{room}（{creator} 创建于 {time}）",
+	"error.hook_usage":         "用法: \\hook add <名字> <房间> 或 \\hook remove <编号>",
+	"error.bot_not_found":      "没有编号为 {id} 的机器人",
+	"error.bot_token":          "无效的令牌",
+	"error.hook_empty":         "消息内容为空",
+	"error.hook_lines":         "一次最多发送 {max} 行",
+	"error.http_not_found":     "没有这个地址",
+	"error.http_method":        "只支持 {method} 请求",
+	"error.http_too_large":     "请求体超过 {max} 字节",
+	"error.http_body":          "无法读取请求体，内容必须是 UTF-8 文本",
+	"error.http_json":          "无效的 JSON 请求体",
+	"error.http_internal":      "服务器内部错误",
+	"forge.push":               "[{repo}] {user} 向 {branch} 推送了 {count} 个提交: {title} {url}",
+	"forge.tag":                "[{repo}] {user} 推送了标签 {tag} {url}",
+	"forge.tag_deleted":        "[{repo}] {user} 删除了标签 {tag}",
+	"forge.branch_deleted":     "[{repo}] {user} 删除了分支 {branch}",
+	"forge.branch_updated":     "[{repo}] {user} 更新了分支 {branch} {url}",
+	"forge.change":             "[{repo}] {user} {verb}{noun} {ref}: {title} {url}",
+	"forge.verb.opened":        "打开了",
+	"forge.verb.closed":        "关闭了",
+	"forge.verb.merged":        "合并了",
+	"forge.verb.reopened":      "重新打开了",
+	"forge.noun.pull_request":  "拉取请求",
+	"forge.noun.merge_request": "合并请求",
+	"forge.noun.issue":         "议题",
+	"forge.pipeline":           "[{repo}] {branch} 上的流水线 {name} {status} {url}",
+	"forge.status.success":     "成功",
+	"forge.status.failed":      "失败",
+	"forge.status.canceled":    "已取消",
+	"error.forge_signature":    "签名验证失败，或者没有为这个仓库配置路由",
+	"error.room_not_found":     "房间 #{room} 不存在",
+	"error.stream_room":        "#{room} 不是公开房间，不能订阅",
+	"error.stream_disabled":    "只读事件流未启用",
+	"error.stream_token":       "缺少或无效的访问令牌",
+	"error.api_disabled":       "REST 接口未启用",
+	"error.api_token":          "缺少或无效的访问令牌",
+	"error.api_session":        "会话无效或已过期，请重新创建会话",
+	"error.api_room":           "#{room} 不是公开房间，只有其中的用户可以读取",
+	"error.api_empty":          "缺少接收者或内容",
+	"error.api_since":          "无效的序号: {value}",
+	"error.api_wait":           "无效的等待时间: {value}，例如 30s",
+	"error.api_tombstones":     "无效的 tombstones 参数: {value}，应为 true 或 false",
+}
@@ -12,11 +12,24 @@ import (
 func main() {
 	// 解析命令行参数
 	var (
-		host     = flag.String("host", "127.0.0.1", "服务器监听地址")
-		port     = flag.Int("port", 8080, "服务器监听端口")
-		maxUsers = flag.Int("max-users", 100, "最大用户数")
-		timeout  = flag.Int("timeout", 40, "用户超时时间(秒)")
-		help     = flag.Bool("help", false, "显示帮助信息")
+		host      = flag.String("host", "127.0.0.1", "服务器监听地址")
+		port      = flag.Int("port", 8080, "服务器监听端口")
+		maxUsers  = flag.Int("max-users", 100, "最大用户数")
+		timeout   = flag.Int("timeout", 40, "用户超时时间(秒)")
+		storeName = flag.String("store", "memory", "状态后端 (memory 或 redis)")
+		redisAddr = flag.String("redis-addr", "127.0.0.1:6379", "Redis地址")
+		lang      = flag.String("lang", "zh-CN", "默认界面语言 (zh-CN 或 en-US)")
+		filter    = flag.String("filter", "", "内容过滤规则文件")
+		motdFile  = flag.String("motd", "", "每日消息文件")
+		webhooks  = flag.String("webhooks", "", "webhook 配置文件")
+		httpAddr  = flag.String("http", "", "HTTP接口监听地址，如 127.0.0.1:8081")
+		repos     = flag.String("repos", "", "GitHub/GitLab 仓库事件路由文件")
+		exportTo  = flag.String("export", "", "导出指定房间的聊天记录后退出")
+		since     = flag.String("export-since", "", "导出的起始时间 (如 24h、7d、2006-01-02)")
+		format    = flag.String("export-format", "md", "导出格式 (md、html 或 jsonl)")
+		exportFor = flag.String("export-user", "", "同时导出该用户参与的私聊")
+		output    = flag.String("export-out", "", "导出文件路径，默认写入导出目录")
+		help      = flag.Bool("help", false, "显示帮助信息")
 	)
 	flag.Parse()
 
@@ -31,6 +44,14 @@ func main() {
 	cfg.Port = *port
 	cfg.MaxUsers = *maxUsers
 	cfg.Timeout = *timeout
+	cfg.Store = *storeName
+	cfg.RedisAddr = *redisAddr
+	cfg.Language = *lang
+	cfg.FilterFile = *filter
+	cfg.MOTDFile = *motdFile
+	cfg.WebhooksFile = *webhooks
+	cfg.HTTPAddr = *httpAddr
+	cfg.ReposFile = *repos
 
 	// 从环境变量加载配置
 	cfg.LoadFromEnv()
@@ -41,8 +62,29 @@ func main() {
 		os.Exit(1)
 	}
 
+	// 导出聊天记录
+	if *exportTo != "" {
+		path, count, err := server.ExportTranscript(cfg, server.ExportOptions{
+			Room:        *exportTo,
+			Since:       *since,
+			Format:      *format,
+			Participant: *exportFor,
+			Output:      *output,
+		})
+		if err != nil {
+			fmt.Printf("导出失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("已导出 %d 条消息到 %s\n", count, path)
+		return
+	}
+
 	// 创建并启动服务器
-	chatServer := server.NewChatServer(cfg)
+	chatServer, err := server.NewChatServer(cfg)
+	if err != nil {
+		fmt.Printf("服务器创建失败: %v\n", err)
+		os.Exit(1)
+	}
 
 	fmt.Println("=== Go聊天室服务器 ===")
 	fmt.Printf("监听地址: %s\n", cfg.GetAddress())
@@ -73,6 +115,33 @@ func showHelp() {
 	fmt.Println("        最大用户数 (默认: 100)")
 	fmt.Println("  -timeout int")
 	fmt.Println("        用户超时时间，单位秒 (默认: 40)")
+	fmt.Println("  -store string")
+	fmt.Println("        状态后端，memory 或 redis (默认: memory)")
+	fmt.Println("  -redis-addr string")
+	fmt.Println("        Redis地址 (默认: 127.0.0.1:6379)")
+	fmt.Println("  -lang string")
+	fmt.Println("        默认界面语言，zh-CN 或 en-US (默认: zh-CN)")
+	fmt.Println("  -filter string")
+	fmt.Println("        内容过滤规则文件，修改后发送 SIGHUP 或使用 \\filter reload 重新加载")
+	fmt.Println("  -motd string")
+	fmt.Println("        每日消息文件，支持 {name}、{online}、{uptime}、{time} 占位符，修改后发送 SIGHUP 或使用 \\motd reload 重新加载")
+	fmt.Println("  -webhooks string")
+	fmt.Println("        webhook 配置文件，每行为 \"<URL> <密钥> <条件>...\"，修改后发送 SIGHUP 重新加载")
+	fmt.Println("  -http string")
+	fmt.Println("        HTTP接口监听地址，提供入站 webhook (POST /hooks/<令牌>)，机器人由管理员用 \\hook 管理 (默认: 不启用)")
+	fmt.Println("  -repos string")
+	fmt.Println("        仓库事件路由文件，每行为 \"<github|gitlab> <仓库> <密钥> <房间> [事件种类...]\"，")
+	fmt.Println("        平台的 webhook 地址为 HTTP 接口的 /repos/github 或 /repos/gitlab，修改后发送 SIGHUP 重新加载")
+	fmt.Println("  -export string")
+	fmt.Println("        导出指定房间的聊天记录后退出，需要与服务器使用同一个状态后端")
+	fmt.Println("  -export-since string")
+	fmt.Println("        导出的起始时间，如 24h、7d 或 2006-01-02 (默认: 全部)")
+	fmt.Println("  -export-format string")
+	fmt.Println("        导出格式，md、html 或 jsonl (默认: md)")
+	fmt.Println("  -export-user string")
+	fmt.Println("        同时导出该用户参与的私聊")
+	fmt.Println("  -export-out string")
+	fmt.Println("        导出文件路径 (默认: 写入导出目录)")
 	fmt.Println("  -help")
 	fmt.Println("        显示此帮助信息")
 	fmt.Println()
@@ -82,8 +151,21 @@ func showHelp() {
 	fmt.Println("  CHATROOM_MAX_USERS 最大用户数")
 	fmt.Println("  CHATROOM_TIMEOUT   用户超时时间")
 	fmt.Println("  CHATROOM_LOG_LEVEL 日志级别")
+	fmt.Println("  CHATROOM_STORE     状态后端 (memory 或 redis)")
+	fmt.Println("  CHATROOM_REDIS_ADDR Redis地址")
+	fmt.Println("  CHATROOM_HISTORY_SIZE 每个房间保留的历史消息数")
+	fmt.Println("  CHATROOM_LANG      默认界面语言")
+	fmt.Println("  CHATROOM_FILTER_FILE 内容过滤规则文件")
+	fmt.Println("  CHATROOM_MOTD_FILE 每日消息文件")
+	fmt.Println("  CHATROOM_WEBHOOKS_FILE webhook 配置文件")
+	fmt.Println("  CHATROOM_HTTP_ADDR HTTP接口监听地址")
+	fmt.Println("  CHATROOM_REPOS_FILE 仓库事件路由文件")
+	fmt.Println("  CHATROOM_STREAM_TOKEN 只读事件流的访问令牌，设置后启用 GET /rooms/<房间>/events")
+	fmt.Println("  CHATROOM_API_TOKEN 创建 REST 接口会话的访问令牌，设置后启用 REST 接口 (POST /sessions)")
+	fmt.Println("  CHATROOM_EXPORT_DIR 聊天记录导出目录")
 	fmt.Println()
 	fmt.Println("示例:")
 	fmt.Println("  chatroom -host 0.0.0.0 -port 9000 -max-users 50")
 	fmt.Println("  CHATROOM_PORT=9000 chatroom")
+	fmt.Println("  chatroom -store redis -export lobby -export-since 7d -export-format html")
 }
@@ -0,0 +1,498 @@
+package message
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"chatroom/i18n"
+)
+
+// MaxSendSize \send 命令允许声明的最大文件大小（字节）
+//
+// 附件被拒绝时服务器仍要读完声明大小的内容以保持协议同步，这里限制客户端能让服务器读取的上限。
+const MaxSendSize = 64 * 1024 * 1024
+
+// ArgType 命令参数类型
+type ArgType int
+
+const (
+	ArgWord ArgType = iota // 单个参数，可以用引号包含空格
+	ArgID                  // 消息或附件ID，允许带 # 前缀，统一转为小写
+	ArgInt                 // 整数，可以限制取值范围
+	ArgEnum                // 枚举值，不区分大小写
+	ArgRest                // 行的剩余部分，原样保留空格和引号
+	ArgList                // 剩余的所有参数，逐个分词后保存
+)
+
+// ArgField 参数保存到 Command 的哪个字段
+type ArgField int
+
+const (
+	FieldContent ArgField = iota // Command.Content
+	FieldTarget                  // Command.Target
+	FieldSize                    // Command.Size
+	FieldOption                  // 追加到 Command.Options，由处理方按取值识别含义
+)
+
+// ArgSpec 命令参数定义
+type ArgSpec struct {
+	Name     string   // 参数名，用于生成用法说明
+	Type     ArgType  // 参数类型
+	Field    ArgField // 保存到的字段
+	Optional bool     // 是否可选（可选参数只能出现在必选参数之后）
+	Choices  []string // 枚举值（仅 ArgEnum）
+	Min, Max int64    // 取值范围（仅 ArgInt），Max 为0表示不限制
+}
+
+// usage 返回参数的用法说明，如 <name>、[code]、<message...>
+func (a ArgSpec) usage() string {
+	name := a.Name
+	if a.Type == ArgEnum {
+		name = strings.Join(a.Choices, "|")
+	}
+	if a.Type == ArgRest || a.Type == ArgList {
+		name += "..."
+	}
+	if a.Optional {
+		return "[" + name + "]"
+	}
+	return "<" + name + ">"
+}
+
+// CommandSpec 命令定义
+//
+// 命令的解析、参数校验和帮助信息都由命令表驱动。帮助文本使用本地化键
+// cmd.<Name>（一行简介）和可选的 cmd.<Name>.detail（详细说明）。
+type CommandSpec struct {
+	Name    string      // 命令名（不含反斜杠）
+	Aliases []string    // 别名
+	Type    CommandType // 命令类型
+	Args    []ArgSpec   // 参数定义
+	Role    string      // 需要的用户角色，为空表示所有人可用
+}
+
+// Usage 返回命令的用法说明
+func (s *CommandSpec) Usage() string {
+	parts := []string{"\\" + s.Name}
+	for _, arg := range s.Args {
+		parts = append(parts, arg.usage())
+	}
+	return strings.Join(parts, " ")
+}
+
+// commandTable 命令表，帮助信息按此顺序列出
+var commandTable = []*CommandSpec{
+	{Name: "who", Type: CmdWho},
+	{Name: "whois", Type: CmdWhois, Args: []ArgSpec{
+		{Name: "name", Field: FieldTarget},
+	}},
+	{Name: "rename", Type: CmdRename, Args: []ArgSpec{
+		{Name: "name"},
+	}},
+	{Name: "whisper", Aliases: []string{"w"}, Type: CmdWhisper, Args: []ArgSpec{
+		{Name: "name", Field: FieldTarget},
+		{Name: "message", Type: ArgRest},
+	}},
+	{Name: "dm", Type: CmdDM, Args: []ArgSpec{
+		{Name: "users|handle|action", Field: FieldTarget},
+		{Name: "text", Type: ArgRest, Optional: true},
+	}},
+	{Name: "r", Type: CmdRespond, Args: []ArgSpec{
+		{Name: "text", Type: ArgRest},
+	}},
+	{Name: "pubkey", Type: CmdPubKey, Args: []ArgSpec{
+		{Name: "name|key", Field: FieldTarget, Optional: true},
+		{Name: "signkey", Optional: true},
+	}},
+	{Name: "ewhisper", Aliases: []string{"ew"}, Type: CmdSealed, Args: []ArgSpec{
+		{Name: "name", Field: FieldTarget},
+		{Name: "sealed"},
+	}},
+	{Name: "time", Type: CmdTime},
+	{Name: "stats", Type: CmdStats},
+	{Name: "lang", Type: CmdLang, Args: []ArgSpec{
+		{Name: "code", Optional: true},
+	}},
+	{Name: "format", Type: CmdFormat, Args: []ArgSpec{
+		{Name: "mode", Type: ArgEnum, Choices: []string{"text", "json"}},
+	}},
+	{Name: "ids", Type: CmdIDs, Args: []ArgSpec{
+		{Name: "state", Type: ArgEnum, Choices: []string{"on", "off"}},
+	}},
+	{Name: "edit", Type: CmdEdit, Args: []ArgSpec{
+		{Name: "id", Type: ArgID, Field: FieldTarget},
+		{Name: "text", Type: ArgRest},
+	}},
+	{Name: "delete", Type: CmdDelete, Args: []ArgSpec{
+		{Name: "id", Type: ArgID, Field: FieldTarget},
+	}},
+	{Name: "react", Type: CmdReact, Args: []ArgSpec{
+		{Name: "id", Type: ArgID, Field: FieldTarget},
+		{Name: "emoji"},
+	}},
+	{Name: "unreact", Type: CmdUnreact, Args: []ArgSpec{
+		{Name: "id", Type: ArgID, Field: FieldTarget},
+		{Name: "emoji", Optional: true},
+	}},
+	{Name: "reactions", Type: CmdReactions, Args: []ArgSpec{
+		{Name: "id", Type: ArgID, Field: FieldTarget},
+	}},
+	{Name: "reply", Type: CmdReply, Args: []ArgSpec{
+		{Name: "id", Type: ArgID, Field: FieldTarget},
+		{Name: "text", Type: ArgRest},
+	}},
+	{Name: "thread", Type: CmdThread, Args: []ArgSpec{
+		{Name: "id", Type: ArgID, Field: FieldTarget},
+	}},
+	{Name: "send", Type: CmdSend, Args: []ArgSpec{
+		{Name: "user|room", Field: FieldTarget},
+		{Name: "file"},
+		{Name: "size", Type: ArgInt, Field: FieldSize, Max: MaxSendSize},
+	}},
+	{Name: "join", Type: CmdJoin, Args: []ArgSpec{
+		{Name: "room", Field: FieldTarget},
+		{Name: "key", Optional: true},
+	}},
+	{Name: "leave", Aliases: []string{"part"}, Type: CmdLeave},
+	{Name: "rooms", Type: CmdRooms},
+	{Name: "topic", Type: CmdTopic, Args: []ArgSpec{
+		{Name: "text", Type: ArgRest, Optional: true},
+	}},
+	{Name: "mode", Type: CmdMode, Args: []ArgSpec{
+		{Name: "mode", Type: ArgEnum, Choices: []string{"public", "private", "invite", "password"}, Optional: true},
+		{Name: "key", Field: FieldOption, Optional: true},
+	}},
+	{Name: "invite", Type: CmdInvite, Args: []ArgSpec{
+		{Name: "name", Field: FieldTarget},
+	}},
+	{Name: "roomop", Type: CmdRoomOp, Args: []ArgSpec{
+		{Name: "name", Field: FieldTarget},
+	}},
+	{Name: "motd", Type: CmdMOTD, Args: []ArgSpec{
+		{Name: "action", Type: ArgEnum, Choices: []string{"reload"}, Optional: true},
+	}},
+	{Name: "search", Type: CmdSearch, Args: []ArgSpec{
+		{Name: "query", Type: ArgRest},
+	}},
+	{Name: "export", Type: CmdExport, Args: []ArgSpec{
+		{Name: "room", Field: FieldOption, Optional: true},
+		{Name: "since", Field: FieldOption, Optional: true},
+		{Name: "format", Field: FieldOption, Optional: true},
+	}},
+	{Name: "get", Type: CmdGet, Args: []ArgSpec{
+		{Name: "id", Type: ArgID, Field: FieldTarget},
+	}},
+	{Name: "away", Type: CmdAway, Args: []ArgSpec{
+		{Name: "message", Type: ArgRest, Optional: true},
+	}},
+	{Name: "busy", Type: CmdBusy, Args: []ArgSpec{
+		{Name: "message", Type: ArgRest, Optional: true},
+	}},
+	{Name: "back", Type: CmdBack},
+	{Name: "ignore", Type: CmdIgnore, Args: []ArgSpec{
+		{Name: "name", Field: FieldTarget},
+	}},
+	{Name: "unignore", Type: CmdUnignore, Args: []ArgSpec{
+		{Name: "name", Field: FieldTarget},
+	}},
+	{Name: "ignores", Type: CmdIgnores},
+	{Name: "poll", Type: CmdPoll, Args: []ArgSpec{
+		{Name: "question|close", Field: FieldOption},
+		{Name: "option|id", Field: FieldOption},
+		{Name: "option", Type: ArgList, Field: FieldOption, Optional: true},
+	}},
+	{Name: "vote", Type: CmdVote, Args: []ArgSpec{
+		{Name: "id", Type: ArgID, Field: FieldTarget},
+		{Name: "n"},
+	}},
+	{Name: "remind", Type: CmdRemind, Args: []ArgSpec{
+		{Name: "when", Field: FieldTarget},
+		{Name: "text", Type: ArgRest},
+	}},
+	{Name: "schedule", Type: CmdSchedule, Args: []ArgSpec{
+		{Name: "when", Field: FieldTarget},
+		{Name: "text", Type: ArgRest},
+	}},
+	{Name: "reminders", Type: CmdReminders, Args: []ArgSpec{
+		{Name: "action", Type: ArgEnum, Choices: []string{"cancel"}, Optional: true},
+		{Name: "id", Type: ArgID, Field: FieldTarget, Optional: true},
+	}},
+	{Name: "oper", Type: CmdOper, Args: []ArgSpec{
+		{Name: "password"},
+	}},
+	{Name: "filter", Type: CmdFilter, Role: "operator", Args: []ArgSpec{
+		{Name: "action", Type: ArgEnum, Choices: []string{"reload"}, Optional: true},
+	}},
+	{Name: "hook", Type: CmdHook, Role: "operator", Args: []ArgSpec{
+		{Name: "action", Type: ArgEnum, Choices: []string{"list", "add", "remove"}, Optional: true},
+		{Name: "bot|id", Field: FieldTarget, Optional: true},
+		{Name: "room", Field: FieldOption, Optional: true},
+	}},
+	{Name: "help", Type: CmdHelp, Args: []ArgSpec{
+		{Name: "command", Optional: true},
+	}},
+	{Name: "quit", Aliases: []string{"exit"}, Type: CmdQuit},
+}
+
+// commandIndex 命令名和别名 -> 命令定义
+var commandIndex = buildCommandIndex(commandTable)
+
+// buildCommandIndex 建立命令名索引，命令表中有重复名字时直接panic
+func buildCommandIndex(table []*CommandSpec) map[string]*CommandSpec {
+	index := make(map[string]*CommandSpec)
+	for _, spec := range table {
+		for _, name := range append([]string{spec.Name}, spec.Aliases...) {
+			if _, exists := index[name]; exists {
+				panic(fmt.Sprintf("命令表中重复的命令名: %s", name))
+			}
+			index[name] = spec
+		}
+	}
+	return index
+}
+
+// LookupCommand 按命令名或别名查找命令定义，名字可以带反斜杠前缀
+func LookupCommand(name string) (*CommandSpec, bool) {
+	spec, exists := commandIndex[strings.ToLower(strings.TrimPrefix(name, "\\"))]
+	return spec, exists
+}
+
+// Commands 返回命令表
+func Commands() []*CommandSpec {
+	return commandTable
+}
+
+// CommandParser 命令解析器
+type CommandParser struct{}
+
+// NewCommandParser 创建新的命令解析器
+func NewCommandParser() *CommandParser {
+	return &CommandParser{}
+}
+
+// ParseCommand 解析用户输入的命令
+//
+// 以反斜杠开头的输入是命令，参数之间用空白分隔，可以用单引号或双引号包含空格，
+// 引号外和双引号内可以用反斜杠转义下一个字符。以两个反斜杠开头的输入作为聊天
+// 消息发送（去掉一个反斜杠），例如 \\who 发送文字 \who。
+func (cp *CommandParser) ParseCommand(input string) (Command, error) {
+	input = strings.TrimSpace(input) // 去除空格
+
+	if !strings.HasPrefix(input, "\\") { // 判断是否以\开头
+		return Command{Type: CmdChat, Content: input}, nil
+	}
+	if strings.HasPrefix(input, "\\\\") {
+		return Command{Type: CmdChat, Content: input[1:]}, nil
+	}
+
+	name, rest := splitCommandName(input)
+	if name == "\\" {
+		return Command{}, i18n.Errorf("error.invalid_command", nil)
+	}
+	spec, exists := LookupCommand(name)
+	if !exists {
+		return Command{}, i18n.Errorf("error.unknown_command", i18n.Params{"command": strings.ToLower(name)})
+	}
+
+	cmd := Command{Type: spec.Type, Spec: spec}
+	s := &argScanner{input: rest}
+	for _, arg := range spec.Args {
+		var value string
+		var present bool
+		if arg.Type == ArgList {
+			count, err := cmd.setList(spec, arg, s)
+			if err != nil {
+				return Command{}, err
+			}
+			if count == 0 && !arg.Optional {
+				return Command{}, i18n.Errorf("error.missing_arg", i18n.Params{"arg": arg.usage(), "usage": spec.Usage()})
+			}
+			continue
+		}
+		if arg.Type == ArgRest {
+			value = s.rest()
+			present = value != ""
+		} else {
+			var err error
+			if value, present, err = s.word(); err != nil {
+				return Command{}, err
+			}
+		}
+		if !present {
+			if arg.Optional {
+				continue
+			}
+			return Command{}, i18n.Errorf("error.missing_arg", i18n.Params{"arg": arg.usage(), "usage": spec.Usage()})
+		}
+		if err := cmd.set(spec, arg, value); err != nil {
+			return Command{}, err
+		}
+	}
+	if s.rest() != "" {
+		return Command{}, i18n.Errorf("error.extra_args", i18n.Params{"usage": spec.Usage()})
+	}
+	return cmd, nil
+}
+
+// splitCommandName 分离命令名和参数部分
+func splitCommandName(input string) (string, string) {
+	end := strings.IndexFunc(input, unicode.IsSpace)
+	if end < 0 {
+		return input, ""
+	}
+	return input[:end], input[end:]
+}
+
+// setList 读取剩余的所有参数并逐个保存，返回参数个数
+func (cmd *Command) setList(spec *CommandSpec, arg ArgSpec, s *argScanner) (int, error) {
+	count := 0
+	for {
+		value, present, err := s.word()
+		if err != nil || !present {
+			return count, err
+		}
+		if err := cmd.set(spec, arg, value); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// set 校验参数值并保存到对应字段
+func (cmd *Command) set(spec *CommandSpec, arg ArgSpec, value string) error {
+	invalid := i18n.Errorf("error.invalid_arg", i18n.Params{"arg": arg.usage(), "value": value, "usage": spec.Usage()})
+
+	switch arg.Type {
+	case ArgID:
+		value = strings.ToLower(strings.TrimPrefix(value, "#"))
+	case ArgEnum:
+		value = strings.ToLower(value)
+		valid := false
+		for _, choice := range arg.Choices {
+			if value == choice {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return invalid
+		}
+	case ArgInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < arg.Min || (arg.Max > 0 && n > arg.Max) {
+			return invalid
+		}
+		if arg.Field == FieldSize {
+			cmd.Size = n
+			return nil
+		}
+	}
+
+	switch arg.Field {
+	case FieldTarget:
+		cmd.Target = value
+	case FieldOption:
+		cmd.Options = append(cmd.Options, value)
+	default:
+		cmd.Content = value
+	}
+	return nil
+}
+
+// argScanner 命令参数分词器
+type argScanner struct {
+	input string // 尚未解析的输入
+}
+
+// word 读取下一个参数，没有更多参数时 present 为false
+func (s *argScanner) word() (value string, present bool, err error) {
+	s.input = strings.TrimLeftFunc(s.input, unicode.IsSpace)
+	if s.input == "" {
+		return "", false, nil
+	}
+
+	var b strings.Builder
+	var quote rune
+	for i := 0; i < len(s.input); {
+		c, size := utf8.DecodeRuneInString(s.input[i:])
+		switch {
+		case quote == 0 && unicode.IsSpace(c):
+			s.input = s.input[i:]
+			return b.String(), true, nil
+		case c == '\\' && quote != '\'' && i+size < len(s.input):
+			// 反斜杠转义下一个字符（单引号内不转义）
+			next, nextSize := utf8.DecodeRuneInString(s.input[i+size:])
+			b.WriteRune(next)
+			i += size + nextSize
+			continue
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote != 0 && c == quote:
+			quote = 0
+		default:
+			b.WriteRune(c)
+		}
+		i += size
+	}
+	if quote != 0 {
+		return "", false, i18n.Errorf("error.unterminated_quote", nil)
+	}
+	s.input = ""
+	return b.String(), true, nil
+}
+
+// rest 读取剩余的全部输入（去掉开头的空白），原样返回
+func (s *argScanner) rest() string {
+	rest := strings.TrimLeftFunc(s.input, unicode.IsSpace)
+	s.input = ""
+	return rest
+}
+
+// GetHelpMessage 根据命令表生成帮助信息
+func GetHelpMessage(lang string) string {
+	width := 0
+	for _, spec := range commandTable {
+		if w := utf8.RuneCountInString(spec.Usage()); w > width {
+			width = w
+		}
+	}
+
+	lines := []string{i18n.T(lang, "help.header", nil)}
+	for _, spec := range commandTable {
+		usage := spec.Usage()
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(usage))
+		lines = append(lines, "  "+usage+padding+" - "+i18n.T(lang, "cmd."+spec.Name, nil))
+	}
+	lines = append(lines, i18n.T(lang, "help.footer", nil))
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// GetCommandHelp 生成单个命令的详细帮助
+func GetCommandHelp(lang, name string) (string, error) {
+	spec, exists := LookupCommand(name)
+	if !exists {
+		return "", i18n.Errorf("error.unknown_command", i18n.Params{"command": "\\" + strings.TrimPrefix(name, "\\")})
+	}
+
+	lines := []string{
+		i18n.T(lang, "help.usage", i18n.Params{"usage": spec.Usage()}),
+		i18n.T(lang, "cmd."+spec.Name, nil),
+	}
+	if len(spec.Aliases) > 0 {
+		aliases := make([]string, len(spec.Aliases))
+		for i, alias := range spec.Aliases {
+			aliases[i] = "\\" + alias
+		}
+		lines = append(lines, i18n.T(lang, "help.aliases", i18n.Params{"aliases": strings.Join(aliases, ", ")}))
+	}
+	if spec.Role != "" {
+		lines = append(lines, i18n.T(lang, "help.role", i18n.Params{"role": i18n.T(lang, "role."+spec.Role, nil)}))
+	}
+	if detail := "cmd." + spec.Name + ".detail"; i18n.Has(lang, detail) {
+		lines = append(lines, i18n.T(lang, detail, nil))
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
@@ -0,0 +1,82 @@
+package message
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseCommand(t *testing.T) {
+	parser := NewCommandParser()
+	tests := []struct {
+		input   string
+		typ     CommandType
+		target  string
+		content string
+	}{
+		{"大家好", CmdChat, "", "大家好"},
+		{`\\who 不是命令`, CmdChat, "", `\who 不是命令`},
+		{`\w 张三 晚上 一起吃饭`, CmdWhisper, "张三", "晚上 一起吃饭"},
+		{`\w "张 三" 你好`, CmdWhisper, "张 三", "你好"},
+		{`\W 张三 大写命令名`, CmdWhisper, "张三", "大写命令名"},
+	}
+	for _, tt := range tests {
+		cmd, err := parser.ParseCommand(tt.input)
+		if err != nil {
+			t.Errorf("ParseCommand(%q): %v", tt.input, err)
+			continue
+		}
+		if cmd.Type != tt.typ || cmd.Target != tt.target || cmd.Content != tt.content {
+			t.Errorf("ParseCommand(%q) = {%v %q %q}, want {%v %q %q}",
+				tt.input, cmd.Type, cmd.Target, cmd.Content, tt.typ, tt.target, tt.content)
+		}
+	}
+
+	for _, input := range []string{`\`, `\nosuchcommand`, `\w`, `\w "未结束的引号`} {
+		if _, err := parser.ParseCommand(input); err == nil {
+			t.Errorf("ParseCommand(%q) 应返回错误", input)
+		}
+	}
+}
+
+func FuzzParseCommand(f *testing.F) {
+	for _, seed := range []string{"你好", `\help`, `\w 张三 hi`, `\topic "a b" c`, `\send room a.txt 10`, `\\x`} {
+		f.Add(seed)
+	}
+	parser := NewCommandParser()
+	f.Fuzz(func(t *testing.T, input string) {
+		cmd, err := parser.ParseCommand(input)
+		if err != nil {
+			return
+		}
+		trimmed := strings.TrimSpace(input)
+		if !strings.HasPrefix(trimmed, `\`) {
+			if cmd.Type != CmdChat || cmd.Content != trimmed {
+				t.Fatalf("ParseCommand(%q) = %+v, 非命令输入应原样作为聊天内容", input, cmd)
+			}
+			return
+		}
+		if cmd.Spec != nil && cmd.Type != cmd.Spec.Type {
+			t.Fatalf("ParseCommand(%q) 类型 %v 与命令定义 %v 不一致", input, cmd.Type, cmd.Spec.Type)
+		}
+		if utf8.ValidString(input) {
+			for _, field := range append([]string{cmd.Content, cmd.Target}, cmd.Options...) {
+				if !utf8.ValidString(field) {
+					t.Fatalf("ParseCommand(%q) 产生了无效的UTF-8: %q", input, field)
+				}
+			}
+		}
+	})
+}
+
+func TestParseSendSize(t *testing.T) {
+	parser := NewCommandParser()
+	cmd, err := parser.ParseCommand(`\send room a.txt ` + strconv.Itoa(MaxSendSize))
+	if err != nil || cmd.Size != MaxSendSize {
+		t.Fatalf("上限大小应该被接受: %+v, %v", cmd, err)
+	}
+	if _, err := parser.ParseCommand(`\send room a.txt ` + strconv.Itoa(MaxSendSize+1)); err == nil {
+		t.Fatal("超过上限的大小应该被拒绝")
+	}
+}
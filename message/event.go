@@ -0,0 +1,166 @@
+package message
+
+import (
+	"encoding/json"
+
+	"chatroom/i18n"
+)
+
+// EventType 事件类型
+type EventType string
+
+const (
+	EventMessage  EventType = "message"  // 新消息
+	EventEdit     EventType = "edit"     // 消息被编辑
+	EventDelete   EventType = "delete"   // 消息被删除
+	EventNotice   EventType = "notice"   // 系统提示
+	EventReact    EventType = "react"    // 表情回应变化
+	EventPresence EventType = "presence" // 在线状态变化
+	EventKey      EventType = "pubkey"   // 公钥查询结果
+	EventSealed   EventType = "sealed"   // 端到端加密私聊
+)
+
+// Event 投递给客户端的事件
+//
+// 文本模式的客户端看到的是按语言格式化后的文本，
+// 结构化(JSON)模式的客户端每个事件收到一行JSON。
+type Event struct {
+	Type    EventType `json:"event"`             // 事件类型
+	Message *Message  `json:"message,omitempty"` // 相关消息
+	By      string    `json:"by,omitempty"`      // 操作者
+	Text    string    `json:"text,omitempty"`    // 提示文本（不含换行）
+
+	Reaction *Reaction `json:"reaction,omitempty"` // 表情回应变化
+	Presence *Presence `json:"presence,omitempty"` // 在线状态变化
+	Key      *Key      `json:"key,omitempty"`      // 公钥
+	Sealed   *Sealed   `json:"sealed,omitempty"`   // 密封信封
+}
+
+// Key 用户发布的端到端加密公钥
+type Key struct {
+	User        string `json:"user"`        // 用户名
+	Box         string `json:"box"`         // X25519 加密公钥 (base64)
+	Sign        string `json:"sign"`        // Ed25519 签名公钥 (base64)
+	Fingerprint string `json:"fingerprint"` // 公钥指纹
+}
+
+// Sealed 服务器原样转发的端到端加密私聊，只有接收者的客户端能解密
+type Sealed struct {
+	From string `json:"from"` // 发送者用户名
+	To   string `json:"to"`   // 接收者用户名
+	Box  string `json:"box"`  // 密封信封
+}
+
+// Presence 在线状态变化
+type Presence struct {
+	User    string `json:"user"`              // 用户名
+	State   string `json:"state"`             // 新状态
+	Message string `json:"message,omitempty"` // 状态留言
+}
+
+// Reaction 表情回应变化
+type Reaction struct {
+	Emoji string `json:"emoji"` // 表情
+	Count int    `json:"count"` // 变化后的回应人数
+	Added bool   `json:"added"` // 是添加还是移除
+}
+
+// NewMessageEvent 创建新消息事件
+func NewMessageEvent(msg *Message) *Event {
+	return &Event{Type: EventMessage, Message: msg}
+}
+
+// NewEditEvent 创建消息编辑事件
+func NewEditEvent(msg *Message, by string) *Event {
+	return &Event{Type: EventEdit, Message: msg, By: by}
+}
+
+// NewDeleteEvent 创建消息删除事件
+func NewDeleteEvent(msg *Message, by string) *Event {
+	return &Event{Type: EventDelete, Message: msg, By: by}
+}
+
+// NewReactionEvent 创建表情回应事件
+func NewReactionEvent(msg *Message, by, emoji string, added bool) *Event {
+	return &Event{
+		Type:    EventReact,
+		Message: msg,
+		By:      by,
+		Reaction: &Reaction{
+			Emoji: emoji,
+			Count: len(msg.Reactions[emoji]),
+			Added: added,
+		},
+	}
+}
+
+// NewPresenceEvent 创建在线状态变化事件
+func NewPresenceEvent(name, state, statusMessage string) *Event {
+	return &Event{
+		Type:     EventPresence,
+		By:       name,
+		Presence: &Presence{User: name, State: state, Message: statusMessage},
+	}
+}
+
+// NewKeyEvent 创建公钥查询结果事件
+func NewKeyEvent(key Key) *Event {
+	return &Event{Type: EventKey, Key: &key}
+}
+
+// NewSealedEvent 创建端到端加密私聊事件
+func NewSealedEvent(from, to, box string) *Event {
+	return &Event{Type: EventSealed, By: from, Sealed: &Sealed{From: from, To: to, Box: box}}
+}
+
+// NewNoticeEvent 创建系统提示事件
+func NewNoticeEvent(text string) *Event {
+	return &Event{Type: EventNotice, Text: text}
+}
+
+// FormatText 按语言把事件格式化为文本
+func (e *Event) FormatText(lang string, showID bool) string {
+	switch e.Type {
+	case EventMessage:
+		return e.Message.FormatMessage(lang, showID)
+	case EventEdit:
+		return i18n.T(lang, "event.edit", i18n.Params{
+			"id": e.Message.ID, "from": e.Message.From, "content": e.Message.Content,
+		}) + "\n"
+	case EventDelete:
+		return i18n.T(lang, "event.delete", i18n.Params{
+			"id": e.Message.ID, "from": e.Message.From, "by": e.By,
+		}) + "\n"
+	case EventReact:
+		return i18n.T(lang, "event.react", i18n.Params{
+			"id": e.Message.ID, "emoji": e.Reaction.Emoji, "count": e.Reaction.Count,
+		}) + "\n"
+	case EventPresence:
+		key := "event.presence"
+		if e.Presence.Message != "" {
+			key = "event.presence_message"
+		}
+		return i18n.T(lang, key, i18n.Params{
+			"name":     e.Presence.User,
+			"presence": i18n.T(lang, "presence."+e.Presence.State, nil),
+			"message":  e.Presence.Message,
+		}) + "\n"
+	case EventKey:
+		return i18n.T(lang, "event.pubkey", i18n.Params{
+			"name": e.Key.User, "fingerprint": e.Key.Fingerprint,
+		}) + "\n"
+	case EventSealed:
+		return i18n.T(lang, "event.sealed", i18n.Params{"from": e.Sealed.From, "to": e.Sealed.To}) + "\n"
+	default:
+		return e.Text + "\n"
+	}
+}
+
+// FormatJSON 把事件格式化为一行JSON
+func (e *Event) FormatJSON() string {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "{}\n"
+	}
+	return string(data) + "\n"
+}
@@ -1,9 +1,14 @@
 package message
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"strconv"
 	"time"
+
+	"chatroom/i18n"
 )
 
 // MessageType 消息类型
@@ -17,18 +22,71 @@ const (
 	TypeBroadcast                    // 广播消息
 )
 
+// DefaultRoom 默认房间，历史记录按房间保存
+const DefaultRoom = "lobby"
+
+// PrivateRoom 保存私聊消息的历史记录房间，导出时按参与者筛选
+const PrivateRoom = "@private"
+
 // Message 消息结构体
 type Message struct {
-	Type      MessageType // 消息类型
-	From      string      // 发送者
-	To        string      // 目标用户
-	Content   string      // 消息内容
-	Timestamp time.Time   // 时间戳
+	ID        string      `json:"id"`                // 消息ID
+	Seq       int64       `json:"seq,omitempty"`     // 房间内的序号，保存到历史记录时分配
+	Type      MessageType `json:"type"`              // 消息类型
+	From      string      `json:"from"`              // 发送者
+	FromID    string      `json:"from_id,omitempty"` // 发送者用户ID
+	To        string      `json:"to,omitempty"`      // 目标用户
+	ToID      string      `json:"to_id,omitempty"`   // 目标用户ID（私聊）
+	Room      string      `json:"room,omitempty"`    // 所在房间，为空表示所有房间（如全服广播）
+	Content   string      `json:"content"`           // 消息内容
+	Timestamp time.Time   `json:"timestamp"`         // 时间戳
+	Edited    bool        `json:"edited,omitempty"`  // 是否已编辑
+	Deleted   bool        `json:"deleted,omitempty"` // 是否已删除
+	Bot       bool        `json:"bot,omitempty"`     // 是否由机器人通过入站 webhook 发送
+
+	Reactions map[string][]string `json:"reactions,omitempty"` // 表情回应 (表情 -> 回应者用户ID)
+	Reactors  map[string]string   `json:"reactors,omitempty"`  // 回应者用户ID -> 回应时的用户名，回应者离线后显示
+
+	ReplyTo    string `json:"reply_to,omitempty"`    // 回复的父消息ID
+	ReplyFrom  string `json:"reply_from,omitempty"`  // 父消息发送者
+	ReplyQuote string `json:"reply_quote,omitempty"` // 父消息内容摘录
+}
+
+// maxQuoteRunes 回复中引用父消息内容的最大字符数
+const maxQuoteRunes = 30
+
+// NewReply 创建回复消息，并记录父消息的发送者和内容摘录
+func NewReply(parent *Message, from, content string) *Message {
+	msg := NewMessage(TypeChat, from, content)
+	msg.ReplyTo = parent.ID
+	msg.ReplyFrom = parent.From
+
+	quote := []rune(parent.Content)
+	if len(quote) > maxQuoteRunes {
+		quote = append(quote[:maxQuoteRunes-1], '…')
+	}
+	msg.ReplyQuote = string(quote)
+	return msg
+}
+
+// IDBytes 消息ID的随机字节数，编码为12个十六进制字符
+const IDBytes = 6
+
+// NewID 生成随机的消息ID
+//
+// 消息ID有48位随机数，房间内保留的消息中重复的概率可以忽略。
+func NewID() string {
+	buf := make([]byte, IDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano()&0xffffffffffff, 16)
+	}
+	return hex.EncodeToString(buf)
 }
 
 // NewMessage 创建新消息
 func NewMessage(msgType MessageType, from, content string) *Message {
 	return &Message{
+		ID:        NewID(),
 		Type:      msgType,
 		From:      from,
 		Content:   content,
@@ -39,6 +97,7 @@ func NewMessage(msgType MessageType, from, content string) *Message {
 // NewPrivateMessage 创建私聊消息
 func NewPrivateMessage(from, to, content string) *Message {
 	return &Message{
+		ID:        NewID(),
 		Type:      TypePrivate,
 		From:      from,
 		To:        to,
@@ -50,77 +109,59 @@ func NewPrivateMessage(from, to, content string) *Message {
 // NewSystemMessage 创建系统消息
 func NewSystemMessage(content string) *Message {
 	return &Message{
+		ID:        NewID(),
 		Type:      TypeSystem,
 		Content:   content,
 		Timestamp: time.Now(),
 	}
 }
 
-// FormatMessage 格式化消息
-func (m *Message) FormatMessage() string {
+// FormatMessage 按语言格式化消息，showID 为真时在行首显示消息ID
+func (m *Message) FormatMessage(lang string, showID bool) string {
+	prefix := ""
+	if showID && m.ID != "" {
+		prefix = "#" + m.ID + " "
+	}
+
+	params := i18n.Params{"from": m.From, "to": m.To, "content": m.Content}
 	switch m.Type {
 	case TypeSystem:
-		return fmt.Sprintf("[系统] %s\n", m.Content)
+		return prefix + i18n.T(lang, "message.system", params) + "\n"
 	case TypeChat:
-		return fmt.Sprintf("[%s] %s\n", m.From, m.Content)
+		if m.ReplyTo != "" {
+			return prefix + i18n.T(lang, "message.reply", i18n.Params{
+				"from": m.From, "parent": m.ReplyFrom, "quote": m.ReplyQuote, "content": m.Content,
+			}) + "\n"
+		}
+		if m.Bot {
+			return prefix + i18n.T(lang, "message.bot", params) + "\n"
+		}
+		return prefix + fmt.Sprintf("[%s] %s\n", m.From, m.Content)
 	case TypePrivate:
-		return fmt.Sprintf("[私聊] %s -> %s: %s\n", m.From, m.To, m.Content)
+		return prefix + i18n.T(lang, "message.private", params) + "\n"
 	case TypeBroadcast:
-		return fmt.Sprintf("[广播] %s: %s\n", m.From, m.Content)
+		return prefix + i18n.T(lang, "message.broadcast", params) + "\n"
 	default:
-		return fmt.Sprintf("[%s] %s\n", m.From, m.Content)
+		return prefix + fmt.Sprintf("[%s] %s\n", m.From, m.Content)
 	}
 }
 
-// CommandParser 命令解析器
-type CommandParser struct{}
-
-// NewCommandParser 创建新的命令解析器
-func NewCommandParser() *CommandParser {
-	return &CommandParser{}
-}
-
-// ParseCommand 解析用户输入的命令
-func (cp *CommandParser) ParseCommand(input string) (Command, error) {
-	input = strings.TrimSpace(input) // 去除空格
-
-	if !strings.HasPrefix(input, "\\") { // 判断是否以\开头
-		return Command{Type: CmdChat, Content: input}, nil
+// Encode 将消息编码为JSON字符串，用于持久化
+func (m *Message) Encode() (string, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
 	}
+	return string(data), nil
+}
 
-	parts := strings.Fields(input) // 分割字符串
-	if len(parts) == 0 {
-		return Command{}, fmt.Errorf("无效命令")
-	}
-
-	cmd := strings.ToLower(parts[0])
-
-	switch cmd {
-	case "\\who":
-		return Command{Type: CmdWho}, nil
-	case "\\rename":
-		if len(parts) < 2 {
-			return Command{}, fmt.Errorf("重命名命令格式: \\rename <新用户名>")
-		}
-		return Command{Type: CmdRename, Content: parts[1]}, nil
-	case "\\help":
-		return Command{Type: CmdHelp}, nil
-	case "\\quit", "\\exit":
-		return Command{Type: CmdQuit}, nil
-	case "\\time":
-		return Command{Type: CmdTime}, nil
-	case "\\stats":
-		return Command{Type: CmdStats}, nil
-	case "\\whisper", "\\w":
-		if len(parts) < 3 {
-			return Command{}, fmt.Errorf("私聊命令格式: \\whisper <用户名> <消息>")
-		}
-		target := parts[1]
-		content := strings.Join(parts[2:], " ")
-		return Command{Type: CmdWhisper, Target: target, Content: content}, nil
-	default:
-		return Command{}, fmt.Errorf("未知命令: %s", cmd)
+// DecodeMessage 从JSON字符串解码消息
+func DecodeMessage(data string) (*Message, error) {
+	var m Message
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		return nil, fmt.Errorf("解码消息失败: %v", err)
 	}
+	return &m, nil
 }
 
 // CommandType 命令类型
@@ -135,56 +176,86 @@ const (
 	CmdTime
 	CmdStats
 	CmdWhisper
+	CmdLang
+	CmdFormat
+	CmdIDs
+	CmdOper
+	CmdEdit
+	CmdDelete
+	CmdReact
+	CmdUnreact
+	CmdReactions
+	CmdReply
+	CmdThread
+	CmdSend
+	CmdGet
+	CmdAway
+	CmdBusy
+	CmdBack
+	CmdWhois
+	CmdIgnore
+	CmdUnignore
+	CmdIgnores
+	CmdFilter
+	CmdSearch
+	CmdExport
+	CmdRemind
+	CmdSchedule
+	CmdReminders
+	CmdPoll
+	CmdVote
+	CmdJoin
+	CmdLeave
+	CmdRooms
+	CmdTopic
+	CmdMOTD
+	CmdMode
+	CmdInvite
+	CmdRoomOp
+	CmdDM
+	CmdRespond
+	CmdPubKey
+	CmdSealed
+	CmdHook
 )
 
 // Command 命令结构体
 type Command struct {
 	Type    CommandType // 命令类型
 	Content string      // 命令内容
-	Target  string      // 目标用户
-}
+	Target  string      // 目标用户或消息ID
+	Size    int64       // 数据大小（附件传输）
+	Options []string    // 顺序无关的可选参数
 
-// GetHelpMessage 获取帮助信息
-func GetHelpMessage() string {
-	return `可用命令:
-  \\who          - 查看在线用户列表
-  \\rename <name> - 重命名
-  \\whisper <user> <msg> - 私聊消息
-  \\time          - 显示当前时间
-  \\stats         - 显示聊天室统计信息
-  \\help          - 显示此帮助信息
-  \\quit          - 退出聊天室
-  \\exit          - 退出聊天室`
+	Spec *CommandSpec // 命令定义，聊天消息为nil
 }
 
 // GetWelcomeMessage 获取欢迎消息
-func GetWelcomeMessage() string {
-	return `欢迎来到Go聊天室!
-输入 \\help 查看可用命令。
-输入消息开始聊天吧！`
+func GetWelcomeMessage() i18n.Text {
+	return i18n.NewText("welcome", nil)
 }
 
 // FormatUserJoinMessage 格式化用户加入消息
-func FormatUserJoinMessage(username string) string {
-	return fmt.Sprintf("用户 [%s] 加入了聊天室", username)
+func FormatUserJoinMessage(username string) i18n.Text {
+	return i18n.NewText("user.join", i18n.Params{"name": username})
 }
 
 // FormatUserLeaveMessage 格式化用户离开消息
-func FormatUserLeaveMessage(username string) string {
-	return fmt.Sprintf("用户 [%s] 离开了聊天室", username)
+func FormatUserLeaveMessage(username string) i18n.Text {
+	return i18n.NewText("user.leave", i18n.Params{"name": username})
 }
 
 // FormatUserRenameMessage 格式化用户重命名消息
-func FormatUserRenameMessage(oldName, newName string) string {
-	return fmt.Sprintf("用户 [%s] 将昵称改为 [%s]", oldName, newName)
+func FormatUserRenameMessage(oldName, newName string) i18n.Text {
+	return i18n.NewText("user.rename", i18n.Params{"old": oldName, "new": newName})
 }
 
 // FormatTimeMessage 格式化时间消息
-func FormatTimeMessage() string {
-	return fmt.Sprintf("当前时间: %s", time.Now().Format("2006-01-02 15:04:05"))
+func FormatTimeMessage() i18n.Text {
+	return i18n.NewText("time", i18n.Params{"time": time.Now().Format("2006-01-02 15:04:05")})
 }
 
 // FormatStatsMessage 格式化统计信息
-func FormatStatsMessage(userCount, maxUsers int) string {
-	return fmt.Sprintf("聊天室统计: 当前用户 %d/%d", userCount, maxUsers)
+func FormatStatsMessage(userCount, maxUsers int) i18n.Text {
+	return i18n.NewText("stats", i18n.Params{"count": userCount, "max": maxUsers})
 }
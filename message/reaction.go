@@ -0,0 +1,141 @@
+package message
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// shortcodes 常用表情短代码
+var shortcodes = map[string]string{
+	"+1":         "👍",
+	"thumbsup":   "👍",
+	"-1":         "👎",
+	"thumbsdown": "👎",
+	"heart":      "❤️",
+	"joy":        "😂",
+	"smile":      "😄",
+	"tada":       "🎉",
+	"eyes":       "👀",
+	"fire":       "🔥",
+	"rocket":     "🚀",
+	"ok_hand":    "👌",
+	"pray":       "🙏",
+	"clap":       "👏",
+	"100":        "💯",
+	"check":      "✅",
+	"x":          "❌",
+	"thinking":   "🤔",
+}
+
+// maxEmojiRunes 单个表情允许的最大码点数（包含变体选择符和零宽连接符）
+const maxEmojiRunes = 10
+
+// ResolveEmoji 把表情短代码（如 :+1:）解析为表情，直接输入的表情原样返回
+func ResolveEmoji(input string) (string, bool) {
+	input = strings.TrimSpace(input)
+	if emoji, ok := shortcodes[strings.Trim(input, ":")]; ok {
+		return emoji, true
+	}
+
+	if input == "" || utf8.RuneCountInString(input) > maxEmojiRunes {
+		return "", false
+	}
+	for _, r := range input {
+		if r < utf8.RuneSelf || unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+	return input, true
+}
+
+// AddReaction 添加用户的表情回应，按用户ID判断是否已回应过，已回应过时返回false
+func (m *Message) AddReaction(emoji, id, name string) bool {
+	for _, existing := range m.Reactions[emoji] {
+		if existing == id {
+			return false
+		}
+	}
+	if m.Reactions == nil {
+		m.Reactions = make(map[string][]string)
+	}
+	if m.Reactors == nil {
+		m.Reactors = make(map[string]string)
+	}
+	m.Reactions[emoji] = append(m.Reactions[emoji], id)
+	m.Reactors[id] = name
+	return true
+}
+
+// RemoveReaction 移除用户的表情回应，emoji为空时移除该用户的所有回应，
+// 返回被移除的表情
+func (m *Message) RemoveReaction(emoji, id string) []string {
+	var removed []string
+	for e, ids := range m.Reactions {
+		if emoji != "" && e != emoji {
+			continue
+		}
+		for i, existing := range ids {
+			if existing == id {
+				m.Reactions[e] = append(ids[:i:i], ids[i+1:]...)
+				removed = append(removed, e)
+				break
+			}
+		}
+		if len(m.Reactions[e]) == 0 {
+			delete(m.Reactions, e)
+		}
+	}
+	if !m.reacted(id) {
+		delete(m.Reactors, id)
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// reacted 判断用户是否还有表情回应
+func (m *Message) reacted(id string) bool {
+	for _, ids := range m.Reactions {
+		for _, existing := range ids {
+			if existing == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReactorNames 获取对某个表情回应的用户名
+//
+// online 是在线用户 (ID -> 用户名)：在线的回应者显示当前的用户名，离线的显示回应时的用户名。
+// 旧的历史记录中回应者保存的就是用户名，没有对应的记录时原样显示。
+func (m *Message) ReactorNames(emoji string, online map[string]string) []string {
+	names := make([]string, 0, len(m.Reactions[emoji]))
+	for _, id := range m.Reactions[emoji] {
+		name, exists := online[id]
+		if !exists {
+			if name, exists = m.Reactors[id]; !exists {
+				name = id
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// ReactionEmojis 获取按回应人数从多到少排序的表情列表
+func (m *Message) ReactionEmojis() []string {
+	emojis := make([]string, 0, len(m.Reactions))
+	for emoji := range m.Reactions {
+		emojis = append(emojis, emoji)
+	}
+	sort.Slice(emojis, func(i, j int) bool {
+		ci, cj := len(m.Reactions[emojis[i]]), len(m.Reactions[emojis[j]])
+		if ci != cj {
+			return ci > cj
+		}
+		return emojis[i] < emojis[j]
+	})
+	return emojis
+}
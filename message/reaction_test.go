@@ -0,0 +1,51 @@
+package message
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReactionByUserID(t *testing.T) {
+	msg := NewMessage(TypeChat, "carol", "hi")
+	if !msg.AddReaction("👍", "user_1", "alice") {
+		t.Fatal("第一次回应应成功")
+	}
+	// 改名后仍是同一个用户，不能重复回应
+	if msg.AddReaction("👍", "user_1", "alicia") {
+		t.Error("同一用户改名后不应能重复回应")
+	}
+	// 之后使用同一用户名的其他连接不能取消原用户的回应
+	if removed := msg.RemoveReaction("👍", "user_2"); len(removed) != 0 {
+		t.Errorf("其他用户取消了回应 %v", removed)
+	}
+	msg.AddReaction("👍", "user_2", "alice")
+	msg.AddReaction("🎉", "user_1", "alicia")
+
+	// 在线用户显示当前用户名，离线用户显示回应时的用户名
+	online := map[string]string{"user_1": "alicia"}
+	if got := strings.Join(msg.ReactorNames("👍", online), ","); got != "alicia,alice" {
+		t.Errorf("回应者 %q, want alicia,alice", got)
+	}
+
+	if removed := msg.RemoveReaction("", "user_1"); strings.Join(removed, ",") != "🎉,👍" {
+		t.Errorf("移除的回应 %v", removed)
+	}
+	if _, exists := msg.Reactors["user_1"]; exists {
+		t.Error("没有回应的用户应从回应者中移除")
+	}
+	if got := strings.Join(msg.ReactorNames("👍", nil), ","); got != "alice" {
+		t.Errorf("回应者 %q, want alice", got)
+	}
+}
+
+func TestReactionLegacyHistory(t *testing.T) {
+	// 旧的历史记录中回应者保存的是用户名
+	var msg Message
+	if err := json.Unmarshal([]byte(`{"id":"1","from":"a","content":"x","reactions":{"👍":["bob"]}}`), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.ReactorNames("👍", nil); len(got) != 1 || got[0] != "bob" {
+		t.Errorf("回应者 %v, want [bob]", got)
+	}
+}
@@ -0,0 +1,76 @@
+package motd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vars 渲染每日消息时可用的变量
+type Vars struct {
+	Name   string        // 用户名，对应 {name}
+	Online int           // 在线人数，对应 {online}
+	Uptime time.Duration // 服务器运行时长，对应 {uptime}
+}
+
+// MOTD 从文件加载的每日消息（message of the day）
+//
+// 文件内容原样显示，其中的 {name}、{online}、{uptime} 和 {time} 在显示时替换为
+// 当前用户名、在线人数、服务器运行时长和当前时间。文件修改后调用 Reload 即可生效。
+type MOTD struct {
+	path  string       // 文件路径，为空表示不启用
+	mutex sync.RWMutex // 读写锁
+	text  string       // 当前内容
+}
+
+// NewMOTD 创建每日消息并从文件加载内容，path为空时不启用
+func NewMOTD(path string) (*MOTD, error) {
+	m := &MOTD{path: path}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload 重新读取文件，加载失败时保留原有内容
+func (m *MOTD) Reload() error {
+	if m.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		return fmt.Errorf("读取每日消息文件失败: %v", err)
+	}
+
+	m.mutex.Lock()
+	m.text = strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	m.mutex.Unlock()
+	return nil
+}
+
+// Enabled 判断是否有可显示的内容
+func (m *MOTD) Enabled() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.text != ""
+}
+
+// Render 替换变量后返回每日消息，未启用时返回空字符串
+func (m *MOTD) Render(vars Vars) string {
+	m.mutex.RLock()
+	text := m.text
+	m.mutex.RUnlock()
+
+	if text == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{name}", vars.Name,
+		"{online}", fmt.Sprint(vars.Online),
+		"{uptime}", vars.Uptime.Round(time.Second).String(),
+		"{time}", time.Now().Format("2006-01-02 15:04:05"),
+	).Replace(text)
+}
@@ -0,0 +1,165 @@
+package nickname
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+
+	"chatroom/i18n"
+)
+
+// DefaultMaxWidth 默认的用户名最大显示宽度（列数，全角字符占两列）
+const DefaultMaxWidth = 20
+
+// DefaultReserved 默认的保留用户名
+var DefaultReserved = []string{"system", "admin", "server", "root", "系统", "管理员", "服务器"}
+
+// allowedPunct 用户名中允许的标点符号
+const allowedPunct = "_-."
+
+// folder 大小写折叠器
+var folder = cases.Fold()
+
+// prototypes 易混淆字符到原型的映射，在 confusableTable 的基础上加入大小写折叠后的字符
+var prototypes = foldedPrototypes()
+
+//go:generate go run gen_confusables.go -version 15.1.0
+
+// foldedPrototypes 在易混淆字符表中加入折叠后字符的映射
+//
+// 小写字母没有自己的映射时使用对应大写字母的原型（如 i 使用 I -> l），ALICE 与 alice 的比较键才会相同；
+// 多个字符折叠为同一个字符时使用码点最小的原字符。
+func foldedPrototypes() map[rune]string {
+	table := make(map[rune]string, len(confusableTable))
+	sources := make([]rune, 0, len(confusableTable))
+	for r, prototype := range confusableTable {
+		table[r] = prototype
+		sources = append(sources, r)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i] < sources[j] })
+	for _, r := range sources {
+		folded := []rune(folder.String(string(r)))
+		if len(folded) != 1 || folded[0] == r {
+			continue
+		}
+		if _, exists := table[folded[0]]; !exists {
+			table[folded[0]] = confusableTable[r]
+		}
+	}
+	return table
+}
+
+// Policy 用户名策略
+type Policy struct {
+	maxWidth int             // 最大显示宽度
+	reserved map[string]bool // 保留用户名（按比较键保存）
+}
+
+// NewPolicy 创建新的用户名策略
+func NewPolicy(maxWidth int, reserved []string) *Policy {
+	if maxWidth <= 0 {
+		maxWidth = DefaultMaxWidth
+	}
+	p := &Policy{
+		maxWidth: maxWidth,
+		reserved: make(map[string]bool, len(reserved)),
+	}
+	for _, name := range reserved {
+		if name = strings.TrimSpace(name); name != "" {
+			p.reserved[Key(name)] = true
+		}
+	}
+	return p
+}
+
+// DefaultPolicy 创建使用默认设置的用户名策略
+func DefaultPolicy() *Policy {
+	return NewPolicy(DefaultMaxWidth, DefaultReserved)
+}
+
+// Normalize 校验用户名并返回NFKC规范化后的显示名
+//
+// 允许任意文字的字母、数字和组合符号，以及少量标点；
+// 拒绝控制字符、格式字符（如零宽字符、方向控制符）和空白。
+func (p *Policy) Normalize(name string) (string, error) {
+	name = norm.NFKC.String(strings.TrimSpace(name))
+	if name == "" {
+		return "", i18n.Errorf("error.name_empty", nil)
+	}
+
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r), unicode.IsNumber(r), unicode.IsMark(r):
+		case strings.ContainsRune(allowedPunct, r):
+		default:
+			return "", i18n.Errorf("error.name_invalid_char", i18n.Params{"char": printable(r)})
+		}
+	}
+	if unicode.IsMark([]rune(name)[0]) {
+		return "", i18n.Errorf("error.name_invalid_char", i18n.Params{"char": printable([]rune(name)[0])})
+	}
+
+	if w := Width(name); w > p.maxWidth {
+		return "", i18n.Errorf("error.name_too_wide", i18n.Params{"max": p.maxWidth, "width": w})
+	}
+
+	if p.reserved[Key(name)] {
+		return "", i18n.Errorf("error.name_reserved", i18n.Params{"name": name})
+	}
+	return name, nil
+}
+
+// Key 计算用户名的比较键
+//
+// 按 Unicode 安全机制（UTS #39）的骨架（skeleton）比较：NFKC规范化后把每个字符替换为
+// Unicode 易混淆字符表中的原型并做大小写折叠，再对折叠后的字符做一次替换。
+// 比较键相同的两个用户名视为同一个名字，用于防止冒充，例如 AIice 与 Alice、раураl（西里尔字母）与 paypal。
+func Key(name string) string {
+	key := skeleton(norm.NFKC.String(strings.TrimSpace(name)), confusableTable)
+	return skeleton(folder.String(key), prototypes)
+}
+
+// skeleton NFD分解后把每个字符替换为原型，再次NFD分解并大小写折叠
+func skeleton(s string, table map[rune]string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if prototype, exists := table[r]; exists {
+			b.WriteString(prototype)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return folder.String(norm.NFD.String(b.String()))
+}
+
+// Width 计算用户名的显示宽度
+func Width(name string) int {
+	total := 0
+	for _, r := range name {
+		switch {
+		case unicode.IsMark(r), unicode.In(r, unicode.Cf):
+			// 组合符号和格式字符不占宽度
+		default:
+			switch width.LookupRune(r).Kind() {
+			case width.EastAsianWide, width.EastAsianFullwidth:
+				total += 2
+			default:
+				total++
+			}
+		}
+	}
+	return total
+}
+
+// printable 返回字符的可显示形式，不可见字符显示为码点
+func printable(r rune) string {
+	if unicode.IsPrint(r) && !unicode.IsSpace(r) {
+		return string(r)
+	}
+	return fmt.Sprintf("U+%04X", r)
+}
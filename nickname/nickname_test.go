@@ -0,0 +1,70 @@
+package nickname
+
+import (
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestKeyConfusables(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"Alice", "alice", true},
+		{"ALICE", "alice", true},
+		{"AIice", "Alice", true},   // 大写 I 与小写 l
+		{"A1ice", "Alice", true},   // 数字 1
+		{"раураl", "paypal", true}, // 西里尔字母
+		{"ΒΟΒ", "bob", true},       // 希腊字母
+		{"B0B", "bob", true},
+		{"rnike", "mike", true},
+		{"Ａｌｉｃｅ", "alice", true}, // 全角字母
+		{"𝐚𝐥𝐢𝐜𝐞", "alice", true}, // 数学字母
+		{"zoë", "zoë", true},
+		{"alice", "bob", false},
+		{"zoë", "zoe", false},
+		{"张三", "张王", false},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.a) == Key(tt.b); got != tt.same {
+			t.Errorf("Key(%q)=%q, Key(%q)=%q, 相同=%v, want %v", tt.a, Key(tt.a), tt.b, Key(tt.b), got, tt.same)
+		}
+	}
+}
+
+func TestKeyIdempotent(t *testing.T) {
+	for _, name := range []string{"Alice", "AIice", "раураl", "rnike", "Zoë", "张三", "user_2"} {
+		if key := Key(name); Key(key) != key {
+			t.Errorf("Key(Key(%q)) = %q, want %q", name, Key(key), key)
+		}
+	}
+}
+
+func TestPolicyReservedConfusable(t *testing.T) {
+	p := DefaultPolicy()
+	for _, name := range []string{"admin", "ADMlN", "аdmin", "r00t"} {
+		if _, err := p.Normalize(name); err == nil {
+			t.Errorf("Normalize(%q) 应拒绝保留用户名", name)
+		}
+	}
+	if _, err := p.Normalize("alice"); err != nil {
+		t.Errorf("Normalize(alice) = %v", err)
+	}
+}
+
+func TestKeyPrototypes(t *testing.T) {
+	if len(confusableTable) == 0 {
+		t.Fatal("易混淆字符表为空")
+	}
+	// 每个字符与它的原型比较键相同；用户名经过NFKC规范化，比较键又按NFD分解，只检查不受规范化影响的映射
+	stable := func(s string) bool { return norm.NFKC.String(s) == s && norm.NFD.String(s) == s }
+	for r, prototype := range confusableTable {
+		if !stable(string(r)) || !stable(prototype) {
+			continue
+		}
+		if Key(string(r)) != Key(prototype) {
+			t.Errorf("Key(%q)=%q, Key(%q)=%q", string(r), Key(string(r)), prototype, Key(prototype))
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package poll
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chatroom/i18n"
+	"chatroom/message"
+)
+
+// 投票的限制
+const (
+	MinOptions  = 2                  // 最少选项数
+	MaxOptions  = 10                 // 最多选项数
+	MaxDuration = 7 * 24 * time.Hour // 最长投票时间
+)
+
+// Poll 投票
+type Poll struct {
+	ID        string    // 投票ID
+	Question  string    // 问题
+	Options   []string  // 选项
+	Creator   string    // 发起者用户名
+	CreatorID string    // 发起者用户ID，只有同一次连接中的发起者可以结束投票
+	Room      string    // 发起投票的房间，结果只在该房间公布
+	Multi     bool      // 是否多选
+	Anonymous bool      // 是否匿名（不公开投票者）
+	Created   time.Time // 发起时间
+	Deadline  time.Time // 截止时间，零值表示直到手动结束
+	Closed    bool      // 是否已结束
+
+	votes  map[string]map[int]bool // 投票者用户ID -> 选择的选项（从0开始）
+	voters map[string]string       // 投票者用户ID -> 投票时的用户名
+}
+
+// Tally 计票结果
+type Tally struct {
+	Counts []int      // 每个选项的票数
+	Names  [][]string // 每个选项的投票者（匿名投票为空）
+	Voters int        // 参与投票的人数
+}
+
+// Tally 统计票数，调用者需持有管理器的锁或使用快照
+func (p *Poll) Tally() Tally {
+	t := Tally{
+		Counts: make([]int, len(p.Options)),
+		Names:  make([][]string, len(p.Options)),
+		Voters: len(p.votes),
+	}
+	for id, choices := range p.votes {
+		for choice := range choices {
+			t.Counts[choice]++
+			if !p.Anonymous {
+				t.Names[choice] = append(t.Names[choice], p.voters[id])
+			}
+		}
+	}
+	for _, names := range t.Names {
+		sort.Strings(names)
+	}
+	return t
+}
+
+// Render 渲染投票的当前结果，每个选项一行，如 "2. 周五 ███ 3 (alice, bob, carol)"
+func (p *Poll) Render() string {
+	tally := p.Tally()
+	max := 0
+	for _, count := range tally.Counts {
+		if count > max {
+			max = count
+		}
+	}
+
+	var lines []string
+	for i, option := range p.Options {
+		line := fmt.Sprintf("  %d. %s %s %d", i+1, option, bar(tally.Counts[i], max), tally.Counts[i])
+		if len(tally.Names[i]) > 0 {
+			line += " (" + strings.Join(tally.Names[i], ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Winners 返回得票最多的选项，没有人投票时返回空
+func (p *Poll) Winners() []string {
+	tally := p.Tally()
+	best := 0
+	var winners []string
+	for i, count := range tally.Counts {
+		switch {
+		case count > best:
+			best = count
+			winners = []string{p.Options[i]}
+		case count == best && count > 0:
+			winners = append(winners, p.Options[i])
+		}
+	}
+	return winners
+}
+
+// parseDuration 解析投票时长，除 time.ParseDuration 的格式外还支持按天计算（如 2d）
+func parseDuration(value string) (time.Duration, bool) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return time.Duration(n) * 24 * time.Hour, true
+		}
+	}
+	d, err := time.ParseDuration(value)
+	return d, err == nil
+}
+
+// bar 用方块表示票数比例，最多10格
+func bar(count, max int) string {
+	if max == 0 {
+		return ""
+	}
+	return strings.Repeat("█", (count*10+max-1)/max)
+}
+
+// snapshot 复制投票，供回调在锁外使用
+func (p *Poll) snapshot() Poll {
+	copied := *p
+	copied.Options = append([]string(nil), p.Options...)
+	copied.votes = make(map[string]map[int]bool, len(p.votes))
+	for id, choices := range p.votes {
+		copied.votes[id] = make(map[int]bool, len(choices))
+		for choice := range choices {
+			copied.votes[id][choice] = true
+		}
+	}
+	copied.voters = make(map[string]string, len(p.voters))
+	for id, name := range p.voters {
+		copied.voters[id] = name
+	}
+	return copied
+}
+
+// Manager 投票管理器
+//
+// 投票保存在本实例内存中。投票后不立即广播结果，而是在 tallyInterval 内合并为一次更新，
+// 避免热门投票刷屏；到达截止时间或被手动结束时回调 onClose。
+type Manager struct {
+	mutex         sync.Mutex
+	polls         map[string]*Poll       // 投票ID -> 投票
+	pending       map[string]*time.Timer // 等待广播结果的投票
+	deadlines     map[string]*time.Timer // 截止时间定时器
+	tallyInterval time.Duration          // 结果更新的最短间隔
+	onTally       func(p Poll)           // 广播当前结果
+	onClose       func(p Poll)           // 公布最终结果
+}
+
+// NewManager 创建投票管理器
+func NewManager(tallyInterval time.Duration, onTally, onClose func(p Poll)) *Manager {
+	return &Manager{
+		polls:         make(map[string]*Poll),
+		pending:       make(map[string]*time.Timer),
+		deadlines:     make(map[string]*time.Timer),
+		tallyInterval: tallyInterval,
+		onTally:       onTally,
+		onClose:       onClose,
+	}
+}
+
+// Create 在房间中发起投票
+//
+// args 依次为问题和选项，末尾可以跟投票时长（如 10m、1h）以及 multi（多选）、anon（匿名）标记。
+func (m *Manager) Create(creatorID, creator, room string, args []string) (Poll, error) {
+	p := &Poll{
+		ID:        message.NewID(),
+		Creator:   creator,
+		CreatorID: creatorID,
+		Room:      room,
+		Created:   time.Now(),
+		votes:     make(map[string]map[int]bool),
+		voters:    make(map[string]string),
+	}
+
+	// 从末尾识别时长和标记
+	for len(args) > 0 {
+		last := strings.ToLower(args[len(args)-1])
+		if last == "multi" && !p.Multi {
+			p.Multi = true
+		} else if (last == "anon" || last == "anonymous") && !p.Anonymous {
+			p.Anonymous = true
+		} else if d, ok := parseDuration(last); ok && p.Deadline.IsZero() {
+			if d <= 0 || d > MaxDuration {
+				return Poll{}, i18n.Errorf("error.poll_duration", i18n.Params{"max": fmt.Sprintf("%dd", MaxDuration/(24*time.Hour))})
+			}
+			p.Deadline = p.Created.Add(d)
+		} else {
+			break
+		}
+		args = args[:len(args)-1]
+	}
+
+	if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
+		return Poll{}, i18n.Errorf("error.poll_question", nil)
+	}
+	p.Question = args[0]
+	seen := make(map[string]bool)
+	for _, option := range args[1:] {
+		option = strings.TrimSpace(option)
+		key := strings.ToLower(option)
+		if option == "" || seen[key] {
+			return Poll{}, i18n.Errorf("error.poll_option", i18n.Params{"option": option})
+		}
+		seen[key] = true
+		p.Options = append(p.Options, option)
+	}
+	if len(p.Options) < MinOptions || len(p.Options) > MaxOptions {
+		return Poll{}, i18n.Errorf("error.poll_options", i18n.Params{"min": MinOptions, "max": MaxOptions})
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.polls[p.ID] = p
+	if !p.Deadline.IsZero() {
+		id := p.ID
+		m.deadlines[id] = time.AfterFunc(time.Until(p.Deadline), func() { m.expire(id) })
+	}
+	return p.snapshot(), nil
+}
+
+// Vote 投票，只有在发起投票的房间中才能投票
+//
+// 单选投票 choice 是一个选项编号，再次投票会改票；多选投票可以用逗号分隔多个编号，
+// 再次选择已选的选项会取消该选项。投票按用户ID记录，改名后再次投票仍是改票。
+// 返回投票者当前选择的选项编号（从1开始）。
+func (m *Manager) Vote(id, room, voterID, voter, choice string) ([]int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	p, exists := m.polls[id]
+	if !exists || p.Room != room {
+		return nil, i18n.Errorf("error.poll_not_found", i18n.Params{"id": id})
+	}
+
+	var picks []int
+	for _, part := range strings.Split(choice, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 || n > len(p.Options) {
+			return nil, i18n.Errorf("error.poll_choice", i18n.Params{"choice": part, "max": len(p.Options)})
+		}
+		picks = append(picks, n-1)
+	}
+	if !p.Multi && len(picks) > 1 {
+		return nil, i18n.Errorf("error.poll_single", nil)
+	}
+
+	choices := p.votes[voterID]
+	if choices == nil || !p.Multi {
+		choices = make(map[int]bool)
+	}
+	for _, pick := range picks {
+		if p.Multi && choices[pick] {
+			delete(choices, pick)
+		} else {
+			choices[pick] = true
+		}
+	}
+	if len(choices) == 0 {
+		delete(p.votes, voterID)
+		delete(p.voters, voterID)
+	} else {
+		p.votes[voterID] = choices
+		p.voters[voterID] = voter
+	}
+	m.scheduleTally(p.ID)
+
+	current := make([]int, 0, len(choices))
+	for pick := range choices {
+		current = append(current, pick+1)
+	}
+	sort.Ints(current)
+	return current, nil
+}
+
+// Close 手动结束投票，只有发起者（按用户ID）或管理员可以结束
+func (m *Manager) Close(id, byID string, isOperator bool) error {
+	m.mutex.Lock()
+	p, exists := m.polls[id]
+	if !exists {
+		m.mutex.Unlock()
+		return i18n.Errorf("error.poll_not_found", i18n.Params{"id": id})
+	}
+	if p.CreatorID != byID && !isOperator {
+		m.mutex.Unlock()
+		return i18n.Errorf("error.poll_not_owner", nil)
+	}
+	closed := m.finish(p)
+	m.mutex.Unlock()
+
+	m.onClose(closed)
+	return nil
+}
+
+// expire 到达截止时间时结束投票
+func (m *Manager) expire(id string) {
+	m.mutex.Lock()
+	p, exists := m.polls[id]
+	if !exists {
+		m.mutex.Unlock()
+		return
+	}
+	closed := m.finish(p)
+	m.mutex.Unlock()
+
+	m.onClose(closed)
+}
+
+// finish 结束投票并移除相关定时器，调用者需持有锁
+func (m *Manager) finish(p *Poll) Poll {
+	p.Closed = true
+	delete(m.polls, p.ID)
+	if timer, exists := m.deadlines[p.ID]; exists {
+		timer.Stop()
+		delete(m.deadlines, p.ID)
+	}
+	if timer, exists := m.pending[p.ID]; exists {
+		timer.Stop()
+		delete(m.pending, p.ID)
+	}
+	return p.snapshot()
+}
+
+// scheduleTally 安排一次结果广播，间隔内的多次投票合并为一次，调用者需持有锁
+func (m *Manager) scheduleTally(id string) {
+	if _, exists := m.pending[id]; exists {
+		return
+	}
+	m.pending[id] = time.AfterFunc(m.tallyInterval, func() {
+		m.mutex.Lock()
+		delete(m.pending, id)
+		p, exists := m.polls[id]
+		var current Poll
+		if exists {
+			current = p.snapshot()
+		}
+		m.mutex.Unlock()
+
+		if exists {
+			m.onTally(current)
+		}
+	})
+}
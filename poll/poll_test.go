@@ -0,0 +1,64 @@
+package poll
+
+import (
+	"testing"
+	"time"
+)
+
+// newManager 创建不广播结果的投票管理器
+func newManager() *Manager {
+	return NewManager(time.Hour, func(Poll) {}, func(Poll) {})
+}
+
+func TestVoteByUserID(t *testing.T) {
+	m := newManager()
+	p, err := m.Create("user_1", "alice", "lobby", []string{"午饭?", "面", "饭"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Vote(p.ID, "lobby", "user_2", "bob", "1"); err != nil {
+		t.Fatal(err)
+	}
+	// 改名后再次投票是改票，不是第二票
+	if _, err := m.Vote(p.ID, "lobby", "user_2", "bobby", "2"); err != nil {
+		t.Fatal(err)
+	}
+	m.mutex.Lock()
+	tally := m.polls[p.ID].Tally()
+	m.mutex.Unlock()
+	if tally.Voters != 1 || tally.Counts[0] != 0 || tally.Counts[1] != 1 {
+		t.Fatalf("计票 %+v, want 只有一票投给第2项", tally)
+	}
+	if len(tally.Names[1]) != 1 || tally.Names[1][0] != "bobby" {
+		t.Errorf("投票者 %v, want [bobby]", tally.Names[1])
+	}
+
+	if _, err := m.Vote(p.ID, "dev", "user_3", "carol", "1"); err == nil {
+		t.Error("其他房间的用户不应能投票")
+	}
+}
+
+func TestCloseByCreatorID(t *testing.T) {
+	m := newManager()
+	p, err := m.Create("user_1", "alice", "lobby", []string{"问题", "是", "否"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 之后使用发起者用户名的其他连接不能结束投票
+	if err := m.Close(p.ID, "user_9", false); err == nil {
+		t.Fatal("非发起者不应能结束投票")
+	}
+	if err := m.Close(p.ID, "user_1", false); err != nil {
+		t.Fatalf("发起者结束投票: %v", err)
+	}
+	if err := m.Close(p.ID, "user_1", false); err == nil {
+		t.Error("已结束的投票不应能再次结束")
+	}
+
+	p, _ = m.Create("user_1", "alice", "lobby", []string{"问题", "是", "否"})
+	if err := m.Close(p.ID, "user_9", true); err != nil {
+		t.Errorf("管理员结束投票: %v", err)
+	}
+}
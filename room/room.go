@@ -0,0 +1,372 @@
+package room
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/store"
+)
+
+// 房间的限制
+const (
+	MaxNameRunes  = 32  // 房间名最大字符数
+	MaxTopicRunes = 200 // 话题最大字符数
+)
+
+// Mode 房间模式
+type Mode string
+
+const (
+	ModePublic   Mode = "public"   // 公开：出现在房间列表中，任何人都可以加入
+	ModePrivate  Mode = "private"  // 私密：不出现在房间列表中，知道房间名即可加入
+	ModeInvite   Mode = "invite"   // 仅限邀请：只有被邀请的用户和房间管理员可以加入
+	ModePassword Mode = "password" // 密码保护：加入时需要提供密码（被邀请的用户除外）
+)
+
+// ParseMode 解析房间模式
+func ParseMode(value string) (Mode, bool) {
+	switch mode := Mode(strings.ToLower(value)); mode {
+	case ModePublic, ModePrivate, ModeInvite, ModePassword:
+		return mode, true
+	}
+	return "", false
+}
+
+// Room 房间信息
+type Room struct {
+	Name    string    `json:"name"`               // 房间名（小写）
+	Topic   string    `json:"topic,omitempty"`    // 话题
+	TopicBy string    `json:"topic_by,omitempty"` // 设置话题的用户
+	TopicAt time.Time `json:"topic_at,omitempty"` // 设置话题的时间
+	Created time.Time `json:"created"`            // 创建时间
+
+	Mode      Mode              `json:"mode,omitempty"`      // 房间模式，为空表示公开
+	KeySalt   string            `json:"key_salt,omitempty"`  // 密码的盐
+	KeyHash   string            `json:"key_hash,omitempty"`  // 加盐后的密码哈希
+	Operators map[string]string `json:"operators,omitempty"` // 房间管理员 (用户ID -> 用户名)
+	Invited   map[string]string `json:"invited,omitempty"`   // 被邀请的用户 (用户ID -> 用户名)
+}
+
+// CurrentMode 获取房间模式，早期保存的房间没有模式，视为公开
+func (r *Room) CurrentMode() Mode {
+	if r.Mode == "" {
+		return ModePublic
+	}
+	return r.Mode
+}
+
+// IsOperator 判断用户是否为房间管理员
+//
+// 管理权限和邀请都绑定到用户ID而不是用户名：用户名可以被别人在原用户离开后重新使用，
+// 用户ID只属于一次连接，用户离开后权限随之失效（见 Manager.Forget）。
+func (r *Room) IsOperator(userID string) bool {
+	_, exists := r.Operators[userID]
+	return exists
+}
+
+// IsInvited 判断用户是否被邀请
+func (r *Room) IsInvited(userID string) bool {
+	_, exists := r.Invited[userID]
+	return exists
+}
+
+// Listed 判断房间是否出现在房间列表中
+func (r *Room) Listed() bool {
+	return r.CurrentMode() != ModePrivate
+}
+
+// Open 判断房间的内容是否对所有人可见（搜索、导出等），非公开房间只对房间内的用户可见
+func (r *Room) Open() bool {
+	return r.CurrentMode() == ModePublic
+}
+
+// CheckJoin 检查用户能否加入房间，key 为加入时提供的密码
+//
+// 房间管理员和被邀请的用户不受仅限邀请和密码的限制。
+func (r *Room) CheckJoin(userID, key string) error {
+	if r.IsOperator(userID) || r.IsInvited(userID) {
+		return nil
+	}
+	switch r.CurrentMode() {
+	case ModeInvite:
+		return i18n.Errorf("error.room_invite_only", i18n.Params{"room": r.Name})
+	case ModePassword:
+		if key == "" {
+			return i18n.Errorf("error.room_key_required", i18n.Params{"room": r.Name})
+		}
+		if !r.checkKey(key) {
+			return i18n.Errorf("error.room_bad_key", i18n.Params{"room": r.Name})
+		}
+	}
+	return nil
+}
+
+// checkKey 校验房间密码
+func (r *Room) checkKey(key string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashKey(r.KeySalt, key)), []byte(r.KeyHash)) == 1
+}
+
+// hashKey 计算加盐的密码哈希
+func hashKey(salt, key string) string {
+	sum := sha256.Sum256([]byte(salt + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Info 房间列表中的一项
+type Info struct {
+	Room
+	Members int // 在线成员数（包括其他实例）
+}
+
+// Normalize 规范化房间名：去掉 # 前缀并转为小写
+//
+// 房间名只能包含字母、数字、- 和 _，不能以 @ 开头（@ 开头的名字保留给私聊等内部记录）。
+func Normalize(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" || utf8.RuneCountInString(name) > MaxNameRunes {
+		return "", i18n.Errorf("error.room_name", i18n.Params{"name": name, "max": MaxNameRunes})
+	}
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '-' && c != '_' {
+			return "", i18n.Errorf("error.room_name", i18n.Params{"name": name, "max": MaxNameRunes})
+		}
+	}
+	return name, nil
+}
+
+// Manager 房间管理器
+//
+// 房间信息和在线成员所在的房间都保存在状态后端中，多个实例看到的是同一份房间列表。
+// 房间在第一次有人加入时创建，之后一直保留（包括话题）。
+type Manager struct {
+	backend store.RoomStore // 状态后端
+}
+
+// NewManager 创建房间管理器，默认房间总是存在
+func NewManager(backend store.RoomStore) (*Manager, error) {
+	m := &Manager{backend: backend}
+	if _, err := m.Ensure(message.DefaultRoom); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Get 获取房间信息
+func (m *Manager) Get(name string) (Room, bool, error) {
+	rooms, err := m.load()
+	if err != nil {
+		return Room{}, false, err
+	}
+	r, exists := rooms[name]
+	return r, exists, nil
+}
+
+// Ensure 获取房间信息，房间不存在时创建
+func (m *Manager) Ensure(name string) (Room, error) {
+	r, exists, err := m.Get(name)
+	if err != nil || exists {
+		return r, err
+	}
+	return m.update(name, func(r *Room, created bool) error { return nil })
+}
+
+// Create 获取房间信息，房间不存在时创建并把创建者设为房间管理员
+//
+// 多个用户同时创建同一个房间时只有一个成为房间管理员。
+func (m *Manager) Create(name, creatorID, creatorName string) (Room, error) {
+	return m.update(name, func(r *Room, created bool) error {
+		if created {
+			r.Operators = map[string]string{creatorID: creatorName}
+		}
+		return nil
+	})
+}
+
+// SetMode 修改房间模式，密码保护模式需要提供密码，默认房间总是公开
+func (m *Manager) SetMode(name string, mode Mode, key string) (Room, error) {
+	if name == message.DefaultRoom {
+		return Room{}, i18n.Errorf("error.room_lobby_mode", nil)
+	}
+	if mode == ModePassword && key == "" {
+		return Room{}, i18n.Errorf("error.room_key_missing", nil)
+	}
+	var salt string
+	if mode == ModePassword {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return Room{}, err
+		}
+		salt = hex.EncodeToString(buf)
+	}
+	return m.update(name, func(r *Room, created bool) error {
+		r.Mode = mode
+		r.KeySalt, r.KeyHash = "", ""
+		if mode == ModePassword {
+			r.KeySalt = salt
+			r.KeyHash = hashKey(salt, key)
+		}
+		return nil
+	})
+}
+
+// Invite 邀请用户加入房间，被邀请的用户在离开聊天室之前可以随时加入
+func (m *Manager) Invite(name, inviteeID, invitee string) (Room, error) {
+	return m.update(name, func(r *Room, created bool) error {
+		if r.Invited == nil {
+			r.Invited = make(map[string]string)
+		}
+		r.Invited[inviteeID] = invitee
+		return nil
+	})
+}
+
+// AddOperator 把用户设为房间管理员
+func (m *Manager) AddOperator(name, operatorID, operator string) (Room, error) {
+	return m.update(name, func(r *Room, created bool) error {
+		if r.Operators == nil {
+			r.Operators = make(map[string]string)
+		}
+		r.Operators[operatorID] = operator
+		return nil
+	})
+}
+
+// SetTopic 设置房间话题，topic 为空表示清除话题
+func (m *Manager) SetTopic(name, topic, by string) (Room, error) {
+	if utf8.RuneCountInString(topic) > MaxTopicRunes {
+		return Room{}, i18n.Errorf("error.topic_too_long", i18n.Params{"max": MaxTopicRunes})
+	}
+	return m.update(name, func(r *Room, created bool) error {
+		r.Topic = topic
+		r.TopicBy = by
+		r.TopicAt = time.Now()
+		return nil
+	})
+}
+
+// Forget 用户离开聊天室时撤销其在所有房间的管理权限和邀请
+//
+// 先找出涉及该用户的房间，再逐个在后端原子地修改最新的房间信息，不会用旧的副本覆盖其间的修改。
+func (m *Manager) Forget(userID string) error {
+	rooms, err := m.load()
+	if err != nil {
+		return err
+	}
+	for name, r := range rooms {
+		_, operator := r.Operators[userID]
+		_, invited := r.Invited[userID]
+		if !operator && !invited {
+			continue
+		}
+		if _, err := m.update(name, func(r *Room, created bool) error {
+			delete(r.Operators, userID)
+			delete(r.Invited, userID)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Names 获取所有房间名（按字母顺序）
+func (m *Manager) Names() ([]string, error) {
+	rooms, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(rooms))
+	for name := range rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// List 获取房间列表及在线成员数（包括私密房间，由调用者决定是否显示），
+// 默认房间排在最前，其余按成员数从多到少排序
+func (m *Manager) List() ([]Info, error) {
+	rooms, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	members, err := m.backend.Members()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, name := range members {
+		counts[name]++
+	}
+
+	list := make([]Info, 0, len(rooms))
+	for name, r := range rooms {
+		list = append(list, Info{Room: r, Members: counts[name]})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if (a.Name == message.DefaultRoom) != (b.Name == message.DefaultRoom) {
+			return a.Name == message.DefaultRoom
+		}
+		if a.Members != b.Members {
+			return a.Members > b.Members
+		}
+		return a.Name < b.Name
+	})
+	return list, nil
+}
+
+// load 从后端加载所有房间，跳过无法解析的记录
+func (m *Manager) load() (map[string]Room, error) {
+	entries, err := m.backend.Rooms()
+	if err != nil {
+		return nil, err
+	}
+	rooms := make(map[string]Room, len(entries))
+	for name, entry := range entries {
+		var r Room
+		if err := json.Unmarshal([]byte(entry), &r); err != nil {
+			continue
+		}
+		rooms[name] = r
+	}
+	return rooms, nil
+}
+
+// update 在后端原子地读取、修改并保存房间信息，房间不存在时先创建（created 为 true）
+//
+// 并发修改同一个房间时不会丢失彼此的修改；Redis 后端冲突时 fn 会对最新的房间信息再次执行。
+func (m *Manager) update(name string, fn func(r *Room, created bool) error) (Room, error) {
+	var result Room
+	err := m.backend.UpdateRoom(name, func(data string, exists bool) (string, error) {
+		r := Room{Name: name, Created: time.Now(), Mode: ModePublic}
+		if exists {
+			if err := json.Unmarshal([]byte(data), &r); err != nil {
+				return "", err
+			}
+		}
+		if err := fn(&r, !exists); err != nil {
+			return "", err
+		}
+		encoded, err := json.Marshal(r)
+		if err != nil {
+			return "", err
+		}
+		result = r
+		return string(encoded), nil
+	})
+	if err != nil {
+		return Room{}, err
+	}
+	return result, nil
+}
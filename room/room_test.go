@@ -0,0 +1,104 @@
+package room
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"chatroom/store"
+)
+
+func TestRightsBoundToUserID(t *testing.T) {
+	m, err := NewManager(store.NewMemoryBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := m.Create("dev", "user_a", "Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SetMode(r.Name, ModeInvite, ""); err != nil {
+		t.Fatal(err)
+	}
+	if r, err = m.Invite(r.Name, "user_b", "Bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.IsOperator("user_a") || r.IsOperator("Alice") {
+		t.Fatal("房间管理员应该按用户ID判断")
+	}
+	if err := r.CheckJoin("user_b", ""); err != nil {
+		t.Fatalf("被邀请的用户应该可以加入: %v", err)
+	}
+	if err := r.CheckJoin("user_c", ""); err == nil {
+		t.Fatal("使用同名但不同ID的用户不应该被视为已邀请")
+	}
+
+	if err := m.Forget("user_a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Forget("user_b"); err != nil {
+		t.Fatal(err)
+	}
+	r, _, err = m.Get("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.IsOperator("user_a") || r.IsInvited("user_b") {
+		t.Fatal("用户离开后权限和邀请应该被撤销")
+	}
+}
+
+func TestCreateExisting(t *testing.T) {
+	m, err := NewManager(store.NewMemoryBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Ensure("dev"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := m.Create("dev", "user_a", "Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.IsOperator("user_a") {
+		t.Fatal("加入已存在的房间不应该成为管理员")
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	m, err := NewManager(store.NewMemoryBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create("dev", "user_a", "Alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同时邀请、设置管理员、修改话题和模式，以及撤销离开用户的权限，彼此的修改都不会丢失
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(3)
+		id := fmt.Sprintf("user_%d", i)
+		go func() { defer wg.Done(); m.Invite("dev", id, id) }()
+		go func() { defer wg.Done(); m.AddOperator("dev", "op_"+id, id) }()
+		go func() { defer wg.Done(); m.SetTopic("dev", "话题", id) }()
+	}
+	wg.Add(2)
+	go func() { defer wg.Done(); m.SetMode("dev", ModeInvite, "") }()
+	go func() { defer wg.Done(); m.Forget("user_a") }()
+	wg.Wait()
+
+	r, _, err := m.Get("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Invited) != n || len(r.Operators) != n {
+		t.Fatalf("邀请 %d 个、管理员 %d 个, want 各 %d 个", len(r.Invited), len(r.Operators), n)
+	}
+	if r.Mode != ModeInvite || r.Topic != "话题" || r.IsOperator("user_a") {
+		t.Fatalf("房间 %+v", r)
+	}
+}
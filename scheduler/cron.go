@@ -0,0 +1,177 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros 常用的定时表达式简写
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField 定时表达式中一个字段的取值范围
+type cronField struct {
+	name     string // 字段名，用于错误信息
+	min, max int    // 取值范围
+}
+
+// cronFields 分、时、日、月、星期（0和7都表示星期日）
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day", 1, 31},
+	{"month", 1, 12},
+	{"weekday", 0, 7},
+}
+
+// Cron 五段式定时表达式（分 时 日 月 星期），按本地时区计算
+//
+// 每个字段支持 *、数字、a-b 范围、/n 步长和逗号分隔的列表。
+// 日和星期都不是 * 时，满足其中之一即可，与标准cron一致。
+type Cron struct {
+	expr     string
+	minutes  uint64 // 每一位表示一个允许的取值
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	anyDay   bool // 日字段为 *
+	anyWeek  bool // 星期字段为 *
+}
+
+// ParseCron 解析定时表达式，也支持 @daily、@hourly 等简写
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		spec = macro
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("定时表达式需要5个字段: %s", expr)
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	// 星期日可以写成 0 或 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return &Cron{
+		expr:     expr,
+		minutes:  sets[0],
+		hours:    sets[1],
+		days:     sets[2],
+		months:   sets[3],
+		weekdays: sets[4],
+		anyDay:   parts[2] == "*",
+		anyWeek:  parts[4] == "*",
+	}, nil
+}
+
+// parseCronField 解析一个字段，返回允许取值的位集合
+func parseCronField(value string, field cronField) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%s 字段的步长无效: %s", field.name, item)
+			}
+			step = n
+		}
+
+		low, high := field.min, field.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("%s 字段的值无效: %s", field.name, item)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return 0, fmt.Errorf("%s 字段的值无效: %s", field.name, item)
+				}
+			} else if hasStep {
+				// 5/15 表示从5开始每15个
+				high = field.max
+			}
+		}
+		if low < field.min || high > field.max || low > high {
+			return 0, fmt.Errorf("%s 字段超出范围 %d-%d: %s", field.name, field.min, field.max, item)
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// String 返回原始表达式
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Next 返回晚于 t 的下一个执行时间（精确到分钟），五年内没有匹配时返回零值
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay 判断日期是否满足日和星期字段
+func (c *Cron) matchDay(t time.Time) bool {
+	day := c.days&(1<<uint(t.Day())) != 0
+	week := c.weekdays&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDay && c.anyWeek:
+		return true
+	case c.anyDay:
+		return week
+	case c.anyWeek:
+		return day
+	default:
+		return day || week
+	}
+}
@@ -0,0 +1,335 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chatroom/message"
+	"chatroom/store"
+	"chatroom/utils"
+)
+
+// Kind 任务类型
+type Kind string
+
+const (
+	KindReminder  Kind = "remind"   // 个人提醒，到时发送给创建者（创建者离开聊天室时取消）
+	KindBroadcast Kind = "schedule" // 定时广播，到时发送给创建时所在房间的用户
+	KindRecurring Kind = "cron"     // 按定时表达式重复的广播（仅管理员）
+)
+
+// ErrUndelivered 任务执行时接收者已不在线
+var ErrUndelivered = errors.New("接收者不在线")
+
+// Job 定时任务
+type Job struct {
+	ID      string    `json:"id"`                 // 任务ID
+	Kind    Kind      `json:"kind"`               // 任务类型
+	Owner   string    `json:"owner"`              // 创建者用户名（用于显示）
+	OwnerID string    `json:"owner_id,omitempty"` // 创建者用户ID
+	Text    string    `json:"text"`               // 内容
+	Room    string    `json:"room,omitempty"`     // 广播发送到的房间（创建时所在的房间）
+	At      time.Time `json:"at"`                 // 下次执行时间
+	Cron    string    `json:"cron,omitempty"`     // 定时表达式（仅 KindRecurring）
+	Created time.Time `json:"created"`            // 创建时间
+}
+
+// OwnedBy 判断任务是否由指定用户创建
+//
+// 任务按创建者的用户ID而不是用户名归属：用户名在原用户离开后可以被别人使用，
+// 没有记录用户ID的早期任务不属于任何人，只有管理员可以管理。
+func (j *Job) OwnedBy(userID string) bool {
+	return j.OwnerID != "" && j.OwnerID == userID
+}
+
+// Scheduler 持久化的定时任务调度器
+//
+// 任务保存在状态后端中，重启后重新加载。执行前先从后端删除任务作为认领，
+// 多个实例加载了同一任务时只有删除成功的实例会执行，重复任务执行后再保存下一次的时间。
+type Scheduler struct {
+	mutex   sync.Mutex
+	backend store.JobStore         // 状态后端
+	logger  *utils.Logger          // 日志记录器
+	run     func(job Job) error    // 任务执行函数
+	jobs    map[string]*Job        // 本实例已安排的任务
+	timers  map[string]*time.Timer // 任务ID -> 定时器
+	stopped bool                   // 是否已停止
+}
+
+// NewScheduler 创建定时任务调度器
+func NewScheduler(backend store.JobStore, logger *utils.Logger) *Scheduler {
+	return &Scheduler{
+		backend: backend,
+		logger:  logger,
+		jobs:    make(map[string]*Job),
+		timers:  make(map[string]*time.Timer),
+	}
+}
+
+// Start 设置任务执行函数并加载已保存的任务
+//
+// 停机期间到期的一次性任务立即执行，重复任务跳过错过的时间，从下一次开始。
+func (s *Scheduler) Start(run func(job Job) error) error {
+	s.mutex.Lock()
+	s.run = run
+	s.mutex.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.Kind == KindRecurring && job.At.Before(time.Now()) {
+			cron, err := ParseCron(job.Cron)
+			if err != nil {
+				s.logger.Warn("跳过无效的定时任务 %s: %v", job.ID, err)
+				continue
+			}
+			job.At = cron.Next(time.Now())
+			if err := s.save(job); err != nil {
+				return err
+			}
+		}
+		s.arm(job)
+	}
+	s.logger.Info("已加载 %d 个定时任务", len(jobs))
+	return nil
+}
+
+// Stop 停止所有定时器，已保存的任务在下次启动时恢复
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stopped = true
+	for id, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+}
+
+// Add 保存并安排新任务，自动分配ID和创建时间
+func (s *Scheduler) Add(job Job) (Job, error) {
+	job.ID = message.NewID()
+	job.Created = time.Now()
+	if err := s.save(&job); err != nil {
+		return Job{}, err
+	}
+	s.arm(&job)
+	return job, nil
+}
+
+// Cancel 取消任务，任务已执行或不存在时返回false
+func (s *Scheduler) Cancel(id string) (bool, error) {
+	removed, err := s.backend.DeleteJob(id)
+	if err != nil {
+		return false, err
+	}
+	s.disarm(id)
+	return removed, nil
+}
+
+// Find 按ID查找任务
+func (s *Scheduler) Find(id string) (Job, bool, error) {
+	return s.find(id)
+}
+
+// Jobs 获取所有已保存的任务（包括其他实例创建的），按执行时间排序
+func (s *Scheduler) Jobs() ([]Job, error) {
+	jobs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Job, 0, len(jobs))
+	for _, job := range jobs {
+		result = append(result, *job)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].At.Before(result[j].At)
+	})
+	return result, nil
+}
+
+// Forget 用户离开聊天室时取消其创建的提醒，定时广播保留，返回取消的数量
+func (s *Scheduler) Forget(userID string) (int, error) {
+	jobs, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, job := range jobs {
+		if job.Kind != KindReminder || !job.OwnedBy(userID) {
+			continue
+		}
+		removed, err := s.Cancel(job.ID)
+		if err != nil {
+			return count, err
+		}
+		if removed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// find 从后端查找任务
+func (s *Scheduler) find(id string) (Job, bool, error) {
+	jobs, err := s.load()
+	if err != nil {
+		return Job{}, false, err
+	}
+	for _, job := range jobs {
+		if job.ID == id {
+			return *job, true, nil
+		}
+	}
+	return Job{}, false, nil
+}
+
+// load 从后端加载所有任务，跳过无法解析的记录
+func (s *Scheduler) load() ([]*Job, error) {
+	entries, err := s.backend.Jobs()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, 0, len(entries))
+	for id, entry := range entries {
+		var job Job
+		if err := json.Unmarshal([]byte(entry), &job); err != nil {
+			s.logger.Warn("跳过无法解析的定时任务 %s: %v", id, err)
+			continue
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+// save 保存任务到后端
+func (s *Scheduler) save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.backend.SaveJob(job.ID, string(data))
+}
+
+// arm 为任务设置定时器
+func (s *Scheduler) arm(job *Job) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		return
+	}
+	if timer, exists := s.timers[job.ID]; exists {
+		timer.Stop()
+	}
+	s.jobs[job.ID] = job
+	id := job.ID
+	s.timers[id] = time.AfterFunc(time.Until(job.At), func() { s.fire(id) })
+}
+
+// disarm 取消任务的定时器
+func (s *Scheduler) disarm(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if timer, exists := s.timers[id]; exists {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+	delete(s.jobs, id)
+}
+
+// fire 定时器到期时执行任务
+func (s *Scheduler) fire(id string) {
+	s.mutex.Lock()
+	job, exists := s.jobs[id]
+	delete(s.timers, id)
+	delete(s.jobs, id)
+	s.mutex.Unlock()
+
+	if exists {
+		s.execute(job)
+	}
+}
+
+// execute 认领并执行任务，重复任务执行后安排下一次，返回是否执行
+func (s *Scheduler) execute(job *Job) bool {
+	// 删除成功才算认领，避免多个实例重复执行
+	claimed, err := s.backend.DeleteJob(job.ID)
+	if err != nil {
+		s.logger.Error("认领定时任务 %s 失败: %v", job.ID, err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	if job.Kind == KindRecurring {
+		if cron, err := ParseCron(job.Cron); err == nil {
+			next := *job
+			next.At = cron.Next(time.Now())
+			if err := s.save(&next); err != nil {
+				s.logger.Error("保存定时任务 %s 失败: %v", job.ID, err)
+			} else {
+				s.arm(&next)
+			}
+		}
+	}
+
+	s.mutex.Lock()
+	run := s.run
+	s.mutex.Unlock()
+
+	err = run(*job)
+	if errors.Is(err, ErrUndelivered) {
+		// 提醒属于创建时的连接，创建者已经离开（如所在实例崩溃）时丢弃
+		s.logger.Info("定时任务 %s 的接收者已不在线，丢弃", job.ID)
+		return false
+	}
+	if err != nil {
+		s.logger.Error("执行定时任务 %s 失败: %v", job.ID, err)
+		return false
+	}
+	s.logger.Info("已执行定时任务 %s (%s, 创建者 %s)", job.ID, job.Kind, job.Owner)
+	return true
+}
+
+// ParseWhen 解析执行时间
+//
+// 支持相对时长（10m、1h30m、2d）、当天的时刻（15:04，已过去则为明天）
+// 以及日期时间（2006-01-02T15:04 或 "2006-01-02 15:04"）。
+func ParseWhen(value string, now time.Time) (time.Time, bool) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, n), true
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(d), true
+	}
+	if clock, err := time.ParseInLocation("15:04", value, time.Local); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local)
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, true
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02T15:04:05"} {
+		if at, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// IsCron 判断执行时间参数是否为定时表达式（@daily 等简写或五段式）
+func IsCron(value string) bool {
+	return strings.HasPrefix(value, "@") || len(strings.Fields(value)) == len(cronFields)
+}
@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"chatroom/store"
+	"chatroom/utils"
+)
+
+func TestOwnedBy(t *testing.T) {
+	job := Job{Owner: "alice", OwnerID: "user_a"}
+	if !job.OwnedBy("user_a") {
+		t.Fatal("创建者应该拥有任务")
+	}
+	if job.OwnedBy("alice") || job.OwnedBy("user_b") {
+		t.Fatal("任务不应该按用户名或其他用户ID归属")
+	}
+	if legacy := (Job{Owner: "alice"}); legacy.OwnedBy("") {
+		t.Fatal("没有用户ID的早期任务不属于任何人")
+	}
+}
+
+func TestForget(t *testing.T) {
+	s := NewScheduler(store.NewMemoryBackend(), utils.NewLogger(false))
+	if err := s.Start(func(job Job) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	at := time.Now().Add(time.Hour)
+	for _, job := range []Job{
+		{Kind: KindReminder, OwnerID: "user_a", At: at},
+		{Kind: KindBroadcast, OwnerID: "user_a", At: at},
+		{Kind: KindReminder, OwnerID: "user_b", At: at},
+	} {
+		if _, err := s.Add(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := s.Forget("user_a")
+	if err != nil || count != 1 {
+		t.Fatalf("Forget = %d, %v", count, err)
+	}
+	jobs, err := s.Jobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("应该保留定时广播和其他用户的提醒，实际剩下 %d 个任务", len(jobs))
+	}
+	for _, job := range jobs {
+		if job.Kind == KindReminder && job.OwnedBy("user_a") {
+			t.Fatal("离开的用户的提醒应该被取消")
+		}
+	}
+}
+
+func TestUndeliveredReminderDropped(t *testing.T) {
+	backend := store.NewMemoryBackend()
+	s := NewScheduler(backend, utils.NewLogger(false))
+	ran := make(chan Job, 1)
+	if err := s.Start(func(job Job) error {
+		ran <- job
+		return ErrUndelivered
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	if _, err := s.Add(Job{Kind: KindReminder, OwnerID: "user_a", At: time.Now().Add(10 * time.Millisecond)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(2 * time.Second):
+		t.Fatal("提醒没有执行")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if jobs, _ := s.Jobs(); len(jobs) != 0 {
+		t.Fatalf("接收者不在线的提醒应该被丢弃，实际保留 %d 个", len(jobs))
+	}
+}
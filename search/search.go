@@ -0,0 +1,385 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/nickname"
+)
+
+// dateLayouts 查询中 before:/after: 支持的时间格式
+var dateLayouts = []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02T15:04:05"}
+
+// Query 搜索条件
+type Query struct {
+	Terms  []string  // 搜索词（已规范化），所有搜索词都必须出现
+	From   string    // 发送者用户名，为空表示不限
+	Room   string    // 房间，为空表示所有房间
+	Before time.Time // 只搜索早于该时间的消息，零值表示不限
+	After  time.Time // 只搜索不早于该时间的消息，零值表示不限
+
+	Hidden map[string]bool // 对搜索者不可见的房间，由调用者根据房间模式设置
+}
+
+// ParseQuery 解析搜索语句
+//
+// 语句由空白分隔的搜索词和过滤条件组成：from:<用户名>、in:<房间>、
+// before:<日期> 和 after:<日期>，日期格式为 2006-01-02 或 2006-01-02T15:04。
+func ParseQuery(input string) (Query, error) {
+	var q Query
+	for _, field := range strings.Fields(input) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok || value == "" {
+			q.Terms = append(q.Terms, normalize(field))
+			continue
+		}
+
+		var err error
+		switch strings.ToLower(key) {
+		case "from":
+			q.From = value
+		case "in":
+			q.Room = strings.TrimPrefix(value, "#")
+		case "before":
+			q.Before, err = parseDate(value)
+		case "after":
+			q.After, err = parseDate(value)
+		default:
+			// 不是过滤条件（例如 URL 中的冒号），作为普通搜索词
+			q.Terms = append(q.Terms, normalize(field))
+		}
+		if err != nil {
+			return Query{}, err
+		}
+	}
+	return q, nil
+}
+
+// IsEmpty 判断搜索条件是否为空
+func (q Query) IsEmpty() bool {
+	return len(q.Terms) == 0 && q.From == "" && q.Room == "" && q.Before.IsZero() && q.After.IsZero()
+}
+
+// parseDate 按本地时区解析日期
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, i18n.Errorf("error.search_date", i18n.Params{"value": value})
+}
+
+// Hit 搜索结果
+type Hit struct {
+	Room    string           // 房间
+	Message *message.Message // 消息
+}
+
+// document 索引中的一条消息
+type document struct {
+	room    string           // 房间
+	from    string           // 发送者用户名比较键
+	text    string           // 规范化后的内容，用于校验中文搜索词
+	tokens  []string         // 内容分词结果
+	message *message.Message // 消息副本
+}
+
+// Index 消息全文倒排索引
+//
+// 中文、日文、韩文按相邻两字（以及单字）切分，其他文字按字母和数字组成的单词切分。
+// 每个房间最多保留 maxPerRoom 条消息，超出时淘汰最早的消息，与历史记录的保留条数一致。
+type Index struct {
+	mutex      sync.RWMutex
+	maxPerRoom int                            // 每个房间保留的消息数，<=0 表示不限制
+	docs       map[string]*document           // 文档键 -> 文档
+	postings   map[string]map[string]struct{} // 词 -> 文档键集合
+	order      map[string][]string            // 房间 -> 按时间顺序的文档键
+}
+
+// docKey 文档键，消息ID只在房间内唯一，不同房间的相同ID不会互相覆盖
+func docKey(room, id string) string {
+	return room + "/" + id
+}
+
+// NewIndex 创建搜索索引
+func NewIndex(maxPerRoom int) *Index {
+	return &Index{
+		maxPerRoom: maxPerRoom,
+		docs:       make(map[string]*document),
+		postings:   make(map[string]map[string]struct{}),
+		order:      make(map[string][]string),
+	}
+}
+
+// Add 索引一条消息，已存在时更新内容
+func (idx *Index) Add(room string, msg *message.Message) {
+	if msg.ID == "" || msg.Type == message.TypeSystem || msg.Type == message.TypePrivate {
+		return
+	}
+	if msg.Deleted {
+		idx.Remove(room, msg.ID)
+		return
+	}
+
+	copied := *msg
+	text := normalize(msg.Content)
+	doc := &document{
+		room:    room,
+		from:    nickname.Key(msg.From),
+		text:    text,
+		tokens:  Tokenize(text),
+		message: &copied,
+	}
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	key := docKey(room, msg.ID)
+	if old, exists := idx.docs[key]; exists {
+		// 编辑：保留原位置，只替换内容
+		idx.unindex(key, old)
+	} else {
+		idx.order[room] = append(idx.order[room], key)
+		idx.evict(room)
+	}
+	idx.docs[key] = doc
+	for _, token := range doc.tokens {
+		if idx.postings[token] == nil {
+			idx.postings[token] = make(map[string]struct{})
+		}
+		idx.postings[token][key] = struct{}{}
+	}
+}
+
+// Remove 从索引中移除房间中的消息
+func (idx *Index) Remove(room, id string) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	key := docKey(room, id)
+	doc, exists := idx.docs[key]
+	if !exists {
+		return
+	}
+	idx.unindex(key, doc)
+	delete(idx.docs, key)
+
+	order := idx.order[room]
+	for i, existing := range order {
+		if existing == key {
+			idx.order[room] = append(order[:i:i], order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Observe 根据广播事件增量更新索引，可以作为用户管理器的事件监听器
+func (idx *Index) Observe(event *message.Event) {
+	room := event.Message.Room
+	if room == "" {
+		room = message.DefaultRoom
+	}
+	switch event.Type {
+	case message.EventMessage, message.EventEdit:
+		idx.Add(room, event.Message)
+	case message.EventDelete:
+		idx.Remove(room, event.Message.ID)
+	}
+}
+
+// unindex 移除文档的倒排记录，调用者需持有写锁
+func (idx *Index) unindex(key string, doc *document) {
+	for _, token := range doc.tokens {
+		if keys := idx.postings[token]; keys != nil {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(idx.postings, token)
+			}
+		}
+	}
+}
+
+// evict 淘汰房间中超出保留条数的最早消息，调用者需持有写锁
+func (idx *Index) evict(room string) {
+	if idx.maxPerRoom <= 0 {
+		return
+	}
+	order := idx.order[room]
+	for len(order) > idx.maxPerRoom {
+		key := order[0]
+		order = order[1:]
+		if doc, exists := idx.docs[key]; exists {
+			idx.unindex(key, doc)
+			delete(idx.docs, key)
+		}
+	}
+	idx.order[room] = order
+}
+
+// Search 搜索消息，按时间从新到旧返回最多 limit 条结果，以及匹配的总数
+func (idx *Index) Search(q Query, limit int) ([]Hit, int) {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	fromKey := ""
+	if q.From != "" {
+		fromKey = nickname.Key(q.From)
+	}
+
+	var hits []Hit
+	for key := range idx.candidates(q.Terms) {
+		doc := idx.docs[key]
+		if doc == nil || !idx.matches(doc, q, fromKey) {
+			continue
+		}
+		copied := *doc.message
+		hits = append(hits, Hit{Room: doc.room, Message: &copied})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Message.Timestamp.After(hits[j].Message.Timestamp)
+	})
+	total := len(hits)
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, total
+}
+
+// candidates 通过倒排索引找出包含所有搜索词分词的文档键，没有搜索词时返回全部文档
+func (idx *Index) candidates(terms []string) map[string]struct{} {
+	var tokens []string
+	for _, term := range terms {
+		tokens = append(tokens, Tokenize(term)...)
+	}
+	if len(terms) > 0 && len(tokens) == 0 {
+		// 搜索词只包含标点等无法索引的字符
+		return nil
+	}
+	if len(tokens) == 0 {
+		all := make(map[string]struct{}, len(idx.docs))
+		for id := range idx.docs {
+			all[id] = struct{}{}
+		}
+		return all
+	}
+
+	// 从最短的倒排列表开始求交集
+	sort.Slice(tokens, func(i, j int) bool {
+		return len(idx.postings[tokens[i]]) < len(idx.postings[tokens[j]])
+	})
+	result := make(map[string]struct{})
+	for id := range idx.postings[tokens[0]] {
+		result[id] = struct{}{}
+	}
+	for _, token := range tokens[1:] {
+		ids := idx.postings[token]
+		for id := range result {
+			if _, ok := ids[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+	return result
+}
+
+// matches 检查过滤条件，并确认中文搜索词作为连续文本出现（两字切分可能误匹配）
+func (idx *Index) matches(doc *document, q Query, fromKey string) bool {
+	if fromKey != "" && doc.from != fromKey {
+		return false
+	}
+	if q.Room != "" && !strings.EqualFold(doc.room, q.Room) {
+		return false
+	}
+	if q.Hidden[doc.room] {
+		return false
+	}
+	ts := doc.message.Timestamp
+	if !q.Before.IsZero() && !ts.Before(q.Before) {
+		return false
+	}
+	if !q.After.IsZero() && ts.Before(q.After) {
+		return false
+	}
+	for _, term := range q.Terms {
+		if hasCJK(term) && !strings.Contains(doc.text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize 规范化文本：NFKC（全角转半角）并转为小写
+func normalize(text string) string {
+	return strings.ToLower(norm.NFKC.String(text))
+}
+
+// Tokenize 将规范化后的文本切分为索引词
+//
+// 连续的中日韩字符输出每个单字和相邻两字，其他文字输出由字母和数字组成的单词。
+func Tokenize(text string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	emit := func(token string) {
+		if token != "" && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	var word []rune
+	var cjk []rune
+	flush := func() {
+		emit(string(word))
+		word = word[:0]
+		for i := range cjk {
+			emit(string(cjk[i]))
+			if i+1 < len(cjk) {
+				emit(string(cjk[i : i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, c := range text {
+		switch {
+		case isCJK(c):
+			if len(word) > 0 {
+				flush()
+			}
+			cjk = append(cjk, c)
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || unicode.Is(unicode.Mn, c):
+			if len(cjk) > 0 {
+				flush()
+			}
+			word = append(word, c)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// isCJK 判断是否为中日韩文字（这些文字不用空格分词）
+func isCJK(c rune) bool {
+	return unicode.In(c, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// hasCJK 判断文本是否包含中日韩文字
+func hasCJK(text string) bool {
+	for _, c := range text {
+		if isCJK(c) {
+			return true
+		}
+	}
+	return false
+}
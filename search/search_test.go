@@ -0,0 +1,29 @@
+package search
+
+import (
+	"testing"
+
+	"chatroom/message"
+)
+
+func TestSameIDInDifferentRooms(t *testing.T) {
+	idx := NewIndex(100)
+
+	lobby := message.NewMessage(message.TypeChat, "alice", "部署完成")
+	lobby.Room = "lobby"
+	dev := message.NewMessage(message.TypeChat, "bob", "部署失败")
+	dev.ID, dev.Room = lobby.ID, "dev"
+	idx.Add(lobby.Room, lobby)
+	idx.Add(dev.Room, dev)
+
+	if _, total := idx.Search(Query{Terms: []string{"部署"}}, 10); total != 2 {
+		t.Fatalf("找到 %d 条消息, want 2", total)
+	}
+
+	// 删除一个房间的消息不影响另一个房间的同ID消息
+	idx.Observe(message.NewDeleteEvent(dev, "bob"))
+	hits, total := idx.Search(Query{Terms: []string{"部署"}}, 10)
+	if total != 1 || hits[0].Room != "lobby" || hits[0].Message.Content != "部署完成" {
+		t.Fatalf("删除后找到 %+v", hits)
+	}
+}
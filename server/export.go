@@ -0,0 +1,90 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"chatroom/config"
+	"chatroom/export"
+	"chatroom/history"
+	"chatroom/message"
+	"chatroom/utils"
+)
+
+// ExportOptions 命令行导出聊天记录的参数
+type ExportOptions struct {
+	Room        string // 房间，为空表示默认房间
+	Since       string // 起始时间（时长或日期），为空表示全部
+	Format      string // 导出格式，为空表示 Markdown
+	Participant string // 同时导出该用户参与的私聊，为空表示不包含私聊
+	Output      string // 输出文件，为空时写入配置的导出目录
+}
+
+// ExportTranscript 不启动服务器，直接从状态后端导出聊天记录，返回输出文件路径和消息数
+//
+// 内存后端的历史消息只存在于运行中的服务器进程里，此时返回错误而不是写出空的记录。
+func ExportTranscript(cfg *config.Config, opts ExportOptions) (string, int, error) {
+	if cfg.Store != "redis" {
+		return "", 0, fmt.Errorf("内存状态后端没有可导出的历史消息，请使用 -store redis 或在聊天室中执行 \\export")
+	}
+	room := strings.ToLower(strings.TrimPrefix(opts.Room, "#"))
+	if room == "" {
+		room = message.DefaultRoom
+	}
+	format := export.DefaultFormat
+	if opts.Format != "" {
+		f, ok := export.ParseFormat(opts.Format)
+		if !ok {
+			return "", 0, fmt.Errorf("不支持的导出格式: %s", opts.Format)
+		}
+		format = f
+	}
+	var since time.Time
+	if opts.Since != "" {
+		t, ok := export.ParseSince(opts.Since, time.Now())
+		if !ok {
+			return "", 0, fmt.Errorf("无效的起始时间: %s", opts.Since)
+		}
+		since = t
+	}
+
+	backend, err := newBackend(cfg, utils.NewLogger(cfg.EnableLogs))
+	if err != nil {
+		return "", 0, err
+	}
+	defer backend.Close()
+
+	historyStore := history.NewStore(backend, cfg.HistorySize)
+	var participant export.Participant
+	if opts.Participant != "" {
+		participant = export.ByName(opts.Participant)
+	}
+	transcript, err := export.Load(historyStore, room, participant, since, cfg.Language)
+	if err != nil {
+		return "", 0, fmt.Errorf("加载历史消息失败: %v", err)
+	}
+
+	path := opts.Output
+	if path == "" {
+		if err := os.MkdirAll(cfg.ExportDir, 0o755); err != nil {
+			return "", 0, fmt.Errorf("创建导出目录失败: %v", err)
+		}
+		path = filepath.Join(cfg.ExportDir, transcript.FileName(format))
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("创建导出文件失败: %v", err)
+	}
+
+	if err := transcript.Render(file, format); err != nil {
+		file.Close()
+		return "", 0, fmt.Errorf("导出聊天记录失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", 0, fmt.Errorf("写入导出文件失败: %v", err)
+	}
+	return path, len(transcript.Messages), nil
+}
@@ -7,34 +7,163 @@ import (
 	"os/signal"
 	"syscall"
 
+	"chatroom/attachment"
+	"chatroom/bot"
 	"chatroom/config"
+	"chatroom/dm"
+	"chatroom/filter"
+	"chatroom/forge"
 	"chatroom/handler"
+	"chatroom/history"
+	"chatroom/httpapi"
+	"chatroom/i18n"
+	"chatroom/motd"
+	"chatroom/nickname"
+	"chatroom/room"
+	"chatroom/scheduler"
+	"chatroom/search"
+	"chatroom/store"
 	"chatroom/user"
 	"chatroom/utils"
+	"chatroom/webhook"
 )
 
 // ChatServer 聊天服务器
 type ChatServer struct {
 	config            *config.Config             // 配置
+	backend           store.Backend              // 状态后端
 	userManager       *user.UserManager          // 用户管理器
+	filter            *filter.Filter             // 内容过滤器
+	motd              *motd.MOTD                 // 每日消息
+	webhooks          *webhook.Dispatcher        // webhook 分发器
+	repos             *forge.Router              // 仓库事件路由
+	scheduler         *scheduler.Scheduler       // 定时任务调度器
 	connectionHandler *handler.ConnectionHandler // 连接处理器
+	api               *httpapi.Server            // HTTP接口，未配置监听地址时为nil
 	logger            *utils.Logger              // 日志记录器
 	listener          net.Listener               // 监听器
 	isRunning         bool                       // 是否运行
 }
 
 // NewChatServer 创建新的聊天服务器
-func NewChatServer(cfg *config.Config) *ChatServer {
+func NewChatServer(cfg *config.Config) (*ChatServer, error) {
 	logger := utils.NewLogger(cfg.EnableLogs)
-	userManager := user.NewUserManager(cfg.MaxUsers)
-	connectionHandler := handler.NewConnectionHandler(userManager, logger, cfg)
+
+	backend, err := newBackend(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := nickname.NewPolicy(cfg.MaxNameWidth, cfg.ReservedNames)
+	userManager := user.NewUserManagerWithBackend(cfg.MaxUsers, backend, policy)
+	historyStore := history.NewStore(backend, cfg.HistorySize)
+
+	attachments, err := attachment.NewStore(cfg.AttachmentDir, cfg.MaxAttachmentSize)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	contentFilter, err := filter.NewFilter(cfg.FilterFile)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	messageOfTheDay, err := motd.NewMOTD(cfg.MOTDFile)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	webhooks, err := webhook.NewDispatcher(cfg.WebhooksFile, logger)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	repos, err := forge.NewRouter(cfg.ReposFile)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	rooms, err := room.NewManager(backend)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	// 用已保存的历史记录建立搜索索引，之后随广播事件增量更新
+	index := search.NewIndex(cfg.HistorySize)
+	names, err := rooms.Names()
+	if err != nil {
+		logger.Warn("加载房间列表失败，搜索索引为空: %v", err)
+	}
+	for _, name := range names {
+		recent, err := historyStore.Recent(name, 0)
+		if err != nil {
+			logger.Warn("加载房间 %s 的历史记录失败: %v", name, err)
+			continue
+		}
+		for _, msg := range recent {
+			index.Add(name, msg)
+		}
+	}
+	userManager.AddEventListener(index.Observe)
+
+	// 加载保存的提醒和定时广播
+	jobs := scheduler.NewScheduler(backend, logger)
+	connectionHandler := handler.NewConnectionHandler(handler.Deps{
+		Users:       userManager,
+		History:     historyStore,
+		Attachments: attachments,
+		Filter:      contentFilter,
+		Index:       index,
+		Scheduler:   jobs,
+		Rooms:       rooms,
+		DMs:         dm.NewManager(backend),
+		Bots:        bot.NewManager(backend, policy),
+		Keys:        backend,
+		MOTD:        messageOfTheDay,
+		Webhooks:    webhooks,
+		Logger:      logger,
+		Config:      cfg,
+	})
+	if err := jobs.Start(connectionHandler.RunJob); err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	// HTTP接口与TCP聊天服务共用连接处理器
+	var api *httpapi.Server
+	if cfg.HTTPAddr != "" {
+		api = httpapi.NewServer(cfg, connectionHandler, repos, logger)
+	}
 
 	return &ChatServer{
 		config:            cfg,
+		backend:           backend,
 		userManager:       userManager,
+		filter:            contentFilter,
+		motd:              messageOfTheDay,
+		webhooks:          webhooks,
+		repos:             repos,
+		scheduler:         jobs,
 		connectionHandler: connectionHandler,
+		api:               api,
 		logger:            logger,
 		isRunning:         false,
+	}, nil
+}
+
+// newBackend 根据配置创建状态后端
+func newBackend(cfg *config.Config, logger *utils.Logger) (store.Backend, error) {
+	switch cfg.Store {
+	case "redis":
+		return store.NewRedisBackend(cfg.RedisAddr, logger)
+	default:
+		return store.NewMemoryBackend(), nil
 	}
 }
 
@@ -51,6 +180,14 @@ func (s *ChatServer) Start() error {
 		return fmt.Errorf("启动服务器失败: %v", err)
 	}
 	s.listener = listener
+
+	// 启动HTTP接口
+	if s.api != nil {
+		if err := s.api.Start(); err != nil {
+			listener.Close()
+			return err
+		}
+	}
 	s.isRunning = true
 
 	s.logger.Info("聊天服务器启动成功，监听地址: %s", s.config.GetAddress())
@@ -77,7 +214,7 @@ func (s *ChatServer) acceptConnections() error {
 		// 检查用户数量限制
 		if s.userManager.GetUserCount() >= s.config.MaxUsers {
 			s.logger.Warn("聊天室已满，拒绝新连接")
-			conn.Write([]byte("聊天室已满，请稍后再试\n"))
+			conn.Write([]byte(i18n.T(s.config.Language, "server.full", nil) + "\n"))
 			conn.Close()
 			continue
 		}
@@ -97,11 +234,25 @@ func (s *ChatServer) Stop() {
 	if s.listener != nil {
 		s.listener.Close()
 	}
+	if s.api != nil {
+		s.api.Close()
+	}
+
+	// 停止定时任务，未执行的任务保存在状态后端中
+	s.scheduler.Stop()
 
 	// 断开所有用户连接
 	users := s.userManager.GetAllUsers()
 	for _, user := range users {
-		close(user.DoneChan)
+		user.Stop()
+	}
+
+	// 停止接收新的 webhook 事件
+	s.webhooks.Close()
+
+	// 关闭状态后端
+	if err := s.backend.Close(); err != nil {
+		s.logger.Error("关闭状态后端失败: %v", err)
 	}
 
 	s.logger.Info("服务器已停止")
@@ -110,11 +261,40 @@ func (s *ChatServer) Stop() {
 // handleSignals 处理系统信号
 func (s *ChatServer) handleSignals() {
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	<-sigChan
-	s.logger.Info("收到停止信号")
-	s.Stop()
+	for sig := range sigChan {
+		if sig == syscall.SIGHUP {
+			// 重新加载内容过滤规则
+			if count, err := s.filter.Reload(); err != nil {
+				s.logger.Error("重新加载过滤规则失败: %v", err)
+			} else {
+				s.logger.Info("已重新加载 %d 条过滤规则", count)
+			}
+			// 重新加载每日消息
+			if err := s.motd.Reload(); err != nil {
+				s.logger.Error("重新加载每日消息失败: %v", err)
+			} else if s.config.MOTDFile != "" {
+				s.logger.Info("已重新加载每日消息")
+			}
+			// 重新加载 webhook 配置
+			if count, err := s.webhooks.Reload(); err != nil {
+				s.logger.Error("重新加载 webhook 配置失败: %v", err)
+			} else if s.config.WebhooksFile != "" {
+				s.logger.Info("已重新加载 %d 个 webhook", count)
+			}
+			// 重新加载仓库事件路由
+			if count, err := s.repos.Reload(); err != nil {
+				s.logger.Error("重新加载仓库路由失败: %v", err)
+			} else if s.config.ReposFile != "" {
+				s.logger.Info("已重新加载 %d 条仓库路由", count)
+			}
+			continue
+		}
+		s.logger.Info("收到停止信号")
+		s.Stop()
+		return
+	}
 }
 
 // GetStats 获取服务器统计信息
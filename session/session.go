@@ -0,0 +1,129 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"chatroom/message"
+)
+
+// 会话参数
+const (
+	tokenBytes = 20  // 令牌的随机字节数，编码为40个十六进制字符
+	InboxSize  = 100 // 每个会话保留的最近私聊数
+)
+
+// ErrUnknown 令牌不属于任何会话（从未创建、已退出或已超时）
+var ErrUnknown = errors.New("无效的会话")
+
+// Whisper 会话收到的私聊
+type Whisper struct {
+	Seq     int64            `json:"seq"`     // 会话内的序号，从1开始
+	Message *message.Message `json:"message"` // 私聊消息
+}
+
+// Session REST 接口的会话，对应本实例用户管理器中的一个用户
+type Session struct {
+	Token  string // 访问令牌
+	UserID string // 会话用户的ID
+
+	mutex sync.Mutex    // 互斥锁
+	inbox []Whisper     // 最近收到的私聊
+	seq   int64         // 最后分配的私聊序号
+	wake  chan struct{} // 收到新私聊时关闭并替换，唤醒等待的请求
+}
+
+// Deliver 保存收到的私聊，超过 InboxSize 时丢弃最早的，并唤醒等待的请求
+func (s *Session) Deliver(msg *message.Message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.seq++
+	s.inbox = append(s.inbox, Whisper{Seq: s.seq, Message: msg})
+	if len(s.inbox) > InboxSize {
+		s.inbox = append([]Whisper{}, s.inbox[len(s.inbox)-InboxSize:]...)
+	}
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// Whispers 获取序号大于 since 的私聊，没有时可以等待返回的通道被关闭
+func (s *Session) Whispers(since int64) ([]Whisper, <-chan struct{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var whispers []Whisper
+	for _, w := range s.inbox {
+		if w.Seq > since {
+			whispers = append(whispers, w)
+		}
+	}
+	return whispers, s.wake
+}
+
+// Manager 会话管理器
+//
+// 会话只保存在创建它的实例中，多个实例时客户端需要始终访问同一个实例。
+type Manager struct {
+	mutex    sync.RWMutex        // 读写锁
+	sessions map[string]*Session // 当前的会话 (令牌 -> 会话)
+}
+
+// NewManager 创建会话管理器
+func NewManager() *Manager {
+	return &Manager{sessions: make(map[string]*Session)}
+}
+
+// Create 创建会话，生成令牌和会话用户的ID
+func (m *Manager) Create() (*Session, error) {
+	token, err := randomHex(tokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	// 用户ID会出现在事件中，与令牌无关
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{Token: token, UserID: "api_" + id, wake: make(chan struct{})}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sessions[token] = s
+	return s, nil
+}
+
+// Get 按令牌查找会话
+func (m *Manager) Get(token string) (*Session, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	s, exists := m.sessions[token]
+	return s, exists
+}
+
+// Remove 删除会话
+func (m *Manager) Remove(token string) (*Session, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	s, exists := m.sessions[token]
+	delete(m.sessions, token)
+	return s, exists
+}
+
+// Count 获取当前会话数量
+func (m *Manager) Count() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.sessions)
+}
+
+// randomHex 生成 n 个随机字节的十六进制编码
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,388 @@
+package store
+
+import "sync"
+
+// MemoryBackend 内存状态后端，适用于单实例部署
+type MemoryBackend struct {
+	mutex       sync.RWMutex                 // 互斥锁
+	online      map[string]string            // 在线用户 (ID -> 用户名)
+	keys        map[string]string            // 在线用户 (ID -> 比较键)
+	names       map[string]string            // 用户名索引 (比较键 -> ID)
+	history     map[string][]string          // 历史记录 (房间 -> 记录)
+	seqs        map[string]int64             // 消息序号 (房间 -> 最后分配的序号)
+	ignores     map[string]map[string]string // 屏蔽列表 (用户ID -> 被屏蔽的比较键 -> 用户名)
+	jobs        map[string]string            // 定时任务 (ID -> 编码)
+	rooms       map[string]string            // 房间信息 (房间名 -> 编码)
+	members     map[string]string            // 在线用户所在房间 (ID -> 房间名)
+	convs       map[string]string            // 私聊群组 (代号 -> 编码)
+	replies     map[string]string            // 最近的私聊对象 (用户ID -> 对象)
+	pubkeys     map[string]string            // 端到端加密公钥 (ID -> 公钥)
+	bots        map[string]string            // 机器人 (令牌哈希 -> 编码)
+	subscribers []func(payload string)       // 订阅者
+}
+
+// NewMemoryBackend 创建新的内存状态后端
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		online:  make(map[string]string),
+		keys:    make(map[string]string),
+		names:   make(map[string]string),
+		history: make(map[string][]string),
+		seqs:    make(map[string]int64),
+		ignores: make(map[string]map[string]string),
+		jobs:    make(map[string]string),
+		rooms:   make(map[string]string),
+		members: make(map[string]string),
+		convs:   make(map[string]string),
+		replies: make(map[string]string),
+		pubkeys: make(map[string]string),
+		bots:    make(map[string]string),
+	}
+}
+
+// Register 登记在线用户
+func (b *MemoryBackend) Register(id, key, name string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if owner, exists := b.names[key]; exists && owner != id {
+		return ErrNameTaken
+	}
+	b.online[id] = name
+	b.keys[id] = key
+	b.names[key] = id
+	return nil
+}
+
+// Unregister 注销在线用户
+func (b *MemoryBackend) Unregister(id string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if key, exists := b.keys[id]; exists {
+		if b.names[key] == id {
+			delete(b.names, key)
+		}
+	}
+	delete(b.online, id)
+	delete(b.keys, id)
+	delete(b.members, id)
+	delete(b.pubkeys, id)
+	delete(b.replies, id)
+	delete(b.ignores, id)
+	return nil
+}
+
+// Rename 修改在线用户的用户名
+func (b *MemoryBackend) Rename(id, oldKey, newKey, newName string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if owner, exists := b.names[newKey]; exists && owner != id {
+		return ErrNameTaken
+	}
+	if b.names[oldKey] == id {
+		delete(b.names, oldKey)
+	}
+	b.online[id] = newName
+	b.keys[id] = newKey
+	b.names[newKey] = id
+	return nil
+}
+
+// Lookup 按用户名比较键查找用户ID
+func (b *MemoryBackend) Lookup(key string) (string, bool, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	id, exists := b.names[key]
+	return id, exists, nil
+}
+
+// Online 获取所有在线用户
+func (b *MemoryBackend) Online() (map[string]string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	result := make(map[string]string, len(b.online))
+	for id, name := range b.online {
+		result[id] = name
+	}
+	return result, nil
+}
+
+// Publish 向所有订阅者发布消息
+func (b *MemoryBackend) Publish(payload string) error {
+	b.mutex.RLock()
+	subscribers := append([]func(string){}, b.subscribers...)
+	b.mutex.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(payload)
+	}
+	return nil
+}
+
+// Subscribe 订阅发布的消息
+func (b *MemoryBackend) Subscribe(fn func(payload string)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.subscribers = append(b.subscribers, fn)
+	return nil
+}
+
+// AppendHistory 追加一条历史记录
+func (b *MemoryBackend) AppendHistory(room, entry string, max int) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entries := append(b.history[room], entry)
+	if max > 0 && len(entries) > max {
+		entries = append([]string{}, entries[len(entries)-max:]...)
+	}
+	b.history[room] = entries
+	return nil
+}
+
+// History 获取最近的历史记录
+func (b *MemoryBackend) History(room string, limit int) ([]string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	entries := b.history[room]
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return append([]string{}, entries...), nil
+}
+
+// SwapHistory 历史记录没有变化时替换一条
+func (b *MemoryBackend) SwapHistory(room string, index int, old, entry string) (bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entries := b.history[room]
+	if index < 0 || index >= len(entries) || entries[index] != old {
+		return false, nil
+	}
+	entries[index] = entry
+	return true, nil
+}
+
+// NextSeq 获取房间的下一个消息序号
+func (b *MemoryBackend) NextSeq(room string) (int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.seqs[room]++
+	return b.seqs[room], nil
+}
+
+// AddIgnore 在屏蔽列表中加入用户名
+func (b *MemoryBackend) AddIgnore(owner, key, name string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.ignores[owner] == nil {
+		b.ignores[owner] = make(map[string]string)
+	}
+	b.ignores[owner][key] = name
+	return nil
+}
+
+// RemoveIgnore 从屏蔽列表中移除用户名
+func (b *MemoryBackend) RemoveIgnore(owner, key string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.ignores[owner], key)
+	return nil
+}
+
+// Ignores 获取屏蔽列表
+func (b *MemoryBackend) Ignores(owner string) (map[string]string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	result := make(map[string]string, len(b.ignores[owner]))
+	for key, name := range b.ignores[owner] {
+		result[key] = name
+	}
+	return result, nil
+}
+
+// UpdateRoom 在锁内读取并修改房间信息
+func (b *MemoryBackend) UpdateRoom(name string, fn func(data string, exists bool) (string, error)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	current, exists := b.rooms[name]
+	data, err := fn(current, exists)
+	if err != nil {
+		return err
+	}
+	b.rooms[name] = data
+	return nil
+}
+
+// Rooms 获取所有房间信息
+func (b *MemoryBackend) Rooms() (map[string]string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	result := make(map[string]string, len(b.rooms))
+	for name, data := range b.rooms {
+		result[name] = data
+	}
+	return result, nil
+}
+
+// SetMember 登记在线用户所在的房间
+func (b *MemoryBackend) SetMember(id, room string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.members[id] = room
+	return nil
+}
+
+// Members 获取所有在线用户所在的房间
+func (b *MemoryBackend) Members() (map[string]string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	result := make(map[string]string, len(b.members))
+	for id, room := range b.members {
+		result[id] = room
+	}
+	return result, nil
+}
+
+// SaveConversation 保存私聊群组
+func (b *MemoryBackend) SaveConversation(handle, data string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.convs[handle] = data
+	return nil
+}
+
+// DeleteConversation 删除私聊群组
+func (b *MemoryBackend) DeleteConversation(handle string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.convs, handle)
+	return nil
+}
+
+// Conversations 获取所有私聊群组
+func (b *MemoryBackend) Conversations() (map[string]string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	result := make(map[string]string, len(b.convs))
+	for handle, data := range b.convs {
+		result[handle] = data
+	}
+	return result, nil
+}
+
+// SaveBot 保存机器人
+func (b *MemoryBackend) SaveBot(key, data string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.bots[key] = data
+	return nil
+}
+
+// DeleteBot 删除机器人
+func (b *MemoryBackend) DeleteBot(key string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.bots, key)
+	return nil
+}
+
+// Bots 获取所有机器人
+func (b *MemoryBackend) Bots() (map[string]string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	result := make(map[string]string, len(b.bots))
+	for key, data := range b.bots {
+		result[key] = data
+	}
+	return result, nil
+}
+
+// SetReplyTarget 记录用户最近的私聊对象
+func (b *MemoryBackend) SetReplyTarget(owner, target string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.replies[owner] = target
+	return nil
+}
+
+// ReplyTarget 获取用户最近的私聊对象
+func (b *MemoryBackend) ReplyTarget(owner string) (string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.replies[owner], nil
+}
+
+// SetPublicKey 登记在线用户的公钥
+func (b *MemoryBackend) SetPublicKey(id, key string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.pubkeys[id] = key
+	return nil
+}
+
+// PublicKey 获取在线用户的公钥
+func (b *MemoryBackend) PublicKey(id string) (string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.pubkeys[id], nil
+}
+
+// SaveJob 保存定时任务
+func (b *MemoryBackend) SaveJob(id, data string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.jobs[id] = data
+	return nil
+}
+
+// DeleteJob 删除定时任务
+func (b *MemoryBackend) DeleteJob(id string) (bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	_, exists := b.jobs[id]
+	delete(b.jobs, id)
+	return exists, nil
+}
+
+// Jobs 获取所有定时任务
+func (b *MemoryBackend) Jobs() (map[string]string, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	result := make(map[string]string, len(b.jobs))
+	for id, data := range b.jobs {
+		result[id] = data
+	}
+	return result, nil
+}
+
+// Close 关闭后端
+func (b *MemoryBackend) Close() error {
+	return nil
+}
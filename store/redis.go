@@ -0,0 +1,830 @@
+package store
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"chatroom/utils"
+)
+
+// Redis中使用的键
+const (
+	redisOnlineKey      = "chatroom:online"    // 在线用户 (ID -> 用户名)
+	redisKeysKey        = "chatroom:keys"      // 在线用户 (ID -> 比较键)
+	redisNamesKey       = "chatroom:names"     // 用户名索引 (比较键 -> ID)
+	redisHistoryPrefix  = "chatroom:history:"  // 历史记录列表前缀
+	redisSeqPrefix      = "chatroom:seq:"      // 消息序号计数器前缀
+	redisIgnorePrefix   = "chatroom:ignore:"   // 屏蔽列表哈希表前缀，后跟用户ID
+	redisJobsKey        = "chatroom:jobs"      // 定时任务 (ID -> 编码)
+	redisRoomsKey       = "chatroom:rooms"     // 房间信息 (房间名 -> 编码)
+	redisMembersKey     = "chatroom:members"   // 在线用户所在房间 (ID -> 房间名)
+	redisConvsKey       = "chatroom:dms"       // 私聊群组 (代号 -> 编码)
+	redisRepliesKey     = "chatroom:replies"   // 最近的私聊对象 (用户ID -> 对象)
+	redisPubkeysKey     = "chatroom:pubkeys"   // 端到端加密公钥 (ID -> 公钥)
+	redisBotsKey        = "chatroom:bots"      // 机器人 (令牌哈希 -> 编码)
+	redisOwnersKey      = "chatroom:owners"    // 在线用户所在的实例 (ID -> 实例ID)
+	redisInstancePrefix = "chatroom:instance:" // 实例存活标记前缀，由心跳续期，实例崩溃后过期
+	redisChannel        = "chatroom:events"    // 消息扇出频道
+)
+
+// Redis连接参数
+const (
+	redisDialTimeout = 5 * time.Second // 建立连接的超时时间
+	redisIOTimeout   = 5 * time.Second // 每条命令读写的超时时间
+
+	redisRetryMin = 500 * time.Millisecond // 重新订阅的初始等待时间
+	redisRetryMax = 30 * time.Second       // 重新订阅的最长等待时间
+
+	redisInstanceTTL = 30 * time.Second // 实例存活标记的有效期，每三分之一有效期续期一次
+	redisTxRetries   = 10               // 事务因冲突重试的最大次数
+	redisTxBackoff   = time.Millisecond // 事务冲突后重试前的随机等待时间上限，每次重试加倍
+)
+
+// RedisBackend 基于Redis协议(RESP)的状态后端
+//
+// 在线状态保存在哈希表中，消息扇出使用PUBLISH/SUBSCRIBE，
+// 历史记录使用列表保存。多个聊天室实例连接同一个Redis即可共享状态。
+// 命令连接读写出错后被断开，下一条命令执行时重新连接。
+//
+// 每个实例有一个由心跳续期的存活标记，在线用户登记所在的实例；实例崩溃后标记过期，
+// 它的用户不再算作在线，用户名可以被重新使用，登记由其他实例的心跳清除。
+type RedisBackend struct {
+	addr        string                 // Redis地址
+	instance    string                 // 本实例的ID
+	ttl         time.Duration          // 存活标记的有效期
+	mutex       sync.Mutex             // 命令连接互斥锁
+	conn        net.Conn               // 命令连接，断开后为nil
+	reader      *bufio.Reader          // 命令连接读取器
+	writer      *bufio.Writer          // 命令连接写入器
+	subMutex    sync.Mutex             // 订阅互斥锁
+	subConn     net.Conn               // 订阅连接
+	subscribers []func(payload string) // 订阅者
+	closed      bool                   // 是否已关闭
+	done        chan struct{}          // 关闭后端时关闭，结束订阅协程
+	logger      *utils.Logger          // 日志记录器
+}
+
+// NewRedisBackend 连接Redis并创建状态后端
+func NewRedisBackend(addr string, logger *utils.Logger) (*RedisBackend, error) {
+	return newRedisBackend(addr, logger, redisInstanceTTL)
+}
+
+// newRedisBackend 创建状态后端，ttl 为本实例存活标记的有效期
+func newRedisBackend(addr string, logger *utils.Logger, ttl time.Duration) (*RedisBackend, error) {
+	instance, err := randomInstanceID()
+	if err != nil {
+		return nil, err
+	}
+	b := &RedisBackend{addr: addr, instance: instance, ttl: ttl, done: make(chan struct{}), logger: logger}
+	if err := b.beat(); err != nil {
+		b.Close()
+		return nil, err
+	}
+	go b.heartbeat()
+	return b, nil
+}
+
+// randomInstanceID 生成随机的实例ID
+func randomInstanceID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// command 执行一条命令的函数，do 或持有命令连接锁时的 doLocked
+type command func(args ...string) (interface{}, error)
+
+// do 在命令连接上执行一条命令，没有连接时先建立连接
+func (b *RedisBackend) do(args ...string) (interface{}, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err := b.connectLocked(); err != nil {
+		return nil, err
+	}
+	return b.doLocked(args...)
+}
+
+// connectLocked 没有命令连接时建立连接，调用者需持有 mutex
+func (b *RedisBackend) connectLocked() error {
+	if b.closed {
+		return fmt.Errorf("Redis后端已关闭")
+	}
+	if b.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", b.addr, redisDialTimeout)
+	if err != nil {
+		return fmt.Errorf("连接Redis失败: %v", err)
+	}
+	b.conn = conn
+	b.reader = bufio.NewReader(conn)
+	b.writer = bufio.NewWriter(conn)
+	return nil
+}
+
+// doLocked 在当前的命令连接上执行一条命令，调用者需持有 mutex
+//
+// 连接已断开时直接返回错误而不重新连接，事务中的命令不会落到没有 WATCH 的新连接上。
+func (b *RedisBackend) doLocked(args ...string) (interface{}, error) {
+	if b.conn == nil {
+		return nil, fmt.Errorf("Redis连接已断开")
+	}
+
+	b.conn.SetDeadline(time.Now().Add(redisIOTimeout))
+	err := writeCommand(b.writer, args...)
+	var reply interface{}
+	if err == nil {
+		reply, err = readReply(b.reader)
+	}
+	if err != nil {
+		if _, ok := err.(respError); ok {
+			// 错误回复已完整读取，连接仍然可用
+			return nil, err
+		}
+		// 读写失败后无法确定回复流的位置，断开连接，下一条命令重新连接
+		b.conn.Close()
+		b.conn = nil
+		return nil, err
+	}
+	return reply, nil
+}
+
+// transact 以 WATCH/MULTI/EXEC 原子地执行 prepare 生成的命令
+//
+// prepare 在监视 keys 之后读取当前状态并生成要执行的写命令，返回错误时放弃事务；
+// 被监视的键在 EXEC 之前被其他连接修改时随机等待一小段时间后重新执行 prepare，
+// 避免多个实例同步地反复冲突，最多重试 redisTxRetries 次。
+func (b *RedisBackend) transact(keys []string, prepare func(run command) ([][]string, error)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for attempt := 0; attempt < redisTxRetries; attempt++ {
+		if err := b.connectLocked(); err != nil {
+			return err
+		}
+		if _, err := b.doLocked(append([]string{"WATCH"}, keys...)...); err != nil {
+			return err
+		}
+		commands, err := prepare(b.doLocked)
+		if err != nil || len(commands) == 0 {
+			b.doLocked("UNWATCH")
+			return err
+		}
+
+		if _, err := b.doLocked("MULTI"); err != nil {
+			return err
+		}
+		for _, args := range commands {
+			if _, err := b.doLocked(args...); err != nil {
+				b.doLocked("DISCARD")
+				return err
+			}
+		}
+		reply, err := b.doLocked("EXEC")
+		if err != nil {
+			return err
+		}
+		if reply != nil {
+			return nil
+		}
+		// 被监视的键已被修改，重新读取后重试
+		time.Sleep(time.Duration(mrand.Int63n(int64(redisTxBackoff << attempt))))
+	}
+	return fmt.Errorf("Redis事务冲突次数过多")
+}
+
+// beat 设置或续期本实例的存活标记
+func (b *RedisBackend) beat() error {
+	ms := strconv.FormatInt(b.ttl.Milliseconds(), 10)
+	_, err := b.do("SET", redisInstancePrefix+b.instance, "1", "PX", ms)
+	return err
+}
+
+// heartbeat 定期续期本实例的存活标记，并清理已下线实例留下的在线用户
+func (b *RedisBackend) heartbeat() {
+	ticker := time.NewTicker(b.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		}
+		if err := b.beat(); err != nil {
+			b.logger.Error("续期Redis实例存活标记失败，超过 %v 后本实例的用户会被其他实例视为离线: %v", b.ttl, err)
+			continue
+		}
+		if err := b.reap(); err != nil {
+			b.logger.Warn("清理已下线实例的在线用户失败: %v", err)
+		}
+	}
+}
+
+// instanceAlive 判断实例是否存活，结果记录在 cache 中避免重复查询
+func (b *RedisBackend) instanceAlive(run command, instance string, cache map[string]bool) (bool, error) {
+	if instance == b.instance {
+		return true, nil
+	}
+	if instance == "" {
+		// 没有登记所在实例的用户是旧版本留下的
+		return false, nil
+	}
+	if alive, exists := cache[instance]; exists {
+		return alive, nil
+	}
+	reply, err := run("EXISTS", redisInstancePrefix+instance)
+	if err != nil {
+		return false, err
+	}
+	n, _ := reply.(int64)
+	cache[instance] = n > 0
+	return n > 0, nil
+}
+
+// userAlive 判断在线用户所在的实例是否存活
+func (b *RedisBackend) userAlive(run command, id string, cache map[string]bool) (bool, error) {
+	reply, err := run("HGET", redisOwnersKey, id)
+	if err != nil {
+		return false, err
+	}
+	instance, _ := reply.(string)
+	return b.instanceAlive(run, instance, cache)
+}
+
+// forgetCommands 清除用户登记的命令（不含用户名索引）
+func forgetCommands(id string) [][]string {
+	return [][]string{
+		{"HDEL", redisKeysKey, id},
+		{"HDEL", redisMembersKey, id},
+		{"HDEL", redisPubkeysKey, id},
+		{"HDEL", redisRepliesKey, id},
+		{"DEL", redisIgnorePrefix + id},
+		{"HDEL", redisOwnersKey, id},
+		{"HDEL", redisOnlineKey, id},
+	}
+}
+
+// Register 登记在线用户
+func (b *RedisBackend) Register(id, key, name string) error {
+	return b.claim(id, "", key, name)
+}
+
+// claim 在一个事务中占用用户名比较键并登记用户，同时释放用户原来的比较键
+//
+// 比较键被已下线实例的用户占用时，清除该用户的登记后占用。
+func (b *RedisBackend) claim(id, oldKey, key, name string) error {
+	return b.transact([]string{redisNamesKey}, func(run command) ([][]string, error) {
+		var commands [][]string
+
+		reply, err := run("HGET", redisNamesKey, key)
+		if err != nil {
+			return nil, err
+		}
+		if owner, _ := reply.(string); owner != "" && owner != id {
+			alive, err := b.userAlive(run, owner, make(map[string]bool))
+			if err != nil {
+				return nil, err
+			}
+			if alive {
+				return nil, ErrNameTaken
+			}
+			commands = append(commands, forgetCommands(owner)...)
+		}
+
+		if oldKey != "" && oldKey != key {
+			reply, err := run("HGET", redisNamesKey, oldKey)
+			if err != nil {
+				return nil, err
+			}
+			if owner, _ := reply.(string); owner == id {
+				commands = append(commands, []string{"HDEL", redisNamesKey, oldKey})
+			}
+		}
+
+		return append(commands,
+			[]string{"HSET", redisNamesKey, key, id},
+			[]string{"HSET", redisKeysKey, id, key},
+			[]string{"HSET", redisOwnersKey, id, b.instance},
+			[]string{"HSET", redisOnlineKey, id, name},
+		), nil
+	})
+}
+
+// Unregister 注销在线用户
+func (b *RedisBackend) Unregister(id string) error {
+	return b.transact([]string{redisNamesKey}, func(run command) ([][]string, error) {
+		commands := forgetCommands(id)
+
+		reply, err := run("HGET", redisKeysKey, id)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := reply.(string); ok {
+			reply, err := run("HGET", redisNamesKey, key)
+			if err != nil {
+				return nil, err
+			}
+			if owner, _ := reply.(string); owner == id {
+				commands = append(commands, []string{"HDEL", redisNamesKey, key})
+			}
+		}
+		return commands, nil
+	})
+}
+
+// Rename 修改在线用户的用户名
+func (b *RedisBackend) Rename(id, oldKey, newKey, newName string) error {
+	return b.claim(id, oldKey, newKey, newName)
+}
+
+// reap 清除已下线实例留下的在线用户和用户名索引
+func (b *RedisBackend) reap() error {
+	return b.transact([]string{redisNamesKey}, func(run command) ([][]string, error) {
+		owners, err := b.hash(run, redisOwnersKey)
+		if err != nil {
+			return nil, err
+		}
+		online, err := b.hash(run, redisOnlineKey)
+		if err != nil {
+			return nil, err
+		}
+		names, err := b.hash(run, redisNamesKey)
+		if err != nil {
+			return nil, err
+		}
+
+		var commands [][]string
+		cache := make(map[string]bool)
+		dead := func(id string) (bool, error) {
+			alive, err := b.instanceAlive(run, owners[id], cache)
+			return !alive, err
+		}
+		for id := range online {
+			if gone, err := dead(id); err != nil {
+				return nil, err
+			} else if gone {
+				commands = append(commands, forgetCommands(id)...)
+			}
+		}
+		for key, id := range names {
+			if gone, err := dead(id); err != nil {
+				return nil, err
+			} else if gone {
+				commands = append(commands, []string{"HDEL", redisNamesKey, key})
+			}
+		}
+		return commands, nil
+	})
+}
+
+// hash 读取整个哈希表
+func (b *RedisBackend) hash(run command, key string) (map[string]string, error) {
+	reply, err := run("HGETALL", key)
+	if err != nil {
+		return nil, err
+	}
+	return hashReply(reply), nil
+}
+
+// Lookup 按用户名比较键查找用户ID，用户所在实例已下线时视为不存在
+func (b *RedisBackend) Lookup(key string) (string, bool, error) {
+	reply, err := b.do("HGET", redisNamesKey, key)
+	if err != nil {
+		return "", false, err
+	}
+	id, ok := reply.(string)
+	if !ok {
+		return "", false, nil
+	}
+	alive, err := b.userAlive(b.do, id, make(map[string]bool))
+	if err != nil || !alive {
+		return "", false, err
+	}
+	return id, true, nil
+}
+
+// Online 获取所有在线用户，不包括已下线实例留下的用户
+func (b *RedisBackend) Online() (map[string]string, error) {
+	online, err := b.hash(b.do, redisOnlineKey)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := b.hash(b.do, redisOwnersKey)
+	if err != nil {
+		return nil, err
+	}
+	cache := make(map[string]bool)
+	for id := range online {
+		alive, err := b.instanceAlive(b.do, owners[id], cache)
+		if err != nil {
+			return nil, err
+		}
+		if !alive {
+			delete(online, id)
+		}
+	}
+	return online, nil
+}
+
+// hashReply 将HGETALL的回复转换为映射
+func hashReply(reply interface{}) map[string]string {
+	items, _ := reply.([]interface{})
+	result := make(map[string]string, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		field, _ := items[i].(string)
+		value, _ := items[i+1].(string)
+		result[field] = value
+	}
+	return result
+}
+
+// Publish 向所有订阅者发布消息
+func (b *RedisBackend) Publish(payload string) error {
+	_, err := b.do("PUBLISH", redisChannel, payload)
+	return err
+}
+
+// Subscribe 订阅发布的消息
+//
+// 第一次订阅时建立独立的订阅连接并启动投递协程。
+func (b *RedisBackend) Subscribe(fn func(payload string)) error {
+	b.subMutex.Lock()
+	defer b.subMutex.Unlock()
+
+	b.subscribers = append(b.subscribers, fn)
+	if b.subConn != nil {
+		return nil
+	}
+
+	reader, err := b.subscribeLocked()
+	if err != nil {
+		return err
+	}
+	go b.receive(reader)
+	return nil
+}
+
+// subscribeLocked 建立订阅连接并订阅消息频道，调用者需持有 subMutex
+func (b *RedisBackend) subscribeLocked() (*bufio.Reader, error) {
+	if b.isClosed() {
+		return nil, fmt.Errorf("Redis后端已关闭")
+	}
+	conn, err := net.DialTimeout("tcp", b.addr, redisDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("连接Redis失败: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(redisIOTimeout))
+	if err := writeCommand(bufio.NewWriter(conn), "SUBSCRIBE", redisChannel); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := readReply(reader); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("订阅失败: %v", err)
+	}
+	// 订阅连接可能长时间没有消息，不设读取超时
+	conn.SetDeadline(time.Time{})
+
+	b.subConn = conn
+	return reader, nil
+}
+
+// resubscribe 订阅连接断开后按退避间隔重新订阅，后端关闭时返回nil
+func (b *RedisBackend) resubscribe() *bufio.Reader {
+	delay := redisRetryMin
+	for {
+		select {
+		case <-b.done:
+			return nil
+		case <-time.After(delay):
+		}
+
+		b.subMutex.Lock()
+		reader, err := b.subscribeLocked()
+		b.subMutex.Unlock()
+		if err == nil {
+			b.logger.Info("已重新订阅Redis消息频道")
+			return reader
+		}
+
+		if delay *= 2; delay > redisRetryMax {
+			delay = redisRetryMax
+		}
+		b.logger.Error("重新订阅Redis消息频道失败，%v 后重试: %v", delay, err)
+	}
+}
+
+// receive 接收订阅消息并投递给订阅者
+//
+// 本实例用户的消息也经订阅送达，订阅连接断开时记录错误并重新订阅，断开期间发布的消息会丢失。
+func (b *RedisBackend) receive(reader *bufio.Reader) {
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			if b.isClosed() {
+				return
+			}
+			b.subMutex.Lock()
+			b.subConn.Close()
+			b.subMutex.Unlock()
+
+			b.logger.Error("Redis订阅连接断开，消息暂时无法送达: %v", err)
+			if reader = b.resubscribe(); reader == nil {
+				return
+			}
+			continue
+		}
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 3 || items[0] != "message" {
+			continue
+		}
+		payload, _ := items[2].(string)
+
+		b.subMutex.Lock()
+		subscribers := append([]func(string){}, b.subscribers...)
+		b.subMutex.Unlock()
+
+		for _, fn := range subscribers {
+			fn(payload)
+		}
+	}
+}
+
+// AppendHistory 追加一条历史记录
+func (b *RedisBackend) AppendHistory(room, entry string, max int) error {
+	key := redisHistoryPrefix + room
+	if _, err := b.do("RPUSH", key, entry); err != nil {
+		return err
+	}
+	if max > 0 {
+		if _, err := b.do("LTRIM", key, strconv.Itoa(-max), "-1"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// History 获取最近的历史记录
+func (b *RedisBackend) History(room string, limit int) ([]string, error) {
+	start := "0"
+	if limit > 0 {
+		start = strconv.Itoa(-limit)
+	}
+	reply, err := b.do("LRANGE", redisHistoryPrefix+room, start, "-1")
+	if err != nil {
+		return nil, err
+	}
+	items, _ := reply.([]interface{})
+	entries := make([]string, 0, len(items))
+	for _, item := range items {
+		if entry, ok := item.(string); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// SwapHistory 在一个事务中检查并替换一条历史记录
+func (b *RedisBackend) SwapHistory(room string, index int, old, entry string) (bool, error) {
+	key := redisHistoryPrefix + room
+	position := strconv.Itoa(index)
+	swapped := false
+	err := b.transact([]string{key}, func(run command) ([][]string, error) {
+		reply, err := run("LINDEX", key, position)
+		if err != nil {
+			return nil, err
+		}
+		current, ok := reply.(string)
+		swapped = ok && current == old
+		if !swapped {
+			return nil, nil
+		}
+		return [][]string{{"LSET", key, position, entry}}, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return swapped, nil
+}
+
+// NextSeq 获取房间的下一个消息序号
+func (b *RedisBackend) NextSeq(room string) (int64, error) {
+	reply, err := b.do("INCR", redisSeqPrefix+room)
+	if err != nil {
+		return 0, err
+	}
+	seq, _ := reply.(int64)
+	return seq, nil
+}
+
+// AddIgnore 在屏蔽列表中加入用户名
+func (b *RedisBackend) AddIgnore(owner, key, name string) error {
+	_, err := b.do("HSET", redisIgnorePrefix+owner, key, name)
+	return err
+}
+
+// RemoveIgnore 从屏蔽列表中移除用户名
+func (b *RedisBackend) RemoveIgnore(owner, key string) error {
+	_, err := b.do("HDEL", redisIgnorePrefix+owner, key)
+	return err
+}
+
+// Ignores 获取屏蔽列表
+func (b *RedisBackend) Ignores(owner string) (map[string]string, error) {
+	reply, err := b.do("HGETALL", redisIgnorePrefix+owner)
+	if err != nil {
+		return nil, err
+	}
+	return hashReply(reply), nil
+}
+
+// UpdateRoom 在一个事务中读取并修改房间信息
+func (b *RedisBackend) UpdateRoom(name string, fn func(data string, exists bool) (string, error)) error {
+	return b.transact([]string{redisRoomsKey}, func(run command) ([][]string, error) {
+		reply, err := run("HGET", redisRoomsKey, name)
+		if err != nil {
+			return nil, err
+		}
+		current, exists := reply.(string)
+		data, err := fn(current, exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists && data == current {
+			return nil, nil
+		}
+		return [][]string{{"HSET", redisRoomsKey, name, data}}, nil
+	})
+}
+
+// Rooms 获取所有房间信息
+func (b *RedisBackend) Rooms() (map[string]string, error) {
+	reply, err := b.do("HGETALL", redisRoomsKey)
+	if err != nil {
+		return nil, err
+	}
+	return hashReply(reply), nil
+}
+
+// SetMember 登记在线用户所在的房间
+func (b *RedisBackend) SetMember(id, room string) error {
+	_, err := b.do("HSET", redisMembersKey, id, room)
+	return err
+}
+
+// Members 获取所有在线用户所在的房间
+func (b *RedisBackend) Members() (map[string]string, error) {
+	reply, err := b.do("HGETALL", redisMembersKey)
+	if err != nil {
+		return nil, err
+	}
+	return hashReply(reply), nil
+}
+
+// SaveConversation 保存私聊群组
+func (b *RedisBackend) SaveConversation(handle, data string) error {
+	_, err := b.do("HSET", redisConvsKey, handle, data)
+	return err
+}
+
+// DeleteConversation 删除私聊群组
+func (b *RedisBackend) DeleteConversation(handle string) error {
+	_, err := b.do("HDEL", redisConvsKey, handle)
+	return err
+}
+
+// Conversations 获取所有私聊群组
+func (b *RedisBackend) Conversations() (map[string]string, error) {
+	reply, err := b.do("HGETALL", redisConvsKey)
+	if err != nil {
+		return nil, err
+	}
+	return hashReply(reply), nil
+}
+
+// SaveBot 保存机器人
+func (b *RedisBackend) SaveBot(key, data string) error {
+	_, err := b.do("HSET", redisBotsKey, key, data)
+	return err
+}
+
+// DeleteBot 删除机器人
+func (b *RedisBackend) DeleteBot(key string) error {
+	_, err := b.do("HDEL", redisBotsKey, key)
+	return err
+}
+
+// Bots 获取所有机器人
+func (b *RedisBackend) Bots() (map[string]string, error) {
+	reply, err := b.do("HGETALL", redisBotsKey)
+	if err != nil {
+		return nil, err
+	}
+	return hashReply(reply), nil
+}
+
+// SetReplyTarget 记录用户最近的私聊对象
+func (b *RedisBackend) SetReplyTarget(owner, target string) error {
+	_, err := b.do("HSET", redisRepliesKey, owner, target)
+	return err
+}
+
+// ReplyTarget 获取用户最近的私聊对象
+func (b *RedisBackend) ReplyTarget(owner string) (string, error) {
+	reply, err := b.do("HGET", redisRepliesKey, owner)
+	if err != nil {
+		return "", err
+	}
+	target, _ := reply.(string)
+	return target, nil
+}
+
+// SetPublicKey 登记在线用户的公钥
+func (b *RedisBackend) SetPublicKey(id, key string) error {
+	_, err := b.do("HSET", redisPubkeysKey, id, key)
+	return err
+}
+
+// PublicKey 获取在线用户的公钥
+func (b *RedisBackend) PublicKey(id string) (string, error) {
+	reply, err := b.do("HGET", redisPubkeysKey, id)
+	if err != nil {
+		return "", err
+	}
+	key, _ := reply.(string)
+	return key, nil
+}
+
+// SaveJob 保存定时任务
+func (b *RedisBackend) SaveJob(id, data string) error {
+	_, err := b.do("HSET", redisJobsKey, id, data)
+	return err
+}
+
+// DeleteJob 删除定时任务，HDEL 是原子的，只有一个实例能删除成功
+func (b *RedisBackend) DeleteJob(id string) (bool, error) {
+	reply, err := b.do("HDEL", redisJobsKey, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := reply.(int64)
+	return n > 0, nil
+}
+
+// Jobs 获取所有定时任务
+func (b *RedisBackend) Jobs() (map[string]string, error) {
+	reply, err := b.do("HGETALL", redisJobsKey)
+	if err != nil {
+		return nil, err
+	}
+	return hashReply(reply), nil
+}
+
+// isClosed 判断后端是否已关闭
+func (b *RedisBackend) isClosed() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close 关闭后端
+func (b *RedisBackend) Close() error {
+	// 删除存活标记，其他实例立即把本实例的用户视为离线
+	b.do("DEL", redisInstancePrefix+b.instance)
+
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return nil
+	}
+	b.closed = true
+	var err error
+	if b.conn != nil {
+		err = b.conn.Close()
+		b.conn = nil
+	}
+	b.mutex.Unlock()
+
+	close(b.done)
+	b.subMutex.Lock()
+	if b.subConn != nil {
+		b.subConn.Close()
+	}
+	b.subMutex.Unlock()
+	return err
+}
@@ -0,0 +1,435 @@
+package store
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RESPServer 进程内的Redis协议替身
+//
+// 它只实现了RedisBackend用到的命令子集，数据保存在内存中，
+// 用于在没有真实Redis的环境下运行后端测试。
+type RESPServer struct {
+	listener net.Listener                  // 监听器
+	mutex    sync.Mutex                    // 互斥锁
+	strings  map[string]string             // 字符串键
+	hashes   map[string]map[string]string  // 哈希键
+	lists    map[string][]string           // 列表键
+	channels map[string]map[*respConn]bool // 频道订阅者
+	conns    map[*respConn]bool            // 所有连接
+	versions map[string]int64              // 键的修改次数，供 WATCH 判断
+	expires  map[string]time.Time          // 键的过期时间
+}
+
+// respConn RESP替身的客户端连接
+type respConn struct {
+	conn   net.Conn      // 网络连接
+	mutex  sync.Mutex    // 写入互斥锁
+	writer *bufio.Writer // 写入器
+
+	watched map[string]int64 // WATCH 的键及当时的修改次数
+	multi   bool             // 是否在 MULTI 中
+	queued  [][]string       // MULTI 中排队的命令
+}
+
+// NewRESPServer 在指定地址启动RESP替身，地址为空时监听随机本地端口
+func NewRESPServer(addr string) (*RESPServer, error) {
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("启动RESP服务失败: %v", err)
+	}
+
+	s := &RESPServer{
+		listener: listener,
+		strings:  make(map[string]string),
+		hashes:   make(map[string]map[string]string),
+		lists:    make(map[string][]string),
+		channels: make(map[string]map[*respConn]bool),
+		conns:    make(map[*respConn]bool),
+		versions: make(map[string]int64),
+		expires:  make(map[string]time.Time),
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Addr 获取监听地址
+func (s *RESPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close 关闭RESP替身
+func (s *RESPServer) Close() error {
+	err := s.listener.Close()
+
+	s.mutex.Lock()
+	for c := range s.conns {
+		c.conn.Close()
+	}
+	s.mutex.Unlock()
+	return err
+}
+
+// serve 接受连接
+func (s *RESPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &respConn{conn: conn, writer: bufio.NewWriter(conn)}
+
+		s.mutex.Lock()
+		s.conns[c] = true
+		s.mutex.Unlock()
+
+		go s.handle(c)
+	}
+}
+
+// handle 处理客户端连接
+func (s *RESPServer) handle(c *respConn) {
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, c)
+		for _, subscribers := range s.channels {
+			delete(subscribers, c)
+		}
+		s.mutex.Unlock()
+		c.conn.Close()
+	}()
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		items, ok := reply.([]interface{})
+		if !ok || len(items) == 0 {
+			c.write("-ERR 协议错误\r\n")
+			continue
+		}
+
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		c.write(s.execute(c, strings.ToUpper(args[0]), args[1:]))
+	}
+}
+
+// write 向客户端写入原始回复
+func (c *respConn) write(reply string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.writer.WriteString(reply)
+	c.writer.Flush()
+}
+
+// execute 执行一条命令并返回编码后的回复，处理事务命令
+func (s *RESPServer) execute(c *respConn, cmd string, args []string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// 过期的键在任何命令之前删除
+	now := time.Now()
+	for key, at := range s.expires {
+		if !now.Before(at) {
+			s.deleteKey(key)
+		}
+	}
+
+	switch cmd {
+	case "WATCH":
+		if c.watched == nil {
+			c.watched = make(map[string]int64)
+		}
+		for _, key := range args {
+			c.watched[key] = s.versions[key]
+		}
+		return "+OK\r\n"
+	case "UNWATCH":
+		c.watched = nil
+		return "+OK\r\n"
+	case "MULTI":
+		if c.multi {
+			return "-ERR MULTI 不能嵌套\r\n"
+		}
+		c.multi = true
+		c.queued = nil
+		return "+OK\r\n"
+	case "DISCARD":
+		c.multi, c.queued, c.watched = false, nil, nil
+		return "+OK\r\n"
+	case "EXEC":
+		if !c.multi {
+			return "-ERR 没有 MULTI\r\n"
+		}
+		queued, watched := c.queued, c.watched
+		c.multi, c.queued, c.watched = false, nil, nil
+		for key, version := range watched {
+			if s.versions[key] != version {
+				return "*-1\r\n"
+			}
+		}
+		var reply strings.Builder
+		fmt.Fprintf(&reply, "*%d\r\n", len(queued))
+		for _, args := range queued {
+			reply.WriteString(s.command(c, strings.ToUpper(args[0]), args[1:]))
+		}
+		return reply.String()
+	}
+
+	if c.multi {
+		c.queued = append(c.queued, append([]string{cmd}, args...))
+		return "+QUEUED\r\n"
+	}
+	return s.command(c, cmd, args)
+}
+
+// respWrites 会修改键的命令，执行后增加键的修改次数
+var respWrites = map[string]bool{
+	"SET": true, "INCR": true, "DEL": true, "HSET": true, "HSETNX": true, "HDEL": true,
+	"RPUSH": true, "LTRIM": true, "LSET": true,
+}
+
+// command 执行一条普通命令并返回编码后的回复
+func (s *RESPServer) command(c *respConn, cmd string, args []string) string {
+	if respWrites[cmd] && len(args) > 0 {
+		keys := args[:1]
+		if cmd == "DEL" {
+			keys = args
+		}
+		for _, key := range keys {
+			s.versions[key]++
+		}
+	}
+
+	switch {
+	case cmd == "PING":
+		return "+PONG\r\n"
+	case cmd == "GET" && len(args) == 1:
+		value, exists := s.strings[args[0]]
+		if !exists {
+			return "$-1\r\n"
+		}
+		return bulkString(value)
+	case cmd == "SET" && (len(args) == 2 || len(args) == 4):
+		s.strings[args[0]] = args[1]
+		delete(s.expires, args[0])
+		if len(args) == 4 {
+			n, err := strconv.Atoi(args[3])
+			if err != nil || n <= 0 {
+				return "-ERR 过期时间无效\r\n"
+			}
+			unit := time.Second
+			if strings.ToUpper(args[2]) == "PX" {
+				unit = time.Millisecond
+			}
+			s.expires[args[0]] = time.Now().Add(time.Duration(n) * unit)
+		}
+		return "+OK\r\n"
+	case cmd == "EXISTS" && len(args) >= 1:
+		var n int64
+		for _, key := range args {
+			_, isString := s.strings[key]
+			_, isHash := s.hashes[key]
+			_, isList := s.lists[key]
+			if isString || isHash || isList {
+				n++
+			}
+		}
+		return integer(n)
+	case cmd == "INCR" && len(args) == 1:
+		n, _ := strconv.ParseInt(s.strings[args[0]], 10, 64)
+		n++
+		s.strings[args[0]] = strconv.FormatInt(n, 10)
+		return integer(n)
+	case cmd == "DEL" && len(args) >= 1:
+		var n int64
+		for _, key := range args {
+			if s.deleteKey(key) {
+				n++
+			}
+		}
+		return integer(n)
+	case cmd == "HSET" && len(args) >= 3 && len(args)%2 == 1:
+		hash := s.hash(args[0])
+		var n int64
+		for i := 1; i+1 < len(args); i += 2 {
+			if _, exists := hash[args[i]]; !exists {
+				n++
+			}
+			hash[args[i]] = args[i+1]
+		}
+		return integer(n)
+	case cmd == "HSETNX" && len(args) == 3:
+		hash := s.hash(args[0])
+		if _, exists := hash[args[1]]; exists {
+			return integer(0)
+		}
+		hash[args[1]] = args[2]
+		return integer(1)
+	case cmd == "HGET" && len(args) == 2:
+		value, exists := s.hashes[args[0]][args[1]]
+		if !exists {
+			return "$-1\r\n"
+		}
+		return bulkString(value)
+	case cmd == "HDEL" && len(args) >= 2:
+		var n int64
+		for _, field := range args[1:] {
+			if _, exists := s.hashes[args[0]][field]; exists {
+				delete(s.hashes[args[0]], field)
+				n++
+			}
+		}
+		return integer(n)
+	case cmd == "HGETALL" && len(args) == 1:
+		hash := s.hashes[args[0]]
+		items := make([]string, 0, len(hash)*2)
+		for field, value := range hash {
+			items = append(items, field, value)
+		}
+		return array(items)
+	case cmd == "RPUSH" && len(args) >= 2:
+		s.lists[args[0]] = append(s.lists[args[0]], args[1:]...)
+		return integer(int64(len(s.lists[args[0]])))
+	case cmd == "LRANGE" && len(args) == 3:
+		list := s.lists[args[0]]
+		start, stop, ok := listRange(len(list), args[1], args[2])
+		if !ok {
+			return "-ERR 参数必须是整数\r\n"
+		}
+		return array(list[start:stop])
+	case cmd == "LTRIM" && len(args) == 3:
+		list := s.lists[args[0]]
+		start, stop, ok := listRange(len(list), args[1], args[2])
+		if !ok {
+			return "-ERR 参数必须是整数\r\n"
+		}
+		s.lists[args[0]] = append([]string{}, list[start:stop]...)
+		return "+OK\r\n"
+	case cmd == "LINDEX" && len(args) == 2:
+		list := s.lists[args[0]]
+		index, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "-ERR 参数必须是整数\r\n"
+		}
+		if index < 0 {
+			index += len(list)
+		}
+		if index < 0 || index >= len(list) {
+			return "$-1\r\n"
+		}
+		return bulkString(list[index])
+	case cmd == "LSET" && len(args) == 3:
+		list := s.lists[args[0]]
+		index, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "-ERR 参数必须是整数\r\n"
+		}
+		if index < 0 {
+			index += len(list)
+		}
+		if index < 0 || index >= len(list) {
+			return "-ERR 索引越界\r\n"
+		}
+		list[index] = args[2]
+		return "+OK\r\n"
+	case cmd == "PUBLISH" && len(args) == 2:
+		subscribers := s.channels[args[0]]
+		message := array([]string{"message", args[0], args[1]})
+		for sub := range subscribers {
+			sub.write(message)
+		}
+		return integer(int64(len(subscribers)))
+	case cmd == "SUBSCRIBE" && len(args) >= 1:
+		var reply strings.Builder
+		for i, channel := range args {
+			if s.channels[channel] == nil {
+				s.channels[channel] = make(map[*respConn]bool)
+			}
+			s.channels[channel][c] = true
+			fmt.Fprintf(&reply, "*3\r\n%s%s%s", bulkString("subscribe"), bulkString(channel), integer(int64(i+1)))
+		}
+		return reply.String()
+	default:
+		return fmt.Sprintf("-ERR 不支持的命令或参数数量错误 '%s'\r\n", cmd)
+	}
+}
+
+// hash 获取或创建哈希键
+func (s *RESPServer) hash(key string) map[string]string {
+	if s.hashes[key] == nil {
+		s.hashes[key] = make(map[string]string)
+	}
+	return s.hashes[key]
+}
+
+// deleteKey 删除任意类型的键
+func (s *RESPServer) deleteKey(key string) bool {
+	_, isString := s.strings[key]
+	_, isHash := s.hashes[key]
+	_, isList := s.lists[key]
+	delete(s.strings, key)
+	delete(s.hashes, key)
+	delete(s.lists, key)
+	delete(s.expires, key)
+	s.versions[key]++
+	return isString || isHash || isList
+}
+
+// listRange 将Redis风格的起止下标（可为负数，闭区间）转换为切片区间
+func listRange(length int, startArg, stopArg string) (int, int, bool) {
+	start, err1 := strconv.Atoi(startArg)
+	stop, err2 := strconv.Atoi(stopArg)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop {
+		return 0, 0, true
+	}
+	return start, stop + 1, true
+}
+
+// bulkString 编码批量字符串
+func bulkString(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// integer 编码整数
+func integer(n int64) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+// array 编码批量字符串数组
+func array(items []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(items))
+	for _, item := range items {
+		b.WriteString(bulkString(item))
+	}
+	return b.String()
+}
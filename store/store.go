@@ -0,0 +1,128 @@
+package store
+
+import "errors"
+
+// Backend 状态存储后端接口
+//
+// 它抽象了各组件依赖的共享状态：在线用户登记、用户名查找、
+// 消息扇出（发布/订阅）以及历史消息列表等。单机部署使用内存实现，
+// 多实例部署可以使用基于Redis协议(RESP)的实现共享同一份状态。
+// 各组件只依赖下面按功能划分的小接口。
+type Backend interface {
+	Presence
+	Bus
+	HistoryStore
+	IgnoreStore
+	RoomStore
+	ConversationStore
+	KeyStore
+	BotStore
+	JobStore
+
+	// Close 关闭后端
+	Close() error
+}
+
+// Presence 在线用户登记
+type Presence interface {
+	// Register 登记在线用户，key 是用户名的比较键，已被占用时返回 ErrNameTaken
+	Register(id, key, name string) error
+	// Unregister 注销在线用户
+	Unregister(id string) error
+	// Rename 修改在线用户的用户名，新用户名已被占用时返回 ErrNameTaken
+	Rename(id, oldKey, newKey, newName string) error
+	// Lookup 按用户名比较键查找用户ID
+	Lookup(key string) (string, bool, error)
+	// Online 获取所有在线用户 (ID -> 用户名)
+	Online() (map[string]string, error)
+}
+
+// Bus 实例之间的消息扇出
+type Bus interface {
+	// Publish 向所有订阅者发布消息
+	Publish(payload string) error
+	// Subscribe 订阅发布的消息，回调在后端的投递协程中执行
+	Subscribe(fn func(payload string)) error
+}
+
+// HistoryStore 房间的历史消息列表
+type HistoryStore interface {
+	// AppendHistory 追加一条历史记录，并只保留最近 max 条
+	AppendHistory(room, entry string, max int) error
+	// History 获取最近 limit 条历史记录（按时间顺序），limit<=0 表示全部
+	History(room string, limit int) ([]string, error)
+	// SwapHistory 第 index 条历史记录（从最早的一条开始计数）仍为 old 时原子地替换为 entry，返回是否替换；
+	// 其他实例追加记录使列表被截断、位置移动时返回false，调用者重新查找后重试
+	SwapHistory(room string, index int, old, entry string) (bool, error)
+	// NextSeq 获取房间的下一个消息序号，从1开始，各实例共享
+	NextSeq(room string) (int64, error)
+}
+
+// IgnoreStore 用户的屏蔽列表
+type IgnoreStore interface {
+	// AddIgnore 在用户 owner 的屏蔽列表中加入用户名，owner 是用户ID，key 是用户名比较键；
+	// 屏蔽列表只属于一次连接，用户注销时清除
+	AddIgnore(owner, key, name string) error
+	// RemoveIgnore 从用户 owner 的屏蔽列表中移除用户名
+	RemoveIgnore(owner, key string) error
+	// Ignores 获取用户 owner 的屏蔽列表 (比较键 -> 用户名)
+	Ignores(owner string) (map[string]string, error)
+}
+
+// RoomStore 房间信息和在线用户所在的房间
+type RoomStore interface {
+	// UpdateRoom 原子地读取并修改房间信息（data 为房间的编码）：fn 收到当前的编码，房间不存在时 exists 为false，
+	// 返回新的编码，返回错误时不保存；Redis 后端与其他实例冲突时重新读取并再次调用 fn
+	UpdateRoom(name string, fn func(data string, exists bool) (string, error)) error
+	// Rooms 获取所有房间信息 (房间名 -> 编码)
+	Rooms() (map[string]string, error)
+	// SetMember 登记在线用户当前所在的房间，Unregister 时一并清除
+	SetMember(id, room string) error
+	// Members 获取所有在线用户所在的房间 (ID -> 房间名)
+	Members() (map[string]string, error)
+}
+
+// ConversationStore 私聊群组和最近的私聊对象
+type ConversationStore interface {
+	// SaveConversation 保存私聊群组（data 为群组的编码），已存在时覆盖
+	SaveConversation(handle, data string) error
+	// DeleteConversation 删除私聊群组
+	DeleteConversation(handle string) error
+	// Conversations 获取所有私聊群组 (代号 -> 编码)
+	Conversations() (map[string]string, error)
+	// SetReplyTarget 记录用户 owner 最近的私聊对象，供 \r 回复；owner 是用户ID，用户注销时清除
+	SetReplyTarget(owner, target string) error
+	// ReplyTarget 获取用户 owner 最近的私聊对象，没有时返回空字符串
+	ReplyTarget(owner string) (string, error)
+}
+
+// KeyStore 在线用户的端到端加密公钥
+type KeyStore interface {
+	// SetPublicKey 登记在线用户发布的端到端加密公钥，Unregister 时一并清除
+	SetPublicKey(id, key string) error
+	// PublicKey 获取在线用户发布的公钥，没有时返回空字符串
+	PublicKey(id string) (string, error)
+}
+
+// BotStore 入站 webhook 机器人
+type BotStore interface {
+	// SaveBot 保存机器人（data 为机器人的编码），key 是令牌的哈希，已存在时覆盖
+	SaveBot(key, data string) error
+	// DeleteBot 删除机器人
+	DeleteBot(key string) error
+	// Bots 获取所有机器人 (令牌哈希 -> 编码)
+	Bots() (map[string]string, error)
+}
+
+// JobStore 定时任务
+type JobStore interface {
+	// SaveJob 保存定时任务（data 为任务的编码），已存在时覆盖
+	SaveJob(id, data string) error
+	// DeleteJob 删除定时任务，返回任务是否存在；多个实例同时删除时只有一个返回true
+	DeleteJob(id string) (bool, error)
+	// Jobs 获取所有定时任务 (ID -> 编码)
+	Jobs() (map[string]string, error)
+}
+
+// ErrNameTaken 用户名已被占用
+var ErrNameTaken = errors.New("用户名已被使用")
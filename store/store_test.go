@@ -0,0 +1,449 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"chatroom/utils"
+)
+
+// forEachBackend 对内存后端和连接到RESP替身的Redis后端分别运行同一组测试
+func forEachBackend(t *testing.T, fn func(t *testing.T, b Backend)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemoryBackend())
+	})
+	t.Run("redis", func(t *testing.T) {
+		fn(t, newTestRedis(t))
+	})
+}
+
+// newTestRedis 启动RESP替身并创建连接到它的Redis后端，测试结束时关闭
+func newTestRedis(t *testing.T) *RedisBackend {
+	t.Helper()
+	server, err := NewRESPServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return newTestRedisAt(t, server.Addr())
+}
+
+// newTestRedisAt 创建连接到指定地址的Redis后端，测试结束时关闭
+func newTestRedisAt(t *testing.T, addr string) *RedisBackend {
+	t.Helper()
+	b, err := NewRedisBackend(addr, utils.NewLogger(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestBackendRegister(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b Backend) {
+		if err := b.Register("u1", "alice", "Alice"); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		if err := b.Register("u2", "alice", "ALICE"); err != ErrNameTaken {
+			t.Fatalf("重复登记: got %v, want ErrNameTaken", err)
+		}
+		// 同一用户重复登记同一个名字不算冲突
+		if err := b.Register("u1", "alice", "Alice"); err != nil {
+			t.Fatalf("重复登记自己: %v", err)
+		}
+		if err := b.Register("u2", "bob", "Bob"); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+
+		if id, ok, err := b.Lookup("alice"); err != nil || !ok || id != "u1" {
+			t.Fatalf("Lookup(alice) = %q, %v, %v", id, ok, err)
+		}
+		online, err := b.Online()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]string{"u1": "Alice", "u2": "Bob"}; !reflect.DeepEqual(online, want) {
+			t.Fatalf("Online = %v, want %v", online, want)
+		}
+
+		if err := b.Rename("u2", "bob", "alice", "Alice"); err != ErrNameTaken {
+			t.Fatalf("改名为已占用的名字: got %v, want ErrNameTaken", err)
+		}
+		if err := b.Rename("u1", "alice", "carol", "Carol"); err != nil {
+			t.Fatalf("Rename: %v", err)
+		}
+		if _, ok, _ := b.Lookup("alice"); ok {
+			t.Fatal("改名后旧名字仍被占用")
+		}
+		if id, ok, _ := b.Lookup("carol"); !ok || id != "u1" {
+			t.Fatalf("Lookup(carol) = %q, %v", id, ok)
+		}
+
+		b.SetMember("u1", "lobby")
+		b.SetPublicKey("u1", "key")
+		if err := b.Unregister("u1"); err != nil {
+			t.Fatalf("Unregister: %v", err)
+		}
+		if _, ok, _ := b.Lookup("carol"); ok {
+			t.Fatal("注销后名字仍被占用")
+		}
+		if key, _ := b.PublicKey("u1"); key != "" {
+			t.Fatalf("注销后公钥仍存在: %q", key)
+		}
+		members, _ := b.Members()
+		if _, exists := members["u1"]; exists {
+			t.Fatal("注销后所在房间仍存在")
+		}
+		online, _ = b.Online()
+		if want := map[string]string{"u2": "Bob"}; !reflect.DeepEqual(online, want) {
+			t.Fatalf("Online = %v, want %v", online, want)
+		}
+	})
+}
+
+func TestBackendPublish(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b Backend) {
+		received := make(chan string, 10)
+		if err := b.Subscribe(func(payload string) { received <- payload }); err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+		for _, payload := range []string{"one", "two", "three"} {
+			if err := b.Publish(payload); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+		}
+		for _, want := range []string{"one", "two", "three"} {
+			select {
+			case got := <-received:
+				if got != want {
+					t.Fatalf("收到 %q, want %q", got, want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("没有收到 %q", want)
+			}
+		}
+	})
+}
+
+func TestBackendHistory(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b Backend) {
+		for _, entry := range []string{"a", "b", "c", "d"} {
+			if err := b.AppendHistory("lobby", entry, 3); err != nil {
+				t.Fatalf("AppendHistory: %v", err)
+			}
+		}
+		b.AppendHistory("other", "x", 3)
+
+		if got, _ := b.History("lobby", 0); !reflect.DeepEqual(got, []string{"b", "c", "d"}) {
+			t.Fatalf("History = %v", got)
+		}
+		if got, _ := b.History("lobby", 2); !reflect.DeepEqual(got, []string{"c", "d"}) {
+			t.Fatalf("History(2) = %v", got)
+		}
+		if swapped, err := b.SwapHistory("lobby", 1, "c", "C"); err != nil || !swapped {
+			t.Fatalf("SwapHistory = %v, %v", swapped, err)
+		}
+		if got, _ := b.History("lobby", 0); !reflect.DeepEqual(got, []string{"b", "C", "d"}) {
+			t.Fatalf("SwapHistory 后 History = %v", got)
+		}
+		// 记录已经变化（如被其他实例截断后位置移动）时不替换
+		if swapped, err := b.SwapHistory("lobby", 1, "c", "X"); err != nil || swapped {
+			t.Fatalf("记录已变化时 SwapHistory = %v, %v", swapped, err)
+		}
+		if swapped, err := b.SwapHistory("lobby", 5, "c", "X"); err != nil || swapped {
+			t.Fatalf("位置越界时 SwapHistory = %v, %v", swapped, err)
+		}
+		if got, _ := b.History("lobby", 0); !reflect.DeepEqual(got, []string{"b", "C", "d"}) {
+			t.Fatalf("SwapHistory 失败后 History = %v", got)
+		}
+		if got, _ := b.History("empty", 0); len(got) != 0 {
+			t.Fatalf("空房间 History = %v", got)
+		}
+
+		for want := int64(1); want <= 3; want++ {
+			if seq, err := b.NextSeq("lobby"); err != nil || seq != want {
+				t.Fatalf("NextSeq = %d, %v, want %d", seq, err, want)
+			}
+		}
+		if seq, _ := b.NextSeq("other"); seq != 1 {
+			t.Fatalf("其他房间的 NextSeq = %d, want 1", seq)
+		}
+	})
+}
+
+func TestBackendRecords(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b Backend) {
+		b.AddIgnore("u2", "bob", "Bob")
+		b.AddIgnore("u2", "carol", "Carol")
+		b.RemoveIgnore("u2", "carol")
+		if got, _ := b.Ignores("u2"); !reflect.DeepEqual(got, map[string]string{"bob": "Bob"}) {
+			t.Fatalf("Ignores = %v", got)
+		}
+		b.Register("u2", "alice", "alice")
+		b.Unregister("u2")
+		if got, _ := b.Ignores("u2"); len(got) != 0 {
+			t.Fatalf("注销后 Ignores = %v, want 空", got)
+		}
+
+		for _, want := range []string{"v1", "v2"} {
+			if err := b.UpdateRoom("lobby", func(data string, exists bool) (string, error) {
+				if exists != (want == "v2") {
+					t.Errorf("UpdateRoom 收到 exists=%v", exists)
+				}
+				return want, nil
+			}); err != nil {
+				t.Fatalf("UpdateRoom: %v", err)
+			}
+		}
+		if err := b.UpdateRoom("lobby", func(string, bool) (string, error) {
+			return "", errors.New("放弃")
+		}); err == nil {
+			t.Fatal("fn 返回错误时 UpdateRoom 应返回错误")
+		}
+		if got, _ := b.Rooms(); !reflect.DeepEqual(got, map[string]string{"lobby": "v2"}) {
+			t.Fatalf("Rooms = %v", got)
+		}
+
+		b.SaveConversation("g1", "data")
+		b.SaveConversation("g2", "data")
+		b.DeleteConversation("g2")
+		if got, _ := b.Conversations(); !reflect.DeepEqual(got, map[string]string{"g1": "data"}) {
+			t.Fatalf("Conversations = %v", got)
+		}
+		if got, _ := b.ReplyTarget("u1"); got != "" {
+			t.Fatalf("ReplyTarget = %q, want 空", got)
+		}
+		b.Register("u1", "alice", "alice")
+		b.SetReplyTarget("u1", "@bob")
+		if got, _ := b.ReplyTarget("u1"); got != "@bob" {
+			t.Fatalf("ReplyTarget = %q", got)
+		}
+		b.Unregister("u1")
+		if got, _ := b.ReplyTarget("u1"); got != "" {
+			t.Fatalf("注销后 ReplyTarget = %q, want 空", got)
+		}
+
+		b.SaveBot("hash", "bot")
+		if got, _ := b.Bots(); !reflect.DeepEqual(got, map[string]string{"hash": "bot"}) {
+			t.Fatalf("Bots = %v", got)
+		}
+		b.DeleteBot("hash")
+		if got, _ := b.Bots(); len(got) != 0 {
+			t.Fatalf("DeleteBot 后 Bots = %v", got)
+		}
+
+		b.SaveJob("j1", "job")
+		if got, _ := b.Jobs(); !reflect.DeepEqual(got, map[string]string{"j1": "job"}) {
+			t.Fatalf("Jobs = %v", got)
+		}
+		if deleted, _ := b.DeleteJob("j1"); !deleted {
+			t.Fatal("DeleteJob 第一次应返回true")
+		}
+		if deleted, _ := b.DeleteJob("j1"); deleted {
+			t.Fatal("DeleteJob 第二次应返回false")
+		}
+	})
+}
+
+// TestRedisShared 两个Redis后端连接同一服务时共享在线状态并互相收到发布的消息
+func TestRedisShared(t *testing.T) {
+	server, err := NewRESPServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	a, b := newTestRedisAt(t, server.Addr()), newTestRedisAt(t, server.Addr())
+
+	received := make(chan string, 1)
+	b.Subscribe(func(payload string) { received <- payload })
+	a.Register("u1", "alice", "Alice")
+	if err := b.Register("u2", "alice", "Alice"); err != ErrNameTaken {
+		t.Fatalf("另一实例登记已占用的名字: got %v, want ErrNameTaken", err)
+	}
+	a.Publish("hello")
+	select {
+	case got := <-received:
+		if got != "hello" {
+			t.Fatalf("收到 %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("另一实例没有收到消息")
+	}
+}
+
+// TestRedisReconnect Redis重启后命令连接重新建立，订阅自动恢复
+func TestRedisReconnect(t *testing.T) {
+	server, err := NewRESPServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := server.Addr()
+	b := newTestRedisAt(t, addr)
+	received := make(chan string, 10)
+	b.Subscribe(func(payload string) { received <- payload })
+
+	server.Close()
+	if _, err := b.Online(); err == nil {
+		t.Fatal("服务关闭后命令应失败")
+	}
+	if server, err = NewRESPServer(addr); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	if err := b.Register("u1", "alice", "Alice"); err != nil {
+		t.Fatalf("重新连接后 Register: %v", err)
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		b.Publish("ping")
+		select {
+		case <-received:
+			return
+		case <-deadline:
+			t.Fatal("没有重新订阅")
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// TestRedisInstanceExpiry 实例下线后它的用户不再算作在线，用户名可以被其他实例重新使用
+func TestRedisInstanceExpiry(t *testing.T) {
+	server, err := NewRESPServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	a, b := newTestRedisAt(t, server.Addr()), newTestRedisAt(t, server.Addr())
+
+	a.Register("u1", "alice", "Alice")
+	a.SetMember("u1", "lobby")
+	if err := b.Register("u2", "alice", "Alice"); err != ErrNameTaken {
+		t.Fatalf("实例存活时: got %v, want ErrNameTaken", err)
+	}
+
+	// 模拟实例崩溃：存活标记过期，但登记没有被注销
+	if _, err := a.do("DEL", redisInstancePrefix+a.instance); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := b.Lookup("alice"); ok {
+		t.Fatal("已下线实例的用户仍能被找到")
+	}
+	if online, _ := b.Online(); len(online) != 0 {
+		t.Fatalf("Online = %v, want 空", online)
+	}
+	if err := b.Register("u2", "alice", "Alice"); err != nil {
+		t.Fatalf("重新使用已下线实例的用户名: %v", err)
+	}
+
+	a.Register("u3", "carol", "Carol")
+	a.do("DEL", redisInstancePrefix+a.instance)
+	if err := b.reap(); err != nil {
+		t.Fatalf("reap: %v", err)
+	}
+	if online, _ := b.hash(b.do, redisOnlineKey); !reflect.DeepEqual(online, map[string]string{"u2": "Alice"}) {
+		t.Fatalf("清理后的在线登记 = %v", online)
+	}
+	if names, _ := b.hash(b.do, redisNamesKey); !reflect.DeepEqual(names, map[string]string{"alice": "u2"}) {
+		t.Fatalf("清理后的用户名索引 = %v", names)
+	}
+	if members, _ := b.Members(); len(members) != 0 {
+		t.Fatalf("清理后的所在房间 = %v", members)
+	}
+}
+
+// TestRedisHeartbeat 心跳在有效期内续期存活标记，关闭后端时立即删除
+func TestRedisHeartbeat(t *testing.T) {
+	server, err := NewRESPServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	a, err := newRedisBackend(server.Addr(), utils.NewLogger(false), 150*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := newTestRedisAt(t, server.Addr())
+
+	a.Register("u1", "alice", "Alice")
+	time.Sleep(500 * time.Millisecond)
+	if _, ok, _ := b.Lookup("alice"); !ok {
+		t.Fatal("心跳没有续期存活标记")
+	}
+
+	a.Close()
+	if _, ok, _ := b.Lookup("alice"); ok {
+		t.Fatal("关闭后端后用户仍然在线")
+	}
+}
+
+// TestRedisRegisterRace 多个实例同时登记同一个用户名时只有一个成功
+func TestRedisUpdateRoomRace(t *testing.T) {
+	server, err := NewRESPServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	// 多个实例同时修改同一个房间，每次修改都不会丢失
+	const instances, updates = 3, 10
+	results := make(chan error, instances)
+	for i := 0; i < instances; i++ {
+		b := newTestRedisAt(t, server.Addr())
+		go func(mark string) {
+			for j := 0; j < updates; j++ {
+				if err := b.UpdateRoom("dev", func(data string, _ bool) (string, error) {
+					return data + mark, nil
+				}); err != nil {
+					results <- err
+					return
+				}
+			}
+			results <- nil
+		}(strconv.Itoa(i))
+	}
+	for i := 0; i < instances; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("UpdateRoom: %v", err)
+		}
+	}
+	rooms, err := newTestRedisAt(t, server.Addr()).Rooms()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(rooms["dev"]); got != instances*updates {
+		t.Fatalf("房间记录了 %d 次修改, want %d", got, instances*updates)
+	}
+}
+
+func TestRedisRegisterRace(t *testing.T) {
+	server, err := NewRESPServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	const n = 8
+	results := make(chan error, n)
+	for i := 0; i < n; i++ {
+		b := newTestRedisAt(t, server.Addr())
+		go func(id string) {
+			results <- b.Register(id, "alice", "Alice")
+		}(fmt.Sprintf("u%d", i))
+	}
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		if err := <-results; err == nil {
+			succeeded++
+		} else if err != ErrNameTaken {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d 个实例登记成功, want 1", succeeded)
+	}
+}
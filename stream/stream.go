@@ -0,0 +1,142 @@
+package stream
+
+import (
+	"sync"
+
+	"chatroom/message"
+)
+
+// BufferSize 每个订阅的事件缓冲长度，缓冲满时订阅被断开，客户端带 Last-Event-ID 重连后从历史记录补发
+const BufferSize = 64
+
+// Hub 把广播的聊天事件分发给订阅了房间的只读流
+//
+// 作为用户管理器的事件监听器，能收到所有实例发出的广播事件；订阅者不是用户，不占用最大用户数。
+type Hub struct {
+	mutex sync.RWMutex               // 读写锁
+	subs  map[*Subscription]struct{} // 当前的订阅
+	open  func(room string) bool     // 判断房间当前是否公开
+}
+
+// Subscription 一个房间的订阅
+type Subscription struct {
+	Room   string              // 订阅的房间
+	public bool                // 是否为只读流的订阅，房间不再公开时结束
+	events chan *message.Event // 待发送的事件
+	done   chan struct{}       // 订阅结束时关闭
+	once   sync.Once           // 保证只结束一次
+}
+
+// NewHub 创建事件分发器，open 判断房间当前是否公开
+func NewHub(open func(room string) bool) *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{}), open: open}
+}
+
+// Subscribe 为只读流订阅公开房间的聊天事件
+//
+// 房间的模式在订阅之后可能改变，每次分发事件时都会重新检查，房间不再公开时订阅结束。
+func (h *Hub) Subscribe(room string) *Subscription {
+	return h.add(room, true)
+}
+
+// Watch 订阅房间的聊天事件，只用于等待房间中的新消息（长轮询），事件内容不能直接发给客户端；
+// 房间不再公开时不结束，调用者在读取消息前自行检查权限
+func (h *Hub) Watch(room string) *Subscription {
+	return h.add(room, false)
+}
+
+// add 登记订阅
+func (h *Hub) add(room string, public bool) *Subscription {
+	sub := &Subscription{
+		Room:   room,
+		public: public,
+		events: make(chan *message.Event, BufferSize),
+		done:   make(chan struct{}),
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe 取消订阅
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mutex.Lock()
+	delete(h.subs, sub)
+	h.mutex.Unlock()
+	sub.stop()
+}
+
+// Count 获取当前订阅数量
+func (h *Hub) Count() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.subs)
+}
+
+// Observe 把广播事件分发给订阅了事件所在房间的订阅者，可以作为用户管理器的事件监听器
+//
+// 只分发公开的聊天消息及其编辑、删除和表情回应，私聊和其他事件被忽略；不会阻塞。
+// 房间已不再公开（如改为私密或设置了密码）时不分发，并结束该房间只读流的订阅。
+func (h *Hub) Observe(event *message.Event) {
+	if !Streamable(event) {
+		return
+	}
+
+	room := event.Message.Room
+	var targets []*Subscription
+	public := false
+	h.mutex.RLock()
+	for sub := range h.subs {
+		if room == "" || room == sub.Room {
+			targets = append(targets, sub)
+			public = public || sub.public
+		}
+	}
+	h.mutex.RUnlock()
+
+	// 房间的模式保存在状态后端，只在有只读流订阅时检查，检查时不持有锁
+	open := room == "" || !public || h.open(room)
+	for _, sub := range targets {
+		if sub.public && !open {
+			sub.stop()
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// 订阅者跟不上，断开后由客户端重连补发
+			sub.stop()
+		}
+	}
+}
+
+// Events 获取待发送的事件
+func (s *Subscription) Events() <-chan *message.Event {
+	return s.events
+}
+
+// Done 订阅结束（取消订阅或缓冲满）时关闭
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// stop 结束订阅
+func (s *Subscription) stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// Streamable 判断事件能否出现在只读流中
+func Streamable(event *message.Event) bool {
+	switch event.Type {
+	case message.EventMessage, message.EventEdit, message.EventDelete, message.EventReact:
+	default:
+		return false
+	}
+	return event.Message != nil && Public(event.Message)
+}
+
+// Public 判断消息是否为公开的房间消息（不是私聊）
+func Public(msg *message.Message) bool {
+	return msg.Type != message.TypePrivate && msg.Room != message.PrivateRoom
+}
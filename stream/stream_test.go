@@ -0,0 +1,66 @@
+package stream
+
+import (
+	"sync"
+	"testing"
+
+	"chatroom/message"
+)
+
+// chat 创建房间中的聊天消息事件
+func chat(room, content string) *message.Event {
+	msg := message.NewMessage(message.TypeChat, "alice", content)
+	msg.Room = room
+	return message.NewMessageEvent(msg)
+}
+
+func TestObserveRoomClosed(t *testing.T) {
+	var mutex sync.Mutex
+	open := map[string]bool{"lobby": true, "dev": true}
+	h := NewHub(func(room string) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return open[room]
+	})
+	stream := h.Subscribe("lobby")
+	watcher := h.Watch("lobby")
+	other := h.Subscribe("dev")
+
+	h.Observe(chat("lobby", "公开"))
+	if len(stream.Events()) != 1 || len(watcher.Events()) != 1 || len(other.Events()) != 0 {
+		t.Fatalf("事件数 %d %d %d, want 1 1 0", len(stream.Events()), len(watcher.Events()), len(other.Events()))
+	}
+
+	// 房间改为非公开后，只读流不再收到事件并结束，长轮询的等待不受影响
+	mutex.Lock()
+	open["lobby"] = false
+	mutex.Unlock()
+	h.Observe(chat("lobby", "私密"))
+	select {
+	case <-stream.Done():
+	default:
+		t.Fatal("房间不再公开时只读流应结束")
+	}
+	if len(stream.Events()) != 1 {
+		t.Errorf("只读流收到了非公开房间的消息")
+	}
+	if len(watcher.Events()) != 2 {
+		t.Errorf("长轮询的等待没有收到新消息的通知")
+	}
+	select {
+	case <-other.Done():
+		t.Error("其他房间的只读流不应结束")
+	default:
+	}
+}
+
+func TestObservePrivate(t *testing.T) {
+	h := NewHub(func(string) bool { return true })
+	sub := h.Subscribe("lobby")
+	msg := message.NewMessage(message.TypePrivate, "alice", "悄悄话")
+	msg.Room = "lobby"
+	h.Observe(message.NewMessageEvent(msg))
+	if len(sub.Events()) != 0 {
+		t.Error("私聊不应出现在只读流中")
+	}
+}
@@ -1,9 +1,17 @@
 package user
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"chatroom/i18n"
+	"chatroom/message"
+	"chatroom/nickname"
+	"chatroom/store"
 )
 
 // User 用户结构体
@@ -15,21 +23,159 @@ type User struct {
 	JoinTime time.Time   // 加入时间
 	LastSeen time.Time   // 最后活跃时间
 	IsActive bool        // 是否活跃
+	Lang     string      // 界面语言
+	Mode     OutputMode  // 输出模式
+	ShowIDs  bool        // 文本模式下是否显示消息ID
+	Role     Role        // 角色
+	Room     string      // 当前所在房间
+
+	Presence      Presence  // 手动设置的在线状态
+	StatusMessage string    // 状态留言（如离开原因）
+	Idle          bool      // 是否因长时间未活动而空闲
+	ClientType    string    // 客户端类型
+	MutedUntil    time.Time // 禁言截止时间
+
+	ignores map[string]string // 本次连接的屏蔽列表 (比较键 -> 用户名)
+	stop    *sync.Once        // 保证退出信号只发送一次
+}
+
+// Stop 发送退出信号，可以被读取、写入和超时监控等多个协程重复调用
+func (u *User) Stop() {
+	u.stop.Do(func() { close(u.DoneChan) })
+}
+
+// Presence 在线状态
+type Presence string
+
+const (
+	PresenceOnline Presence = "online" // 在线
+	PresenceAway   Presence = "away"   // 离开
+	PresenceBusy   Presence = "busy"   // 忙碌
+	PresenceIdle   Presence = "idle"   // 空闲（根据最后活跃时间自动判断）
+)
+
+// 客户端类型
+const (
+	ClientTCP = "tcp" // TCP文本协议客户端
+	ClientAPI = "api" // REST 接口会话
+)
+
+// CurrentPresence 获取当前在线状态，在线但长时间未活动的用户视为空闲
+func (u *User) CurrentPresence() Presence {
+	if u.Presence == PresenceOnline && u.Idle {
+		return PresenceIdle
+	}
+	return u.Presence
+}
+
+// OutputMode 客户端输出模式
+type OutputMode string
+
+const (
+	ModeText OutputMode = "text" // 文本模式
+	ModeJSON OutputMode = "json" // 结构化模式，每个事件一行JSON
+)
+
+// Role 用户角色
+type Role string
+
+const (
+	RoleMember   Role = "member"   // 普通成员
+	RoleOperator Role = "operator" // 管理员
+)
+
+// IsOperator 是否为管理员
+func (u *User) IsOperator() bool {
+	return u.Role == RoleOperator
 }
 
 // UserManager 用户管理器
+//
+// users 中只保存连接到本实例的用户，在线登记、用户名查找和消息扇出
+// 都经过状态后端，多个实例共享同一后端时消息可以送达所有实例的用户。
 type UserManager struct {
 	users    map[string]*User // 用户列表
 	mutex    sync.RWMutex     // 互斥锁
 	maxUsers int              // 最大用户数
+	backend  store.Backend    // 状态后端
+	policy   *nickname.Policy // 用户名策略
+
+	listeners []func(event *message.Event) // 广播事件监听器
+}
+
+// envelope 经状态后端扇出的消息
+//
+// Local 和 Event 在投递给具体用户时才按其语言和输出模式渲染，Text 原样投递。
+type envelope struct {
+	From    string         `json:"from,omitempty"`    // 发送者用户名比较键，屏蔽了发送者的用户不会收到
+	Exclude string         `json:"exclude,omitempty"` // 不投递的用户ID
+	To      string         `json:"to,omitempty"`      // 目标用户ID，为空表示所有用户
+	Role    Role           `json:"role,omitempty"`    // 只投递给该角色的用户
+	Room    string         `json:"room,omitempty"`    // 只投递给该房间的用户，为空表示所有房间
+	Text    string         `json:"text,omitempty"`    // 消息内容
+	Local   *i18n.Text     `json:"local,omitempty"`   // 可本地化的消息
+	Event   *message.Event `json:"event,omitempty"`   // 消息事件
+}
+
+// blockedBy 判断消息的发送者是否被用户屏蔽
+func (env *envelope) blockedBy(user *User) bool {
+	if env.From == "" {
+		return false
+	}
+	_, ignored := user.ignores[env.From]
+	return ignored
 }
 
-// NewUserManager 创建新的用户管理器
+// render 按用户语言和输出模式渲染消息
+func (env *envelope) render(user *User) string {
+	event := env.Event
+	if event == nil {
+		text := env.Text
+		if env.Local != nil {
+			text = env.Local.Render(user.Lang)
+		}
+		if user.Mode != ModeJSON {
+			return strings.TrimSuffix(text, "\n") + "\n"
+		}
+		event = message.NewNoticeEvent(strings.TrimSuffix(text, "\n"))
+	}
+
+	if user.Mode == ModeJSON {
+		return event.FormatJSON()
+	}
+	return event.FormatText(user.Lang, user.ShowIDs)
+}
+
+// NewUserManager 创建使用内存后端和默认用户名策略的用户管理器
 func NewUserManager(maxUsers int) *UserManager {
-	return &UserManager{
+	return NewUserManagerWithBackend(maxUsers, store.NewMemoryBackend(), nickname.DefaultPolicy())
+}
+
+// NewUserManagerWithBackend 创建使用指定状态后端和用户名策略的用户管理器
+func NewUserManagerWithBackend(maxUsers int, backend store.Backend, policy *nickname.Policy) *UserManager {
+	um := &UserManager{
 		users:    make(map[string]*User),
 		maxUsers: maxUsers,
+		backend:  backend,
+		policy:   policy,
 	}
+	backend.Subscribe(um.deliver)
+	return um
+}
+
+// AddEventListener 添加广播事件监听器
+//
+// 所有经状态后端扇出的广播事件（包括其他实例发出的）都会回调监听器，
+// 回调在后端的投递协程中执行，不能阻塞。
+func (um *UserManager) AddEventListener(fn func(event *message.Event)) {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+	um.listeners = append(um.listeners, fn)
+}
+
+// Policy 获取用户名策略
+func (um *UserManager) Policy() *nickname.Policy {
+	return um.policy
 }
 
 // CreateUser 创建新用户
@@ -39,22 +185,59 @@ func (um *UserManager) CreateUser(id, name string) (*User, error) {
 
 	// 检查用户数量限制
 	if len(um.users) >= um.maxUsers {
-		return nil, fmt.Errorf("聊天室已满，无法加入新用户")
+		return nil, i18n.Errorf("error.room_full", nil)
 	}
 
 	// 检查用户ID是否已存在
 	if _, exists := um.users[id]; exists {
-		return nil, fmt.Errorf("用户ID已存在")
+		return nil, i18n.Errorf("error.user_id_exists", nil)
+	}
+
+	// 校验用户名
+	name, err := um.policy.Normalize(name)
+	if err != nil {
+		return nil, err
 	}
 
+	// 登记在线状态，默认用户名重复时追加序号
+	registered := name
+	for i := 2; ; i++ {
+		err := um.backend.Register(id, nickname.Key(registered), registered)
+		if err == nil {
+			break
+		}
+		if err == store.ErrNameTaken && i > um.maxUsers+1 {
+			return nil, i18n.Errorf("error.name_taken", nil)
+		}
+		if err != store.ErrNameTaken {
+			return nil, err
+		}
+		registered = fmt.Sprintf("%s_%d", name, i)
+	}
+	name = registered
+
 	user := &User{
 		ID:       id,
 		Name:     name,
 		MsgChan:  make(chan string, 100),
 		DoneChan: make(chan bool, 1),
+		stop:     new(sync.Once),
 		JoinTime: time.Now(),
 		LastSeen: time.Now(),
 		IsActive: true,
+		Mode:     ModeText,
+		Role:     RoleMember,
+		Room:     message.DefaultRoom,
+
+		Presence:   PresenceOnline,
+		ClientType: ClientTCP,
+
+		ignores: make(map[string]string),
+	}
+
+	if err := um.backend.SetMember(id, user.Room); err != nil {
+		um.backend.Unregister(id)
+		return nil, err
 	}
 
 	um.users[id] = user
@@ -77,9 +260,10 @@ func (um *UserManager) RemoveUser(id string) (*User, bool) {
 	user, exists := um.users[id]
 	if exists {
 		delete(um.users, id)
+		um.backend.Unregister(id)
 		user.IsActive = false
 		close(user.MsgChan)
-		close(user.DoneChan)
+		user.Stop()
 	}
 	return user, exists
 }
@@ -103,14 +287,77 @@ func (um *UserManager) GetUserCount() int {
 	return len(um.users)
 }
 
-// UpdateUserLastSeen 更新用户最后活跃时间
-func (um *UserManager) UpdateUserLastSeen(id string) {
+// UpdateUserLastSeen 更新用户最后活跃时间，返回用户是否刚从空闲恢复为在线
+func (um *UserManager) UpdateUserLastSeen(id string) bool {
 	um.mutex.Lock()
 	defer um.mutex.Unlock()
 
 	if user, exists := um.users[id]; exists {
 		user.LastSeen = time.Now()
+		wasIdle := user.Idle
+		user.Idle = false
+		return wasIdle && user.Presence == PresenceOnline
+	}
+	return false
+}
+
+// MarkIdle 用户超过 idleAfter 未活动时标记为空闲，返回是否刚刚变为空闲
+func (um *UserManager) MarkIdle(id string, idleAfter time.Duration) bool {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	user, exists := um.users[id]
+	if !exists || user.Idle || time.Since(user.LastSeen) <= idleAfter {
+		return false
+	}
+	user.Idle = true
+	return user.Presence == PresenceOnline
+}
+
+// SetPresence 设置用户在线状态和状态留言
+func (um *UserManager) SetPresence(id string, presence Presence, statusMessage string) {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	if user, exists := um.users[id]; exists {
+		user.Presence = presence
+		user.StatusMessage = statusMessage
+	}
+}
+
+// MuteUser 禁言用户直到指定时间
+func (um *UserManager) MuteUser(id string, until time.Time) {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	if user, exists := um.users[id]; exists {
+		user.MutedUntil = until
+	}
+}
+
+// MutedFor 获取用户剩余的禁言时长，未被禁言时返回0
+func (um *UserManager) MutedFor(id string) time.Duration {
+	um.mutex.RLock()
+	defer um.mutex.RUnlock()
+
+	if user, exists := um.users[id]; exists {
+		if remaining := time.Until(user.MutedUntil); remaining > 0 {
+			return remaining
+		}
 	}
+	return 0
+}
+
+// GetUserSnapshot 获取用户信息的副本，可以在不加锁的情况下读取
+func (um *UserManager) GetUserSnapshot(id string) (User, bool) {
+	um.mutex.RLock()
+	defer um.mutex.RUnlock()
+
+	user, exists := um.users[id]
+	if !exists {
+		return User{}, false
+	}
+	return *user, true
 }
 
 // RenameUser 重命名用户
@@ -120,80 +367,456 @@ func (um *UserManager) RenameUser(id, newName string) error {
 
 	user, exists := um.users[id]
 	if !exists {
-		return fmt.Errorf("用户不存在")
+		return i18n.Errorf("error.user_not_found", nil)
+	}
+
+	// 校验新用户名
+	newName, err := um.policy.Normalize(newName)
+	if err != nil {
+		return err
 	}
 
-	// 检查新用户名是否已被使用
-	for _, u := range um.users {
-		if u.ID != id && u.Name == newName {
-			return fmt.Errorf("用户名已被使用")
+	// 检查新用户名是否已被使用，或与在线用户的名字易混淆
+	if err := um.backend.Rename(id, nickname.Key(user.Name), nickname.Key(newName), newName); err != nil {
+		if err == store.ErrNameTaken {
+			return um.nameConflict(newName)
 		}
+		return err
 	}
 
 	user.Name = newName
+
+	// 不能屏蔽自己，改成已屏蔽的用户名时移除该项
+	if key := nickname.Key(newName); user.ignores[key] != "" {
+		delete(user.ignores, key)
+		return um.backend.RemoveIgnore(id, key)
+	}
+	return nil
+}
+
+// IgnoreUser 屏蔽用户，返回被屏蔽的用户名
+//
+// 屏蔽列表只属于本次连接，离开聊天室时清除；在有账号之前不按用户名保存，
+// 之后使用同一用户名的人不会继承屏蔽列表。列表同步到状态后端，其他实例可以据此判断私聊是否被屏蔽。
+func (um *UserManager) IgnoreUser(id, name string) (string, error) {
+	name, err := um.policy.Normalize(name)
+	if err != nil {
+		return "", err
+	}
+	// 优先使用在线用户的实际用户名
+	if ownerID, exists, _ := um.backend.Lookup(nickname.Key(name)); exists {
+		if online, err := um.backend.Online(); err == nil && online[ownerID] != "" {
+			name = online[ownerID]
+		}
+	}
+
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	user, exists := um.users[id]
+	if !exists {
+		return "", i18n.Errorf("error.user_not_found", nil)
+	}
+
+	key := nickname.Key(name)
+	if key == nickname.Key(user.Name) {
+		return "", i18n.Errorf("error.ignore_self", nil)
+	}
+	if existing, ignored := user.ignores[key]; ignored {
+		return "", i18n.Errorf("error.already_ignored", i18n.Params{"name": existing})
+	}
+	if err := um.backend.AddIgnore(id, key, name); err != nil {
+		return "", err
+	}
+	user.ignores[key] = name
+	return name, nil
+}
+
+// UnignoreUser 取消屏蔽用户，返回被取消屏蔽的用户名
+func (um *UserManager) UnignoreUser(id, name string) (string, error) {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	user, exists := um.users[id]
+	if !exists {
+		return "", i18n.Errorf("error.user_not_found", nil)
+	}
+
+	key := nickname.Key(name)
+	existing, ignored := user.ignores[key]
+	if !ignored {
+		return "", i18n.Errorf("error.not_ignored", i18n.Params{"name": name})
+	}
+	if err := um.backend.RemoveIgnore(id, key); err != nil {
+		return "", err
+	}
+	delete(user.ignores, key)
+	return existing, nil
+}
+
+// GetIgnoredNames 获取用户屏蔽的用户名列表（按字母顺序）
+func (um *UserManager) GetIgnoredNames(id string) []string {
+	um.mutex.RLock()
+	defer um.mutex.RUnlock()
+
+	user, exists := um.users[id]
+	if !exists {
+		return nil
+	}
+	names := make([]string, 0, len(user.ignores))
+	for _, name := range user.ignores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsIgnoring 判断用户是否屏蔽了指定用户名
+func (um *UserManager) IsIgnoring(id, name string) bool {
+	um.mutex.RLock()
+	defer um.mutex.RUnlock()
+
+	user, exists := um.users[id]
+	if !exists {
+		return false
+	}
+	_, ignored := user.ignores[nickname.Key(name)]
+	return ignored
+}
+
+// nameConflict 生成用户名冲突错误，区分完全重名和易混淆的名字
+func (um *UserManager) nameConflict(name string) error {
+	online, err := um.backend.Online()
+	if err != nil {
+		return err
+	}
+	if ownerID, exists, _ := um.backend.Lookup(nickname.Key(name)); exists {
+		if existing := online[ownerID]; existing != "" && !strings.EqualFold(existing, name) {
+			return i18n.Errorf("error.name_confusable", i18n.Params{"name": existing})
+		}
+	}
+	return i18n.Errorf("error.name_taken", nil)
+}
+
+// FindUserByName 按用户名查找本实例的用户，比较时忽略大小写和易混淆字符
+func (um *UserManager) FindUserByName(name string) (*User, bool) {
+	id, exists, err := um.backend.Lookup(nickname.Key(name))
+	if err != nil || !exists {
+		return nil, false
+	}
+	return um.GetUser(id)
+}
+
+// LookupUserID 按用户名查找在线用户的ID，用户可以连接在任意实例
+func (um *UserManager) LookupUserID(name string) (string, bool) {
+	id, exists, err := um.backend.Lookup(nickname.Key(name))
+	if err != nil {
+		return "", false
+	}
+	return id, exists
+}
+
+// LookupOnline 按用户名查找在线用户的ID和实际用户名，用户可以连接在任意实例
+func (um *UserManager) LookupOnline(name string) (string, string, bool) {
+	id, exists := um.LookupUserID(name)
+	if !exists {
+		return "", "", false
+	}
+	online, err := um.backend.Online()
+	if err != nil || online[id] == "" {
+		return "", "", false
+	}
+	return id, online[id], true
+}
+
+// OnlineNames 获取所有在线用户 (ID -> 用户名)，用户可以连接在任意实例
+func (um *UserManager) OnlineNames() map[string]string {
+	online, err := um.backend.Online()
+	if err != nil {
+		return nil
+	}
+	return online
+}
+
+// IgnoredBy 判断用户 ownerID 是否屏蔽了 name，用户可以连接在任意实例
+func (um *UserManager) IgnoredBy(ownerID, name string) bool {
+	ignores, err := um.backend.Ignores(ownerID)
+	if err != nil {
+		return false
+	}
+	_, ignored := ignores[nickname.Key(name)]
+	return ignored
+}
+
+// SetUserLang 设置用户界面语言
+func (um *UserManager) SetUserLang(id, lang string) {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	if user, exists := um.users[id]; exists {
+		user.Lang = lang
+	}
+}
+
+// SetUserMode 设置用户输出模式
+func (um *UserManager) SetUserMode(id string, mode OutputMode) {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	if user, exists := um.users[id]; exists {
+		user.Mode = mode
+	}
+}
+
+// SetUserClient 设置用户的客户端类型
+func (um *UserManager) SetUserClient(id, client string) {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	if user, exists := um.users[id]; exists {
+		user.ClientType = client
+	}
+}
+
+// SetUserShowIDs 设置文本模式下是否显示消息ID
+func (um *UserManager) SetUserShowIDs(id string, show bool) {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	if user, exists := um.users[id]; exists {
+		user.ShowIDs = show
+	}
+}
+
+// SetUserRole 设置用户角色
+func (um *UserManager) SetUserRole(id string, role Role) {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	if user, exists := um.users[id]; exists {
+		user.Role = role
+	}
+}
+
+// SetUserRoom 设置用户当前所在的房间
+func (um *UserManager) SetUserRoom(id, room string) error {
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+
+	user, exists := um.users[id]
+	if !exists {
+		return i18n.Errorf("error.user_not_found", nil)
+	}
+	if err := um.backend.SetMember(id, room); err != nil {
+		return err
+	}
+	user.Room = room
 	return nil
 }
 
-// GetUserList 获取用户列表字符串
-func (um *UserManager) GetUserList() string {
+// OnlineCount 获取所有实例的在线用户数，后端不可用时返回本实例的用户数
+func (um *UserManager) OnlineCount() int {
+	online, err := um.backend.Online()
+	if err != nil {
+		return um.GetUserCount()
+	}
+	return len(online)
+}
+
+// GetUserList 按语言获取用户列表字符串
+func (um *UserManager) GetUserList(lang string) string {
 	users := um.GetAllUsers()
 	if len(users) == 0 {
-		return "当前没有在线用户\n"
+		return i18n.T(lang, "userlist.empty", nil) + "\n"
 	}
 
-	result := fmt.Sprintf("当前在线用户 (%d人):\n", len(users))
+	result := i18n.T(lang, "userlist.header", i18n.Params{"count": len(users)}) + "\n"
 	for _, user := range users {
 		onlineTime := time.Since(user.JoinTime).Round(time.Second)
-		result += fmt.Sprintf("- %s (ID: %s, 在线时长: %s)\n",
-			user.Name, user.ID, onlineTime)
+		result += i18n.T(lang, "userlist.item", i18n.Params{
+			"name": user.Name, "id": user.ID, "duration": onlineTime,
+			"presence": i18n.T(lang, "presence."+string(user.CurrentPresence()), nil),
+		}) + "\n"
 	}
 	return result
 }
 
 // BroadcastToAll 向所有用户广播消息
 func (um *UserManager) BroadcastToAll(message string) {
+	um.publish(envelope{Text: message})
+}
+
+// BroadcastToOthers 向除指定用户外的所有用户广播消息
+func (um *UserManager) BroadcastToOthers(excludeID, message string) {
+	um.publish(envelope{From: um.senderKey(excludeID), Exclude: excludeID, Text: message})
+}
+
+// BroadcastLocalized 向除指定用户外的所有用户广播可本地化的消息，excludeID为空表示所有用户
+func (um *UserManager) BroadcastLocalized(excludeID string, text i18n.Text) {
+	um.publish(envelope{From: um.senderKey(excludeID), Exclude: excludeID, Local: &text})
+}
+
+// BroadcastRoomLocalized 向房间内除指定用户外的所有用户广播可本地化的消息，excludeID为空表示房间内所有用户
+func (um *UserManager) BroadcastRoomLocalized(room, excludeID string, text i18n.Text) {
+	um.publish(envelope{From: um.senderKey(excludeID), Exclude: excludeID, Room: room, Local: &text})
+}
+
+// NotifyOperators 向所有在线管理员发送可本地化的消息
+func (um *UserManager) NotifyOperators(text i18n.Text) {
+	um.publish(envelope{Role: RoleOperator, Local: &text})
+}
+
+// BroadcastMessage 向消息所在房间的用户广播聊天消息，消息没有房间时发送给所有用户
+func (um *UserManager) BroadcastMessage(msg *message.Message) {
+	um.BroadcastEvent(message.NewMessageEvent(msg))
+}
+
+// BroadcastEvent 广播消息事件，与消息相关的事件只发送给消息所在房间的用户
+func (um *UserManager) BroadcastEvent(event *message.Event) {
+	room := ""
+	if event.Message != nil {
+		room = event.Message.Room
+	}
+	um.publish(envelope{From: eventSenderKey(event), Room: room, Event: event})
+}
+
+// SendLocalized 向指定用户发送可本地化的消息
+func (um *UserManager) SendLocalized(userID string, text i18n.Text) error {
+	return um.send(userID, envelope{To: userID, Local: &text})
+}
+
+// SendMessageToUser 向指定用户发送聊天消息
+func (um *UserManager) SendMessageToUser(userID string, msg *message.Message) error {
+	return um.SendEventToUser(userID, message.NewMessageEvent(msg))
+}
+
+// SendEventToUser 向指定用户发送事件，用户屏蔽了事件的发送者时不投递
+func (um *UserManager) SendEventToUser(userID string, event *message.Event) error {
+	return um.send(userID, envelope{From: eventSenderKey(event), To: userID, Event: event})
+}
+
+// SendToUser 向指定用户发送消息
+//
+// 用户连接在本实例时直接投递，否则经状态后端转发给其所在实例。
+func (um *UserManager) SendToUser(userID, message string) error {
+	return um.send(userID, envelope{To: userID, Text: message})
+}
+
+// SendRaw 向本实例的用户原样发送数据，不做本地化和输出模式转换（用于文件传输）
+func (um *UserManager) SendRaw(userID, data string) error {
 	um.mutex.RLock()
 	defer um.mutex.RUnlock()
 
-	for _, user := range um.users {
-		select {
-		case user.MsgChan <- message:
-		default:
-			// 如果用户的消息通道已满，跳过该用户
+	user, exists := um.users[userID]
+	if !exists {
+		return i18n.Errorf("error.user_not_found", nil)
+	}
+	return um.offer(user, data)
+}
+
+// send 向指定用户投递消息
+func (um *UserManager) send(userID string, env envelope) error {
+	um.mutex.RLock()
+	if user, exists := um.users[userID]; exists {
+		defer um.mutex.RUnlock()
+		if env.blockedBy(user) {
+			return nil
 		}
+		return um.offer(user, env.render(user))
+	}
+	um.mutex.RUnlock()
+
+	online, err := um.backend.Online()
+	if err != nil {
+		return err
 	}
+	if _, exists := online[userID]; !exists {
+		return i18n.Errorf("error.user_not_found", nil)
+	}
+	return um.publish(env)
 }
 
-// BroadcastToOthers 向除指定用户外的所有用户广播消息
-func (um *UserManager) BroadcastToOthers(excludeID, message string) {
+// publish 经状态后端扇出消息
+func (um *UserManager) publish(env envelope) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return um.backend.Publish(string(payload))
+}
+
+// deliver 将状态后端扇出的消息投递给本实例的用户
+func (um *UserManager) deliver(payload string) {
+	var env envelope
+	if err := json.Unmarshal([]byte(payload), &env); err != nil {
+		return
+	}
+
 	um.mutex.RLock()
 	defer um.mutex.RUnlock()
 
+	if env.Event != nil && env.To == "" {
+		for _, fn := range um.listeners {
+			fn(env.Event)
+		}
+	}
+
+	if env.To != "" {
+		if user, exists := um.users[env.To]; exists && !env.blockedBy(user) {
+			um.offer(user, env.render(user))
+		}
+		return
+	}
+
 	for _, user := range um.users {
-		if user.ID != excludeID {
-			select {
-			case user.MsgChan <- message:
-			default:
-				// 如果用户的消息通道已满，跳过该用户
-			}
+		if env.Role != "" && user.Role != env.Role {
+			continue
+		}
+		if env.Room != "" && user.Room != env.Room {
+			continue
+		}
+		if user.ID != env.Exclude && !env.blockedBy(user) {
+			// 如果用户的消息通道已满，跳过该用户
+			um.offer(user, env.render(user))
 		}
 	}
 }
 
-// SendToUser 向指定用户发送消息
-func (um *UserManager) SendToUser(userID, message string) error {
+// senderKey 获取本实例用户的用户名比较键，用于屏蔽判断
+func (um *UserManager) senderKey(id string) string {
+	if id == "" {
+		return ""
+	}
 	um.mutex.RLock()
 	defer um.mutex.RUnlock()
 
-	user, exists := um.users[userID]
-	if !exists {
-		return fmt.Errorf("用户不存在")
+	if user, exists := um.users[id]; exists {
+		return nickname.Key(user.Name)
+	}
+	return ""
+}
+
+// eventSenderKey 获取事件发起者的用户名比较键，系统消息没有发起者
+func eventSenderKey(event *message.Event) string {
+	sender := event.By
+	if event.Type == message.EventMessage {
+		if event.Message.Type == message.TypeSystem {
+			return ""
+		}
+		sender = event.Message.From
 	}
+	if sender == "" {
+		return ""
+	}
+	return nickname.Key(sender)
+}
 
+// offer 非阻塞地向用户消息通道写入消息
+func (um *UserManager) offer(user *User, message string) error {
 	select {
 	case user.MsgChan <- message:
 		return nil
 	default:
-		return fmt.Errorf("用户消息通道已满")
+		return i18n.Errorf("error.channel_full", nil)
 	}
 }
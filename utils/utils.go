@@ -1,10 +1,15 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"strings"
 	"time"
+	"unicode"
+
+	"chatroom/nickname"
 )
 
 // Logger 日志记录器
@@ -53,41 +58,39 @@ func (l *Logger) Warn(format string, args ...interface{}) {
 	}
 }
 
-// GenerateUserID 生成用户ID
-func GenerateUserID(conn net.Conn) string {
-	addr := conn.RemoteAddr().String()
-	// 移除端口号，只保留IP地址
-	if idx := strings.LastIndex(addr, ":"); idx != -1 {
-		addr = addr[:idx]
+// userIDBytes 用户ID的随机字节数
+const userIDBytes = 8
+
+// GenerateUserID 生成随机的用户ID
+//
+// 用户ID只属于一个连接，同一地址同一秒内的多个连接也不会得到相同的ID。
+func GenerateUserID() string {
+	buf := make([]byte, userIDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("user_%x", time.Now().UnixNano())
 	}
-	return fmt.Sprintf("user_%s_%d", addr, time.Now().Unix())
+	return "user_" + hex.EncodeToString(buf)
 }
 
 // GenerateUsername 生成默认用户名
 func GenerateUsername(conn net.Conn) string {
 	addr := conn.RemoteAddr().String()
 	// 移除端口号，只保留IP地址
-	if idx := strings.LastIndex(addr, ":"); idx != -1 {
-		addr = addr[:idx]
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	// IPv6地址只保留最后一段，使默认用户名符合用户名策略
+	if idx := strings.LastIndex(host, ":"); idx != -1 {
+		host = host[idx+1:]
 	}
-	return fmt.Sprintf("用户_%s", addr)
+	return fmt.Sprintf("用户_%s", host)
 }
 
-// ValidateUsername 验证用户名
+// ValidateUsername 按默认用户名策略验证用户名
 func ValidateUsername(username string) error {
-	if len(username) == 0 {
-		return fmt.Errorf("用户名不能为空")
-	}
-	if len(username) > 20 {
-		return fmt.Errorf("用户名长度不能超过20个字符")
-	}
-	// 检查是否包含特殊字符
-	for _, char := range username {
-		if char < 32 || char > 126 {
-			return fmt.Errorf("用户名包含无效字符")
-		}
-	}
-	return nil
+	_, err := nickname.DefaultPolicy().Normalize(username)
+	return err
 }
 
 // FormatDuration 格式化持续时间
@@ -105,6 +108,18 @@ func FormatDuration(d time.Duration) string {
 	return fmt.Sprintf("%d小时%d分", hours, minutes)
 }
 
+// FormatSize 格式化文件大小
+func FormatSize(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
+
 // IsValidIP 检查IP地址是否有效
 func IsValidIP(ip string) bool {
 	return net.ParseIP(ip) != nil
@@ -127,20 +142,31 @@ func GetLocalIP() string {
 	return "127.0.0.1"
 }
 
-// TruncateString 截断字符串
+// TruncateString 截断字符串，maxLen 按字符（而不是字节）计算，超长时以 ... 结尾
+//
+// 截断不会拆开多字节字符；maxLen 不足以放下省略号时只保留前 maxLen 个字符。
 func TruncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+	if maxLen <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return string(runes)
+	}
+	if maxLen <= 3 {
+		return string(runes[:maxLen])
 	}
-	return s[:maxLen-3] + "..."
+	return string(runes[:maxLen-3]) + "..."
 }
 
 // SanitizeInput 清理用户输入
+//
+// 移除所有控制字符（包括C1控制字符），无效的UTF-8字节替换为U+FFFD，并去除首尾空白。
+// 结果总是有效的UTF-8，且再次清理不会改变结果。
 func SanitizeInput(input string) string {
-	// 移除控制字符
 	var result strings.Builder
 	for _, char := range input {
-		if char >= 32 && char != 127 {
+		if !unicode.IsControl(char) {
 			result.WriteRune(char)
 		}
 	}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode"
+	"unicode/utf8"
+
+	"chatroom/nickname"
+)
+
+// chatLines 真实聊天中的输入，作为模糊测试的种子
+var chatLines = []string{
+	"大家好，今天的会议改到下午三点",
+	"hello everyone 👋",
+	"  前后有空格  ",
+	"混合 mixed 文本 with emoji 🎉🎉",
+	"\\w 张三 晚上一起吃饭吗？",
+	"line\nbreak\tand\rcarriage",
+	"\x1b[31m红色\x1b[0m",
+	"\u0085C1控制字符\u009b",
+	"零宽​字符",
+	"é 组合符号",
+	"\xff\xfe无效字节",
+	"",
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		s      string
+		maxLen int
+		want   string
+	}{
+		{"hello", 10, "hello"},
+		{"hello world", 8, "hello..."},
+		{"你好世界你好世界", 5, "你好..."},
+		{"你好世界", 4, "你好世界"},
+		{"你好世界", 3, "你好世"},
+		{"abc", 0, ""},
+		{"abc", -1, ""},
+		{"\xff", 5, "�"},
+	}
+	for _, tt := range tests {
+		if got := TruncateString(tt.s, tt.maxLen); got != tt.want {
+			t.Errorf("TruncateString(%q, %d) = %q, want %q", tt.s, tt.maxLen, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeInput(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"  hello  ", "hello"},
+		{"a\x00b\x07c", "abc"},
+		{"\x1b[31m红色\x1b[0m", "[31m红色[0m"},
+		{"\u0085前\u009b后", "前后"},
+		{"line\nbreak", "linebreak"},
+		{"\xff", "�"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeInput(tt.input); got != tt.want {
+			t.Errorf("SanitizeInput(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestTruncateStringProperties 对随机输入检查截断的性质
+func TestTruncateStringProperties(t *testing.T) {
+	property := func(s string, n uint8) bool {
+		return checkTruncate(t, s, int(n))
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestSanitizeInputProperties 对随机输入检查清理的性质
+func TestSanitizeInputProperties(t *testing.T) {
+	property := func(s string) bool {
+		return checkSanitize(t, s)
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func FuzzTruncateString(f *testing.F) {
+	for _, line := range chatLines {
+		f.Add(line, 5)
+		f.Add(line, 3)
+	}
+	f.Fuzz(func(t *testing.T, s string, maxLen int) {
+		checkTruncate(t, s, maxLen)
+	})
+}
+
+func FuzzSanitizeInput(f *testing.F) {
+	for _, line := range chatLines {
+		f.Add(line)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		checkSanitize(t, input)
+	})
+}
+
+func FuzzValidateUsername(f *testing.F) {
+	for _, name := range []string{"alice", "张三", "Ｆｕｌｌ", "a b", "é", "́e", "零宽​", "admin", ""} {
+		f.Add(name)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		if ValidateUsername(name) != nil {
+			return
+		}
+		// 通过验证的用户名规范化后仍然有效，且规范化是幂等的
+		normalized, err := nickname.DefaultPolicy().Normalize(name)
+		if err != nil {
+			t.Fatalf("Normalize(%q) 失败: %v", name, err)
+		}
+		again, err := nickname.DefaultPolicy().Normalize(normalized)
+		if err != nil || again != normalized {
+			t.Fatalf("Normalize 不是幂等的: %q -> %q -> %q (%v)", name, normalized, again, err)
+		}
+		if !utf8.ValidString(normalized) {
+			t.Fatalf("Normalize(%q) = %q 不是有效的UTF-8", name, normalized)
+		}
+	})
+}
+
+// checkTruncate 检查截断结果：有效的UTF-8、不超过 maxLen 个字符、未超长时不变、超长时是原文的前缀
+func checkTruncate(t *testing.T, s string, maxLen int) bool {
+	t.Helper()
+	got := TruncateString(s, maxLen)
+	runes := []rune(s)
+	switch {
+	case !utf8.ValidString(got):
+		t.Errorf("TruncateString(%q, %d) = %q 不是有效的UTF-8", s, maxLen, got)
+	case maxLen <= 0 && got != "":
+		t.Errorf("TruncateString(%q, %d) = %q, want 空", s, maxLen, got)
+	case maxLen > 0 && utf8.RuneCountInString(got) > maxLen:
+		t.Errorf("TruncateString(%q, %d) = %q 超过 %d 个字符", s, maxLen, got, maxLen)
+	case maxLen > 0 && len(runes) <= maxLen && got != string(runes):
+		t.Errorf("TruncateString(%q, %d) = %q, 未超长时不应改变", s, maxLen, got)
+	case len(runes) > maxLen && maxLen > 3 && !strings.HasPrefix(string(runes), strings.TrimSuffix(got, "...")):
+		t.Errorf("TruncateString(%q, %d) = %q 不是原文的前缀", s, maxLen, got)
+	default:
+		return true
+	}
+	return false
+}
+
+// checkSanitize 检查清理结果：有效的UTF-8、没有控制字符、首尾没有空白、再次清理不变
+func checkSanitize(t *testing.T, input string) bool {
+	t.Helper()
+	got := SanitizeInput(input)
+	if !utf8.ValidString(got) {
+		t.Errorf("SanitizeInput(%q) = %q 不是有效的UTF-8", input, got)
+		return false
+	}
+	if strings.IndexFunc(got, unicode.IsControl) >= 0 {
+		t.Errorf("SanitizeInput(%q) = %q 含有控制字符", input, got)
+		return false
+	}
+	if got != strings.TrimSpace(got) {
+		t.Errorf("SanitizeInput(%q) = %q 首尾有空白", input, got)
+		return false
+	}
+	if again := SanitizeInput(got); again != got {
+		t.Errorf("SanitizeInput 不是幂等的: %q -> %q -> %q", input, got, again)
+		return false
+	}
+	return true
+}
+
+func TestGenerateUserID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := GenerateUserID()
+		if !strings.HasPrefix(id, "user_") || len(id) != len("user_")+userIDBytes*2 {
+			t.Fatalf("用户ID格式错误: %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("用户ID重复: %q", id)
+		}
+		seen[id] = true
+	}
+}
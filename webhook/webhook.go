@@ -0,0 +1,348 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"chatroom/message"
+	"chatroom/utils"
+)
+
+// 投递参数
+const (
+	QueueSize    = 256                    // 每个 webhook 的待投递队列长度，队列满时丢弃新事件
+	maxAttempts  = 4                      // 每个事件最多尝试投递的次数
+	retryBackoff = 500 * time.Millisecond // 第一次重试前的等待时间，之后每次翻倍
+	httpTimeout  = 10 * time.Second       // 单次请求超时
+
+	// SignatureHeader 请求体的 HMAC-SHA256 签名，格式为 "sha256=<hex>"
+	SignatureHeader = "X-Chatroom-Signature"
+	// EventHeader 事件类型
+	EventHeader = "X-Chatroom-Event"
+	// DeliveryHeader 投递ID，重试时不变，接收方可以据此去重
+	DeliveryHeader = "X-Chatroom-Delivery"
+)
+
+// Type 事件类型
+type Type string
+
+const (
+	TypeJoin       Type = "join"       // 用户进入聊天室或房间
+	TypeLeave      Type = "leave"      // 用户离开聊天室或房间
+	TypeChat       Type = "chat"       // 房间中的聊天消息（包括回复）
+	TypeModeration Type = "moderation" // 管理动作：过滤规则标记、拒绝、禁言和管理员删除消息
+)
+
+// 管理动作
+const (
+	ActionFlag   = "flag"   // 消息命中 flag 规则
+	ActionReject = "reject" // 消息被规则拒绝
+	ActionMute   = "mute"   // 用户被规则禁言
+	ActionDelete = "delete" // 管理员删除了他人的消息
+)
+
+// Event 发送给 webhook 的事件，以JSON作为请求体
+type Event struct {
+	Type      Type      `json:"type"`                 // 事件类型
+	Room      string    `json:"room,omitempty"`       // 所在房间
+	User      string    `json:"user,omitempty"`       // 相关用户
+	MessageID string    `json:"message_id,omitempty"` // 相关消息ID
+	Text      string    `json:"text,omitempty"`       // 聊天内容
+	Action    string    `json:"action,omitempty"`     // 管理动作
+	Reason    string    `json:"reason,omitempty"`     // 触发管理动作的规则
+	By        string    `json:"by,omitempty"`         // 管理动作的执行者
+	Keywords  []string  `json:"keywords,omitempty"`   // 命中的关键词（mention 过滤条件）
+	Time      time.Time `json:"time"`                 // 发生时间
+
+	// Open 事件所在的房间是否对所有人公开，由调用者设置；
+	// 非公开（私密、仅限邀请、密码保护）房间的事件只投递给明确指定了该房间的条件
+	Open bool `json:"-"`
+}
+
+// condition 一个过滤条件
+type condition struct {
+	kind    string // join / leave / chat / mention / moderation / *
+	room    string // 限定的房间（join / leave / chat / moderation），为空表示所有公开房间
+	keyword string // 关键词（mention），小写
+}
+
+// Hook 一个 webhook 配置
+type Hook struct {
+	URL        string      // 接收地址
+	Line       int         // 在配置文件中的行号
+	secret     string      // HMAC 签名密钥
+	conditions []condition // 过滤条件，满足任意一个即投递
+	queue      chan delivery
+}
+
+// delivery 一次待投递的事件
+type delivery struct {
+	id    string // 投递ID
+	event Event  // 事件
+}
+
+// match 判断事件是否满足过滤条件，返回命中的关键词
+func (h *Hook) match(e Event) (bool, []string) {
+	matched := false
+	var keywords []string
+	text := strings.ToLower(e.Text)
+	for _, c := range h.conditions {
+		// 没有指定房间的条件只匹配公开房间的事件
+		if (c.room != "" && c.room != e.Room) || (c.room == "" && !e.Open) {
+			continue
+		}
+		switch c.kind {
+		case "*":
+			matched = true
+		case string(TypeModeration):
+			if e.Type == TypeModeration {
+				matched = true
+			}
+		case "mention":
+			if e.Type == TypeChat && strings.Contains(text, c.keyword) {
+				matched = true
+				keywords = append(keywords, c.keyword)
+			}
+		default:
+			if Type(c.kind) == e.Type {
+				matched = true
+			}
+		}
+	}
+	return matched, keywords
+}
+
+// Dispatcher webhook 分发器
+//
+// 每个 webhook 有独立的有界队列和投递协程，慢的接收方只会让自己的队列积压，
+// 不会阻塞聊天，也不会拖慢其他 webhook；队列满时丢弃新事件并记录日志。
+// 投递失败时通过 utils.RetryWithBackoff 按指数退避重试。
+type Dispatcher struct {
+	path   string        // 配置文件路径，为空表示不启用
+	logger *utils.Logger // 日志记录器
+	client *http.Client  // HTTP客户端
+	mutex  sync.RWMutex  // 读写锁
+	hooks  []*Hook       // 当前生效的 webhook
+	closed bool          // 是否已关闭
+}
+
+// NewDispatcher 创建分发器并从配置文件加载 webhook，path为空时不启用
+func NewDispatcher(path string, logger *utils.Logger) (*Dispatcher, error) {
+	d := &Dispatcher{
+		path:   path,
+		logger: logger,
+		client: &http.Client{Timeout: httpTimeout},
+	}
+	if _, err := d.Reload(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reload 重新加载配置文件，返回 webhook 数量
+//
+// 加载失败时保留原有配置；成功时旧 webhook 的投递协程在处理完已排队的事件后退出。
+func (d *Dispatcher) Reload() (int, error) {
+	if d.path == "" {
+		return 0, nil
+	}
+
+	file, err := os.Open(d.path)
+	if err != nil {
+		return 0, fmt.Errorf("打开 webhook 配置文件失败: %v", err)
+	}
+	defer file.Close()
+
+	hooks, err := ParseHooks(file)
+	if err != nil {
+		return 0, err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return 0, nil
+	}
+	for _, h := range d.hooks {
+		close(h.queue)
+	}
+	d.hooks = hooks
+	for _, h := range hooks {
+		go d.run(h)
+	}
+	return len(hooks), nil
+}
+
+// Count 获取当前 webhook 数量
+func (d *Dispatcher) Count() int {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return len(d.hooks)
+}
+
+// Notify 把事件加入所有匹配的 webhook 的队列，不会阻塞
+func (d *Dispatcher) Notify(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for _, h := range d.hooks {
+		matched, keywords := h.match(e)
+		if !matched {
+			continue
+		}
+		hookEvent := e
+		hookEvent.Keywords = keywords
+		select {
+		case h.queue <- delivery{id: message.NewID(), event: hookEvent}:
+		default:
+			d.logger.Warn("webhook %s 的队列已满，丢弃 %s 事件", h.URL, e.Type)
+		}
+	}
+}
+
+// Close 停止接收新事件，投递协程处理完已排队的事件后退出
+func (d *Dispatcher) Close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return
+	}
+	d.closed = true
+	for _, h := range d.hooks {
+		close(h.queue)
+	}
+	d.hooks = nil
+}
+
+// run 依次投递一个 webhook 队列中的事件
+func (d *Dispatcher) run(h *Hook) {
+	for item := range h.queue {
+		body, err := json.Marshal(item.event)
+		if err != nil {
+			d.logger.Error("编码 webhook 事件失败: %v", err)
+			continue
+		}
+		err = utils.RetryWithBackoff(maxAttempts, retryBackoff, func() error {
+			return d.post(h, item, body)
+		})
+		if err != nil {
+			d.logger.Warn("webhook %s 投递 %s 事件失败 (已尝试 %d 次): %v", h.URL, item.event.Type, maxAttempts, err)
+		}
+	}
+}
+
+// post 发送一次请求，非 2xx 响应视为失败
+func (d *Dispatcher) post(h *Hook, item delivery, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(item.event.Type))
+	req.Header.Set(DeliveryHeader, item.id)
+	req.Header.Set(SignatureHeader, Sign(h.secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("响应状态 %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign 计算请求体的签名，接收方用同一密钥计算后与 X-Chatroom-Signature 比较
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseHooks 解析 webhook 配置文件
+//
+// 每行一个 webhook，格式为 "<URL> <密钥> <条件>..."，空行和以 # 开头的行被忽略：
+//
+//	https://ci.example.com/chat     s3cret  join leave
+//	https://oncall.example.com/hook t0ps3c  chat:ops mention:outage moderation
+//
+// 条件满足任意一个即投递：join、leave、chat、moderation 可以用 :<房间> 限定房间，
+// mention:<关键词> 匹配包含关键词的聊天消息（不区分大小写），moderation 匹配管理动作，* 匹配所有事件。
+// 没有限定房间的条件只匹配公开房间的事件，非公开房间的事件需要用 :<房间> 明确指定。
+func ParseHooks(r io.Reader) ([]*Hook, error) {
+	var hooks []*Hook
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hook, err := parseHook(text)
+		if err != nil {
+			return nil, fmt.Errorf("webhook 配置第%d行: %v", line, err)
+		}
+		hook.Line = line
+		hooks = append(hooks, hook)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 webhook 配置失败: %v", err)
+	}
+	return hooks, nil
+}
+
+// parseHook 解析一行配置
+func parseHook(text string) (*Hook, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("格式应为 \"<URL> <密钥> <条件>...\"")
+	}
+
+	target, err := url.Parse(fields[0])
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("无效的URL: %s", fields[0])
+	}
+
+	hook := &Hook{URL: fields[0], secret: fields[1], queue: make(chan delivery, QueueSize)}
+	for _, field := range fields[2:] {
+		kind, arg, _ := strings.Cut(field, ":")
+		kind = strings.ToLower(kind)
+		switch kind {
+		case "*":
+			if arg != "" {
+				return nil, fmt.Errorf("条件 %s 不接受参数", kind)
+			}
+			hook.conditions = append(hook.conditions, condition{kind: kind})
+		case string(TypeJoin), string(TypeLeave), string(TypeChat), string(TypeModeration):
+			hook.conditions = append(hook.conditions, condition{kind: kind, room: strings.ToLower(strings.TrimPrefix(arg, "#"))})
+		case "mention":
+			if arg == "" {
+				return nil, fmt.Errorf("mention 条件缺少关键词")
+			}
+			hook.conditions = append(hook.conditions, condition{kind: kind, keyword: strings.ToLower(arg)})
+		default:
+			return nil, fmt.Errorf("未知的条件: %s", field)
+		}
+	}
+	return hook, nil
+}
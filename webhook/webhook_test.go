@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"chatroom/utils"
+)
+
+// received 测试服务器收到的一次请求
+type received struct {
+	header http.Header
+	body   []byte
+	event  Event
+}
+
+// newReceiver 启动记录请求的测试服务器，status 决定每次请求的响应状态
+func newReceiver(t *testing.T, status func(n int) int) (*httptest.Server, chan received) {
+	t.Helper()
+	requests := make(chan received, QueueSize*2)
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event Event
+		json.Unmarshal(body, &event)
+		n := int(atomic.AddInt32(&count, 1))
+		requests <- received{header: r.Header.Clone(), body: body, event: event}
+		w.WriteHeader(status(n))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+// newDispatcher 用给定的配置内容创建分发器
+func newDispatcher(t *testing.T, config string) *Dispatcher {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "webhooks.conf")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDispatcher(path, utils.NewLogger(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(d.Close)
+	return d
+}
+
+// next 等待下一次请求
+func next(t *testing.T, requests chan received) received {
+	t.Helper()
+	select {
+	case r := <-requests:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到 webhook 请求")
+		return received{}
+	}
+}
+
+// none 确认一段时间内没有请求
+func none(t *testing.T, requests chan received) {
+	t.Helper()
+	select {
+	case r := <-requests:
+		t.Fatalf("不应该收到 webhook 请求: %s", r.body)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestSignature(t *testing.T) {
+	server, requests := newReceiver(t, func(int) int { return http.StatusOK })
+	d := newDispatcher(t, server.URL+" s3cret *\n")
+
+	d.Notify(Event{Type: TypeChat, Room: "lobby", User: "alice", Text: "你好", Open: true})
+	r := next(t, requests)
+
+	if got, want := r.header.Get(SignatureHeader), Sign("s3cret", r.body); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Fatalf("签名 %q, want %q", got, want)
+	}
+	if r.header.Get(SignatureHeader) == Sign("wrong", r.body) {
+		t.Fatal("不同密钥的签名不应该相同")
+	}
+	if r.header.Get(EventHeader) != string(TypeChat) || r.header.Get(DeliveryHeader) == "" {
+		t.Fatalf("请求头 %v", r.header)
+	}
+	if r.event.Text != "你好" || r.event.User != "alice" {
+		t.Fatalf("事件 %+v", r.event)
+	}
+	if strings.Contains(string(r.body), "Open") || strings.Contains(string(r.body), "open") {
+		t.Fatalf("请求体不应包含房间是否公开: %s", r.body)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	hooks, err := ParseHooks(strings.NewReader(strings.Join([]string{
+		"http://h/all    k *",
+		"http://h/chat   k chat",
+		"http://h/ops    k chat:ops moderation:ops",
+		"http://h/alert  k mention:outage",
+		"http://h/mod    k moderation",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := make(map[string]*Hook)
+	for _, h := range hooks {
+		byPath[h.URL[len("http://h"):]] = h
+	}
+
+	tests := []struct {
+		name  string
+		event Event
+		hooks []string
+	}{
+		{"公开房间的聊天", Event{Type: TypeChat, Room: "lobby", Text: "hi", Open: true}, []string{"/all", "/chat"}},
+		{"公开房间的提及", Event{Type: TypeChat, Room: "lobby", Text: "OUTAGE now", Open: true}, []string{"/all", "/chat", "/alert"}},
+		{"非公开房间的提及", Event{Type: TypeChat, Room: "secret", Text: "outage"}, nil},
+		{"指定了房间的非公开房间", Event{Type: TypeChat, Room: "ops", Text: "outage"}, []string{"/ops"}},
+		{"非公开房间的加入", Event{Type: TypeJoin, Room: "secret"}, nil},
+		{"公开房间的管理动作", Event{Type: TypeModeration, Room: "lobby", Open: true}, []string{"/all", "/mod"}},
+		{"非公开房间的管理动作", Event{Type: TypeModeration, Room: "secret"}, nil},
+		{"指定房间的管理动作", Event{Type: TypeModeration, Room: "ops"}, []string{"/ops"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, path := range []string{"/all", "/chat", "/ops", "/alert", "/mod"} {
+			if matched, _ := byPath[path].match(tt.event); matched {
+				got = append(got, path)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.hooks, ",") {
+			t.Errorf("%s: 匹配 %v, want %v", tt.name, got, tt.hooks)
+		}
+	}
+
+	if _, keywords := byPath["/alert"].match(Event{Type: TypeChat, Room: "lobby", Text: "Outage!", Open: true}); len(keywords) != 1 || keywords[0] != "outage" {
+		t.Errorf("命中的关键词 %v", keywords)
+	}
+}
+
+func TestParseHooksErrors(t *testing.T) {
+	for _, line := range []string{
+		"http://h k",
+		"ftp://h k chat",
+		"http://h k mention",
+		"http://h k *:lobby",
+		"http://h k unknown",
+	} {
+		if _, err := ParseHooks(strings.NewReader(line)); err == nil {
+			t.Errorf("ParseHooks(%q) 应返回错误", line)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	server, requests := newReceiver(t, func(n int) int {
+		if n < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	d := newDispatcher(t, server.URL+" k *\n")
+
+	d.Notify(Event{Type: TypeJoin, Room: "lobby", User: "alice", Open: true})
+	first := next(t, requests)
+	for i := 0; i < 2; i++ {
+		retry := next(t, requests)
+		if retry.header.Get(DeliveryHeader) != first.header.Get(DeliveryHeader) {
+			t.Fatal("重试时投递ID应该不变")
+		}
+		if string(retry.body) != string(first.body) {
+			t.Fatal("重试时请求体应该不变")
+		}
+	}
+	none(t, requests)
+}
+
+func TestQueueOverflow(t *testing.T) {
+	release := make(chan struct{})
+	var once sync.Once
+	var delivered int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		atomic.AddInt32(&delivered, 1)
+	}))
+	defer server.Close()
+	defer once.Do(func() { close(release) })
+
+	d := newDispatcher(t, server.URL+" k *\n")
+
+	// 接收方阻塞时，队列之外的事件被丢弃，Notify 不会阻塞
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < QueueSize*2; i++ {
+			d.Notify(Event{Type: TypeChat, Room: "lobby", Open: true})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("队列满时 Notify 不应该阻塞")
+	}
+
+	once.Do(func() { close(release) })
+	d.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && atomic.LoadInt32(&delivered) < QueueSize {
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	// 正在投递的一个加上队列中的事件
+	if got := atomic.LoadInt32(&delivered); got < QueueSize || got > QueueSize+1 {
+		t.Fatalf("投递了 %d 个事件，want %d 或 %d", got, QueueSize, QueueSize+1)
+	}
+}